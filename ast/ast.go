@@ -50,9 +50,11 @@ func (p *Program) StackLine() string {
 }
 
 type LetStatement struct {
-	Token tokens.Token // LET token
-	Name  *Identifier
-	Value Expression
+	Token   tokens.Token // LET or CONST token
+	Name    *Identifier
+	Pattern Expression      // ArrayPattern or HashPattern if the value is destructured, Name is nil then
+	Type    *TypeAnnotation // optional, `let port: string = "3000"`
+	Value   Expression
 }
 
 func (l *LetStatement) statementNode()       {}
@@ -61,7 +63,14 @@ func (l *LetStatement) TokenLiteral() string { return l.Token.Literal }
 func (l *LetStatement) String() string {
 	out := bytes.Buffer{}
 	out.WriteString(l.TokenLiteral() + " ")
-	out.WriteString(l.Name.String())
+	if l.Pattern != nil {
+		out.WriteString(l.Pattern.String())
+	} else {
+		out.WriteString(l.Name.String())
+	}
+	if l.Type != nil {
+		out.WriteString(": " + l.Type.String())
+	}
 	out.WriteString(" = ")
 	if l.Value != nil {
 		out.WriteString(l.Value.String())
@@ -73,6 +82,237 @@ func (l *LetStatement) StackLine() string {
 	return fmt.Sprintf("file: %s; line: %d", l.Token.FileName, l.Token.LineNumber)
 }
 
+// IsConst is true for `const` declarations which can't be reassigned
+func (l *LetStatement) IsConst() bool { return l.Token.Type == tokens.CONST }
+
+// StructStatement defines a struct: struct Point { x; y = 0; fn len(self) { ... } }
+type StructStatement struct {
+	Token   tokens.Token // STRUCT token
+	Name    *Identifier
+	Fields  []*StructField
+	Methods []*StructMethod
+	End     tokens.Token // closing brace
+}
+
+// StructField is a field of struct with optional default value
+type StructField struct {
+	Name  *Identifier
+	Value Expression
+}
+
+// StructMethod is a named function of struct, the first parameter receives the instance
+type StructMethod struct {
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (s *StructStatement) statementNode()       {}
+func (s *StructStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *StructStatement) String() string {
+	out := bytes.Buffer{}
+	out.WriteString(s.TokenLiteral() + " ")
+	out.WriteString(s.Name.String())
+	out.WriteString(" { ")
+	for _, field := range s.Fields {
+		out.WriteString(field.Name.String())
+		if field.Value != nil {
+			out.WriteString(" = ")
+			out.WriteString(field.Value.String())
+		}
+		out.WriteString("; ")
+	}
+	for _, method := range s.Methods {
+		if method.Function.Async {
+			out.WriteString("async ")
+		}
+		out.WriteString(method.Function.TokenLiteral() + " ")
+		out.WriteString(method.Name.String() + "(")
+		out.WriteString(method.Function.ParametersString())
+		out.WriteString(")")
+		out.WriteString(method.Function.resultString())
+		out.WriteString(method.Function.Body.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+func (s *StructStatement) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", s.Token.FileName, s.Token.LineNumber)
+}
+
+// BoundNames returns the names bound by the identifier or destructuring pattern
+func BoundNames(target Expression) []string {
+	switch n := target.(type) {
+	case *Identifier:
+		return []string{n.Value}
+	case *ArrayPattern:
+		names := []string{}
+		for _, element := range n.Elements {
+			names = append(names, BoundNames(element)...)
+		}
+		if n.Rest != nil {
+			names = append(names, n.Rest.Value)
+		}
+		return names
+	case *HashPattern:
+		names := []string{}
+		for _, element := range n.Targets {
+			names = append(names, BoundNames(element)...)
+		}
+		if n.Rest != nil {
+			names = append(names, n.Rest.Value)
+		}
+		return names
+	case *AlternativePattern:
+		names := []string{}
+		for _, pattern := range n.Patterns {
+			names = append(names, BoundNames(pattern)...)
+		}
+		return names
+	default:
+		return nil
+	}
+}
+
+// ArrayPattern destructures array: [a, [b, c], ...rest]
+type ArrayPattern struct {
+	Token    tokens.Token // [ token
+	Elements []Expression // Identifier or nested pattern
+	Rest     *Identifier
+}
+
+func (a *ArrayPattern) expressionNode()      {}
+func (a *ArrayPattern) TokenLiteral() string { return a.Token.Literal }
+func (a *ArrayPattern) String() string {
+	elements := []string{}
+	for _, element := range a.Elements {
+		elements = append(elements, element.String())
+	}
+	if a.Rest != nil {
+		elements = append(elements, "..."+a.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+func (a *ArrayPattern) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", a.Token.FileName, a.Token.LineNumber)
+}
+
+// HashPattern destructures hash by string keys or struct instance by fields: {name, port: p, ...rest}
+type HashPattern struct {
+	Token   tokens.Token // { token
+	Keys    []*Identifier
+	Targets []Expression // Identifier or nested pattern for each key
+	Rest    *Identifier
+}
+
+func (h *HashPattern) expressionNode()      {}
+func (h *HashPattern) TokenLiteral() string { return h.Token.Literal }
+func (h *HashPattern) String() string {
+	entries := []string{}
+	for i, key := range h.Keys {
+		if ident, ok := h.Targets[i].(*Identifier); ok && ident.Value == key.Value {
+			entries = append(entries, key.String())
+			continue
+		}
+		entries = append(entries, key.String()+": "+h.Targets[i].String())
+	}
+	if h.Rest != nil {
+		entries = append(entries, "..."+h.Rest.String())
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+func (h *HashPattern) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", h.Token.FileName, h.Token.LineNumber)
+}
+
+// MatchExpression evaluates the body of the first arm which pattern matches the value:
+// match (value) { 1 | 2 => "small", [a, b] => a + b, n if n > 10 => "big", _ => "other" }
+type MatchExpression struct {
+	Token tokens.Token // MATCH token
+	Value Expression
+	Arms  []*MatchArm
+	End   tokens.Token // closing brace
+}
+
+func (m *MatchExpression) expressionNode()      {}
+func (m *MatchExpression) TokenLiteral() string { return m.Token.Literal }
+func (m *MatchExpression) String() string {
+	arms := make([]string, len(m.Arms))
+	for i, arm := range m.Arms {
+		arms[i] = arm.String()
+	}
+	return "match (" + m.Value.String() + ") {" + strings.Join(arms, ", ") + "}"
+}
+func (m *MatchExpression) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", m.Token.FileName, m.Token.LineNumber)
+}
+
+// MatchArm is a pattern with optional guard: `pattern if guard => body`.
+// Patterns are literals, `_`, identifiers binding the value, ArrayPattern, HashPattern and AlternativePattern
+type MatchArm struct {
+	Token   tokens.Token // the first token of pattern
+	Pattern Expression
+	Guard   Expression // optional
+	Body    *BlockStatement
+}
+
+func (m *MatchArm) TokenLiteral() string { return m.Token.Literal }
+func (m *MatchArm) String() string {
+	out := bytes.Buffer{}
+	out.WriteString(m.Pattern.String())
+	if m.Guard != nil {
+		out.WriteString(" if " + m.Guard.String())
+	}
+	out.WriteString(" => {" + m.Body.String() + "}")
+	return out.String()
+}
+func (m *MatchArm) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", m.Token.FileName, m.Token.LineNumber)
+}
+
+// AlternativePattern matches if any of the patterns matches: 1 | 2 | 3
+type AlternativePattern struct {
+	Token    tokens.Token // the first | token
+	Patterns []Expression
+}
+
+func (a *AlternativePattern) expressionNode()      {}
+func (a *AlternativePattern) TokenLiteral() string { return a.Token.Literal }
+func (a *AlternativePattern) String() string {
+	patterns := make([]string, len(a.Patterns))
+	for i, pattern := range a.Patterns {
+		patterns[i] = pattern.String()
+	}
+	return strings.Join(patterns, " | ")
+}
+func (a *AlternativePattern) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", a.Token.FileName, a.Token.LineNumber)
+}
+
+// MultipleAssignment assigns values to targets in parallel: a, b = b, a
+type MultipleAssignment struct {
+	Token   tokens.Token // = token
+	Targets []Expression
+	Values  []Expression
+}
+
+func (m *MultipleAssignment) expressionNode()      {}
+func (m *MultipleAssignment) TokenLiteral() string { return m.Token.Literal }
+func (m *MultipleAssignment) String() string {
+	targets := make([]string, len(m.Targets))
+	for i, target := range m.Targets {
+		targets[i] = target.String()
+	}
+	values := make([]string, len(m.Values))
+	for i, value := range m.Values {
+		values[i] = value.String()
+	}
+	return strings.Join(targets, ", ") + " = " + strings.Join(values, ", ")
+}
+func (m *MultipleAssignment) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", m.Token.FileName, m.Token.LineNumber)
+}
+
 type Identifier struct {
 	Token tokens.Token
 	Value string
@@ -111,6 +351,39 @@ func (r *ReturnStatement) StackLine() string {
 	return fmt.Sprintf("file: %s; line: %d", r.Token.FileName, r.Token.LineNumber)
 }
 
+// BreakStatement stops the loop, the optional label refers to an enclosing loop: break outer;
+type BreakStatement struct {
+	Token tokens.Token // BREAK token
+	Label *Identifier
+}
+
+func (b *BreakStatement) statementNode()       {}
+func (b *BreakStatement) TokenLiteral() string { return b.Token.Literal }
+func (b *BreakStatement) String() string       { return branchString(b.TokenLiteral(), b.Label) }
+func (b *BreakStatement) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", b.Token.FileName, b.Token.LineNumber)
+}
+
+// ContinueStatement starts the next iteration of the loop, the optional label refers to an enclosing loop: continue outer;
+type ContinueStatement struct {
+	Token tokens.Token // CONTINUE token
+	Label *Identifier
+}
+
+func (c *ContinueStatement) statementNode()       {}
+func (c *ContinueStatement) TokenLiteral() string { return c.Token.Literal }
+func (c *ContinueStatement) String() string       { return branchString(c.TokenLiteral(), c.Label) }
+func (c *ContinueStatement) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", c.Token.FileName, c.Token.LineNumber)
+}
+
+func branchString(keyword string, label *Identifier) string {
+	if label == nil {
+		return keyword + ";"
+	}
+	return keyword + " " + label.String() + ";"
+}
+
 type ExpressionStatement struct {
 	Token      tokens.Token // The first token in the expression
 	Expression Expression
@@ -182,6 +455,35 @@ func (s *StringLiteral) StackLine() string {
 	return fmt.Sprintf("file: %s; line: %d", s.Token.FileName, s.Token.LineNumber)
 }
 
+// TemplateLiteral is a string with interpolations: "a = ${a}", the parts are string literals and expressions
+type TemplateLiteral struct {
+	Token tokens.Token
+	Parts []Expression
+}
+
+func (t *TemplateLiteral) expressionNode()      {}
+func (t *TemplateLiteral) TokenLiteral() string { return t.Token.Literal }
+func (t *TemplateLiteral) String() string {
+	out := bytes.Buffer{}
+
+	out.WriteString(`"`)
+	for _, part := range t.Parts {
+		if str, ok := part.(*StringLiteral); ok {
+			out.WriteString(str.Value)
+			continue
+		}
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+	out.WriteString(`"`)
+
+	return out.String()
+}
+func (t *TemplateLiteral) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", t.Token.FileName, t.Token.LineNumber)
+}
+
 type BooleanLiteral struct {
 	Token tokens.Token
 	Value bool
@@ -194,9 +496,22 @@ func (b *BooleanLiteral) StackLine() string {
 	return fmt.Sprintf("file: %s; line: %d", b.Token.FileName, b.Token.LineNumber)
 }
 
+// NullLiteral is the `null` value
+type NullLiteral struct {
+	Token tokens.Token
+}
+
+func (n *NullLiteral) expressionNode()      {}
+func (n *NullLiteral) TokenLiteral() string { return n.Token.Literal }
+func (n *NullLiteral) String() string       { return n.Token.Literal }
+func (n *NullLiteral) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", n.Token.FileName, n.Token.LineNumber)
+}
+
 type ArrayLiteral struct {
 	Token    tokens.Token
 	Elements []Expression
+	End      tokens.Token // closing bracket
 }
 
 func (a *ArrayLiteral) expressionNode()      {}
@@ -220,6 +535,8 @@ func (a *ArrayLiteral) StackLine() string {
 type HashLiteral struct {
 	Token tokens.Token
 	Pairs map[Expression]Expression
+	Keys  []Expression // keys in the source order
+	End   tokens.Token // closing brace
 }
 
 func (h *HashLiteral) expressionNode()      {}
@@ -282,7 +599,7 @@ func (i *InfixExpression) String() string {
 	if i.Left != nil {
 		out.WriteString(i.Left.String())
 	}
-	if i.Operator == "." {
+	if i.Operator == "." || i.Operator == "?." {
 		out.WriteString(i.Operator)
 	} else {
 		out.WriteString(" " + i.Operator + " ")
@@ -327,6 +644,7 @@ func (i *IfExpression) StackLine() string {
 
 type ForExpression struct {
 	Token     tokens.Token
+	Label     *Identifier // optional, `outer: for (...) {...}`
 	Initial   Expression // optional
 	Condition Expression // optional
 	Complete  Expression // optional
@@ -338,6 +656,9 @@ func (f *ForExpression) TokenLiteral() string { return f.Token.Literal }
 func (f *ForExpression) String() string {
 	out := bytes.Buffer{}
 
+	if f.Label != nil {
+		out.WriteString(f.Label.String() + ": ")
+	}
 	out.WriteString("for (")
 	if f.Initial != nil {
 		out.WriteString(f.Initial.String())
@@ -361,9 +682,45 @@ func (f *ForExpression) StackLine() string {
 	return fmt.Sprintf("file: %s; line: %d", f.Token.FileName, f.Token.LineNumber)
 }
 
+// ForInExpression iterates over a collection: for (v in arr) {...} or for (k, v in hash) {...}
+type ForInExpression struct {
+	Token    tokens.Token // FOR token
+	Label    *Identifier  // optional
+	Key      *Identifier  // optional, index or key of the element
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (f *ForInExpression) expressionNode()      {}
+func (f *ForInExpression) TokenLiteral() string { return f.Token.Literal }
+func (f *ForInExpression) String() string {
+	out := bytes.Buffer{}
+
+	if f.Label != nil {
+		out.WriteString(f.Label.String() + ": ")
+	}
+	out.WriteString("for (")
+	if f.Key != nil {
+		out.WriteString(f.Key.String() + ", ")
+	}
+	out.WriteString(f.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(f.Iterable.String())
+	out.WriteString(") {")
+	out.WriteString(f.Body.String())
+	out.WriteString("}")
+
+	return out.String()
+}
+func (f *ForInExpression) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", f.Token.FileName, f.Token.LineNumber)
+}
+
 type BlockStatement struct {
 	Token      tokens.Token // start block literal -> {
 	Statements []Statement
+	End        tokens.Token // closing brace, it's empty for the expression body of match arm
 }
 
 func (b *BlockStatement) statementNode()       {}
@@ -382,7 +739,13 @@ func (b *BlockStatement) StackLine() string {
 type FunctionLiteral struct {
 	Token      tokens.Token
 	Parameters []*Identifier
+	Patterns   map[int]Expression      // destructured parameters by index, the parameter name is the pattern string then
+	Defaults   map[int]Expression      // default values of optional parameters by index
+	Rest       *Identifier             // optional, receives the rest of arguments as array: fn(a, ...rest)
+	Types      map[int]*TypeAnnotation // annotated parameter types by index: fn(a: int)
+	Result     *TypeAnnotation         // optional, annotated result type: fn(a: int): int
 	Body       *BlockStatement
+	Async      bool // async function returns a promise and evaluates its body on the event loop
 }
 
 func (f *FunctionLiteral) expressionNode()      {}
@@ -390,27 +753,157 @@ func (f *FunctionLiteral) TokenLiteral() string { return f.Token.Literal }
 func (f *FunctionLiteral) String() string {
 	out := bytes.Buffer{}
 
-	out.WriteString(f.Token.Literal + "(")
-
-	params := make([]string, len(f.Parameters))
-	for i, parameter := range f.Parameters {
-		params[i] = parameter.Value
+	if f.Async {
+		out.WriteString("async ")
 	}
-
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(f.Token.Literal + "(")
+	out.WriteString(f.ParametersString())
 	out.WriteString(")")
+	out.WriteString(f.resultString())
 	out.WriteString(f.Body.String())
 
 	return out.String()
 }
+
+// ParametersString joins the parameters with default values and the rest parameter: a, b = 2, ...rest
+func (f *FunctionLiteral) ParametersString() string {
+	return FormatParameters(f.Parameters, f.Types, f.Defaults, f.Rest)
+}
+
+func (f *FunctionLiteral) resultString() string {
+	if f.Result == nil {
+		return ""
+	}
+	return ": " + f.Result.String() + " "
+}
+
+// FormatParameters joins the parameters of function, it's shared by function literals and function objects
+func FormatParameters(parameters []*Identifier, types map[int]*TypeAnnotation, defaults map[int]Expression, rest *Identifier) string {
+	params := make([]string, 0, len(parameters)+1)
+	for i, parameter := range parameters {
+		param := parameter.Value
+		if t, ok := types[i]; ok {
+			param += ": " + t.String()
+		}
+		if value, ok := defaults[i]; ok {
+			param += " = " + value.String()
+		}
+		params = append(params, param)
+	}
+	if rest != nil {
+		params = append(params, "..."+rest.Value)
+	}
+	return strings.Join(params, ", ")
+}
 func (f *FunctionLiteral) StackLine() string {
 	return fmt.Sprintf("file: %s; line: %d", f.Token.FileName, f.Token.LineNumber)
 }
 
+type AwaitExpression struct {
+	Token tokens.Token // AWAIT token
+	Value Expression
+}
+
+func (a *AwaitExpression) expressionNode()      {}
+func (a *AwaitExpression) TokenLiteral() string { return a.Token.Literal }
+func (a *AwaitExpression) String() string {
+	out := bytes.Buffer{}
+
+	out.WriteString("(")
+	out.WriteString(a.TokenLiteral() + " ")
+	if a.Value != nil {
+		out.WriteString(a.Value.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+func (a *AwaitExpression) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", a.Token.FileName, a.Token.LineNumber)
+}
+
+type SpawnExpression struct {
+	Token tokens.Token // SPAWN token
+	Value Expression   // Function or call expression evaluated in a new task
+}
+
+func (s *SpawnExpression) expressionNode()      {}
+func (s *SpawnExpression) TokenLiteral() string { return s.Token.Literal }
+func (s *SpawnExpression) String() string {
+	out := bytes.Buffer{}
+
+	out.WriteString(s.TokenLiteral() + " ")
+	if s.Value != nil {
+		out.WriteString(s.Value.String())
+	}
+
+	return out.String()
+}
+func (s *SpawnExpression) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", s.Token.FileName, s.Token.LineNumber)
+}
+
+type SelectExpression struct {
+	Token   tokens.Token // SELECT token
+	Cases   []*SelectCase
+	Default *BlockStatement // optional
+	End     tokens.Token    // closing brace
+}
+
+func (s *SelectExpression) expressionNode()      {}
+func (s *SelectExpression) TokenLiteral() string { return s.Token.Literal }
+func (s *SelectExpression) String() string {
+	out := bytes.Buffer{}
+
+	out.WriteString("select {")
+	for _, c := range s.Cases {
+		out.WriteString(c.String())
+	}
+	if s.Default != nil {
+		out.WriteString("default {")
+		out.WriteString(s.Default.String())
+		out.WriteString("}")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+func (s *SelectExpression) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", s.Token.FileName, s.Token.LineNumber)
+}
+
+type SelectCase struct {
+	Token     tokens.Token    // CASE token
+	Name      *Identifier     // optional, receives the value of `recv`
+	Operation *CallExpression // `recv(channel)` or `send(channel, value)`
+	Body      *BlockStatement
+}
+
+func (s *SelectCase) TokenLiteral() string { return s.Token.Literal }
+func (s *SelectCase) String() string {
+	out := bytes.Buffer{}
+
+	out.WriteString("case ")
+	if s.Name != nil {
+		out.WriteString(s.Name.Value + " = ")
+	}
+	out.WriteString(s.Operation.String())
+	out.WriteString(" {")
+	out.WriteString(s.Body.String())
+	out.WriteString("}")
+
+	return out.String()
+}
+func (s *SelectCase) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", s.Token.FileName, s.Token.LineNumber)
+}
+
 type CallExpression struct {
 	Token     tokens.Token // Token for (
 	Function  Expression   // Identifier or Function literal
 	Arguments []Expression
+	Named     []*NamedArgument // named arguments follow the positional ones: f(1, scale: 2)
+	Optional  bool             // f?.() returns null if the function is null
 }
 
 func (c *CallExpression) expressionNode()      {}
@@ -419,11 +912,17 @@ func (c *CallExpression) String() string {
 	out := bytes.Buffer{}
 
 	out.WriteString(c.Function.String())
+	if c.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("(")
 
-	args := make([]string, len(c.Arguments))
-	for i, argument := range c.Arguments {
-		args[i] = argument.String()
+	args := make([]string, 0, len(c.Arguments)+len(c.Named))
+	for _, argument := range c.Arguments {
+		args = append(args, argument.String())
+	}
+	for _, argument := range c.Named {
+		args = append(args, argument.Name.String()+": "+argument.Value.String())
 	}
 
 	out.WriteString(strings.Join(args, ", "))
@@ -435,10 +934,31 @@ func (c *CallExpression) StackLine() string {
 	return fmt.Sprintf("file: %s; line: %d", c.Token.FileName, c.Token.LineNumber)
 }
 
+// NamedArgument passes the value to the parameter by name
+type NamedArgument struct {
+	Name  *Identifier
+	Value Expression
+}
+
+// SpreadExpression expands an array into call arguments or array elements: f(...args), [...a, ...b]
+type SpreadExpression struct {
+	Token tokens.Token // ... token
+	Value Expression
+}
+
+func (s *SpreadExpression) expressionNode()      {}
+func (s *SpreadExpression) TokenLiteral() string { return s.Token.Literal }
+func (s *SpreadExpression) String() string       { return "..." + s.Value.String() }
+func (s *SpreadExpression) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", s.Token.FileName, s.Token.LineNumber)
+}
+
 type IncludeDeclaration struct {
-	Token   tokens.Token
-	Alias   *Identifier
-	Include *StringLiteral
+	Token    tokens.Token
+	Alias    *Identifier
+	Include  *StringLiteral
+	Names    []*ImportName // selective import: # {a, b as c} from "file.rs"
+	Wildcard bool          // import of all public names: # * from "file.rs"
 }
 
 func (c *IncludeDeclaration) declarationNode()     {}
@@ -447,7 +967,16 @@ func (c *IncludeDeclaration) String() string {
 	out := bytes.Buffer{}
 
 	out.WriteString("# ")
-	if c.Alias != nil {
+	switch {
+	case c.Wildcard:
+		out.WriteString("* from ")
+	case c.Names != nil:
+		names := make([]string, len(c.Names))
+		for i, name := range c.Names {
+			names[i] = name.String()
+		}
+		out.WriteString("{" + strings.Join(names, ", ") + "} from ")
+	case c.Alias != nil:
 		out.WriteString(c.Alias.Value + " ")
 	}
 	out.WriteString("\"")
@@ -456,14 +985,64 @@ func (c *IncludeDeclaration) String() string {
 
 	return out.String()
 }
+
+// Bound returns the names bound by the declaration in the including script
+func (c *IncludeDeclaration) Bound() []string {
+	if c.Names == nil {
+		return []string{c.Alias.Value}
+	}
+	names := make([]string, len(c.Names))
+	for i, name := range c.Names {
+		names[i] = name.Bound().Value
+	}
+	return names
+}
 func (c *IncludeDeclaration) StackLine() string {
 	return fmt.Sprintf("file: %s; line: %d", c.Token.FileName, c.Token.LineNumber)
 }
 
+// ImportName is the name of selective import, optionally renamed: name as alias
+type ImportName struct {
+	Name  *Identifier
+	Alias *Identifier
+}
+
+func (n *ImportName) String() string {
+	if n.Alias != nil {
+		return n.Name.Value + " as " + n.Alias.Value
+	}
+	return n.Name.Value
+}
+
+// Bound returns the identifier the name is bound to in the including script
+func (n *ImportName) Bound() *Identifier {
+	if n.Alias != nil {
+		return n.Alias
+	}
+	return n.Name
+}
+
+// ExportStatement marks the names declared by let, const, struct or include as public names of module:
+// export let a = 1;
+type ExportStatement struct {
+	Token     tokens.Token // EXPORT token
+	Statement Statement
+}
+
+func (e *ExportStatement) statementNode()       {}
+func (e *ExportStatement) TokenLiteral() string { return e.Token.Literal }
+func (e *ExportStatement) String() string {
+	return e.TokenLiteral() + " " + e.Statement.String()
+}
+func (e *ExportStatement) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", e.Token.FileName, e.Token.LineNumber)
+}
+
 type IndexExpression struct {
-	Token tokens.Token
-	Left  Expression
-	Index Expression
+	Token    tokens.Token
+	Left     Expression
+	Index    Expression
+	Optional bool // left?[index] returns null if left is null or the index is missing
 }
 
 func (i *IndexExpression) expressionNode()      {}
@@ -473,7 +1052,7 @@ func (i *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(i.Left.String())
-	out.WriteString("[")
+	out.WriteString(optionalBracket(i.Optional))
 	out.WriteString(i.Index.String())
 	out.WriteString("]")
 	out.WriteString(")")
@@ -483,3 +1062,84 @@ func (i *IndexExpression) String() string {
 func (i *IndexExpression) StackLine() string {
 	return fmt.Sprintf("file: %s; line: %d", i.Token.FileName, i.Token.LineNumber)
 }
+
+func optionalBracket(optional bool) string {
+	if optional {
+		return "?["
+	}
+	return "["
+}
+
+// SliceExpression is `left[start:end]`, both bounds are optional
+type SliceExpression struct {
+	Token    tokens.Token
+	Left     Expression
+	Start    Expression
+	End      Expression
+	Optional bool // left?[start:end] returns null if left is null
+}
+
+func (s *SliceExpression) expressionNode()      {}
+func (s *SliceExpression) TokenLiteral() string { return s.Token.Literal }
+func (s *SliceExpression) String() string {
+	out := bytes.Buffer{}
+
+	out.WriteString("(")
+	out.WriteString(s.Left.String())
+	out.WriteString(optionalBracket(s.Optional))
+	if s.Start != nil {
+		out.WriteString(s.Start.String())
+	}
+	out.WriteString(":")
+	if s.End != nil {
+		out.WriteString(s.End.String())
+	}
+	out.WriteString("]")
+	out.WriteString(")")
+
+	return out.String()
+}
+func (s *SliceExpression) StackLine() string {
+	return fmt.Sprintf("file: %s; line: %d", s.Token.FileName, s.Token.LineNumber)
+}
+
+// TypeAnnotation is an optional static type: int, array[string], fn(int, ...string): bool, int | null.
+// The annotations are verified by the checker, the evaluator ignores them
+type TypeAnnotation struct {
+	Token      tokens.Token
+	Name       string            // type name, `fn` for function types, empty for union
+	Parameters []*TypeAnnotation // element type of array or hash, parameter types of function
+	Variadic   bool              // the last parameter of function type receives the rest of arguments
+	Result     *TypeAnnotation   // optional result type of function
+	Union      []*TypeAnnotation // alternatives of union type
+}
+
+func (t *TypeAnnotation) String() string {
+	if len(t.Union) != 0 {
+		alternatives := make([]string, len(t.Union))
+		for i, alternative := range t.Union {
+			alternatives[i] = alternative.String()
+		}
+		return strings.Join(alternatives, " | ")
+	}
+
+	params := make([]string, len(t.Parameters))
+	for i, parameter := range t.Parameters {
+		params[i] = parameter.String()
+	}
+	if t.Name != "fn" {
+		if len(params) == 0 {
+			return t.Name
+		}
+		return t.Name + "[" + strings.Join(params, ", ") + "]"
+	}
+
+	if t.Variadic {
+		params[len(params)-1] = "..." + params[len(params)-1]
+	}
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if t.Result != nil {
+		out += ": " + t.Result.String()
+	}
+	return out
+}
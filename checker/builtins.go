@@ -0,0 +1,77 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/YReshetko/rash-lang/parser"
+)
+
+// builtinSignatures describes the builtins with a fixed signature, other builtins are checked as `any`
+var builtinSignatures = map[string]string{
+	"len":        "fn(string | array | hash | range): int",
+	"type":       "fn(any): string",
+	"str":        "fn(any): string",
+	"int":        "fn(int | double | string | bool): int",
+	"float":      "fn(int | double | string): double",
+	"bool":       "fn(any): bool",
+	"keys":       "fn(hash): array",
+	"values":     "fn(hash): array",
+	"has":        "fn(hash, any): bool",
+	"push":       "fn(array, ...any): array",
+	"print":      "fn(...any): null",
+	"println":    "fn(...any): null",
+	"frozen":     "fn(any): bool",
+	"split":      "fn(string, string): array[string]",
+	"upper":      "fn(string): string",
+	"lower":      "fn(string): string",
+	"contains":   "fn(string, string): bool",
+	"startsWith": "fn(string, string): bool",
+	"endsWith":   "fn(string, string): bool",
+	"chars":      "fn(string): array[string]",
+	"repeat":     "fn(string, int): string",
+}
+
+var builtinTypes = map[string]*Type{}
+
+// BuiltinSignature returns the signature of builtin if it's known
+func BuiltinSignature(name string) (string, bool) {
+	signature, ok := builtinSignatures[name]
+	return signature, ok
+}
+
+func init() {
+	for name, signature := range builtinSignatures {
+		t, err := parseSignature(signature)
+		if err != nil {
+			panic(fmt.Sprintf("invalid signature of builtin %s: %v", name, err))
+		}
+		builtinTypes[name] = t
+	}
+}
+
+// parseSignature parses the function type of builtin or plugin function
+func parseSignature(signature string) (*Type, error) {
+	annotation, errs := parser.ParseType(signature)
+	if len(errs) != 0 {
+		return nil, fmt.Errorf("%s", errs[0])
+	}
+	t, unknown := fromAnnotation(annotation, nil)
+	if len(unknown) != 0 {
+		return nil, fmt.Errorf("unknown type %s", unknown[0])
+	}
+	if t.Name != "fn" {
+		return nil, fmt.Errorf("expected function type, but got %s", t)
+	}
+	return t, nil
+}
+
+// methodType returns the type of builtin called as a method of the receiver type, nil if it's unknown
+func methodType(receiver *Type, name string) *Type {
+	builtin, ok := builtinTypes[name]
+	if !ok || len(builtin.Params) == 0 || builtin.Variadic && len(builtin.Params) == 1 {
+		return nil
+	}
+	if !assignable(receiver, builtin.Params[0]) {
+		return nil
+	}
+	return &Type{Name: "fn", Params: builtin.Params[1:], Variadic: builtin.Variadic, Result: builtin.Result}
+}
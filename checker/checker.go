@@ -0,0 +1,983 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/lexer"
+	"github.com/YReshetko/rash-lang/loaders"
+	"github.com/YReshetko/rash-lang/parser"
+	"github.com/YReshetko/rash-lang/tokens"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is a problem found by the checker
+type Diagnostic struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+}
+
+// Signatures returns the signature of plugin function declared by the plugin manifest (see extensions.Manifest)
+type Signatures func(pkgName, fnName string) (string, bool)
+
+// Checker infers the types of script and included modules and reports the values which don't match the annotations,
+// the arguments which don't match the signatures of functions and the operations on wrong types.
+// Unannotated parameters are `any`, so only the mismatches the checker is sure about are reported
+type Checker struct {
+	// Loader resolves and reads the checked scripts, the default loader reads them from the OS filesystem
+	Loader *loaders.Loader
+
+	signatures  Signatures
+	modules     map[string]*Type // checked modules by absolute path, nil while the module is being checked
+	loading     []string         // chain of modules being checked
+	diagnostics []Diagnostic
+
+	scope    *scope
+	structs  map[string]*Type
+	function *function
+	exports  map[string]bool
+}
+
+type scope struct {
+	names map[string]*variable
+	outer *scope
+}
+
+type variable struct {
+	t        *Type
+	declared bool // the type is annotated, so the assignments are checked
+}
+
+// function collects the result types of the function being checked
+type function struct {
+	result  *Type // annotated result type, nil if the result is inferred
+	returns *Type
+}
+
+// New creates the checker, signatures may be nil if there are no plugins
+func New(signatures Signatures) *Checker {
+	return &Checker{
+		Loader:     loaders.Default,
+		signatures: signatures,
+		modules:    map[string]*Type{},
+	}
+}
+
+// CheckFile checks the script and the modules it includes
+func (c *Checker) CheckFile(path string) []Diagnostic {
+	c.loadModule(path, tokens.Token{FileName: path})
+	return c.diagnostics
+}
+
+// Check checks the parsed program, the included modules are loaded from files
+func (c *Checker) Check(program *ast.Program) []Diagnostic {
+	c.checkModule(program)
+	return c.diagnostics
+}
+
+func (c *Checker) report(token tokens.Token, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		File:    token.FileName,
+		Line:    token.LineNumber,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// include resolves the included module the same way as the interpreter does and checks it
+func (c *Checker) include(include *ast.IncludeDeclaration) *Type {
+	path, err := c.Loader.Resolve(include.Include.Value, include.Token.FileName)
+	if err != nil {
+		c.report(include.Token, "%v", err)
+		return anyType
+	}
+	return c.loadModule(path, include.Token)
+}
+
+// loadModule parses and checks the module once, the module included while it's being checked is an import cycle
+func (c *Checker) loadModule(path string, token tokens.Token) *Type {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = path
+	}
+	if module, ok := c.modules[key]; ok {
+		if module == nil {
+			c.report(token, "import cycle: %s", strings.Join(append(c.cycle(key), path), " -> "))
+			return anyType
+		}
+		return module
+	}
+
+	src, err := c.Loader.ReadFile(path)
+	if err != nil {
+		c.report(token, "unable to load module %s: %v", path, err)
+		c.modules[key] = anyType
+		return anyType
+	}
+	p := parser.New(lexer.New(string(src), path))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			c.diagnostics = append(c.diagnostics, parserDiagnostic(path, msg))
+		}
+		c.modules[key] = anyType
+		return anyType
+	}
+
+	c.modules[key] = nil
+	c.loading = append(c.loading, path)
+	module := c.checkModule(program)
+	c.loading = c.loading[:len(c.loading)-1]
+	c.modules[key] = module
+	return module
+}
+
+// cycle returns the chain of modules being checked starting from the module included again
+func (c *Checker) cycle(key string) []string {
+	for i, path := range c.loading {
+		if abs, err := filepath.Abs(path); err == nil && abs == key {
+			return append([]string{}, c.loading[i:]...)
+		}
+	}
+	return []string{}
+}
+
+// parserDiagnostic moves the line from the end of parser error to the diagnostic
+func parserDiagnostic(path, msg string) Diagnostic {
+	d := Diagnostic{File: path, Message: msg}
+	if i := strings.LastIndex(msg, " on line "); i >= 0 {
+		if line, err := strconv.Atoi(msg[i+len(" on line "):]); err == nil {
+			d.Line = line
+			d.Message = msg[:i]
+		}
+	}
+	return d
+}
+
+func (c *Checker) checkModule(program *ast.Program) *Type {
+	saved, structs, fn, exports := c.scope, c.structs, c.function, c.exports
+	defer func() { c.scope, c.structs, c.function, c.exports = saved, structs, fn, exports }()
+
+	c.scope = &scope{names: map[string]*variable{}}
+	c.structs = map[string]*Type{}
+	c.function = nil
+	c.exports = nil
+
+	// structs can be used as types before the declaration
+	for _, statement := range program.Statements {
+		if export, ok := statement.(*ast.ExportStatement); ok {
+			statement = export.Statement
+		}
+		if s, ok := statement.(*ast.StructStatement); ok {
+			c.structs[s.Name.Value] = &Type{Name: s.Name.Value, Members: map[string]*Type{}}
+		}
+	}
+	for _, statement := range program.Statements {
+		c.checkStatement(statement)
+	}
+
+	module := &Type{Name: "module", Members: map[string]*Type{}, Exports: c.exports}
+	for name, v := range c.scope.names {
+		module.Members[name] = v.t
+	}
+	return module
+}
+
+func (c *Checker) openScope() {
+	c.scope = &scope{names: map[string]*variable{}, outer: c.scope}
+}
+
+func (c *Checker) closeScope() {
+	c.scope = c.scope.outer
+}
+
+func (c *Checker) declare(name string, t *Type, declared bool) {
+	c.scope.names[name] = &variable{t: t, declared: declared}
+}
+
+func (c *Checker) lookup(name string) *variable {
+	for s := c.scope; s != nil; s = s.outer {
+		if v, ok := s.names[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// annotation converts the annotation to type and reports unknown type names
+func (c *Checker) annotation(annotation *ast.TypeAnnotation) *Type {
+	t, unknown := fromAnnotation(annotation, c.structs)
+	for _, name := range unknown {
+		c.report(annotation.Token, "unknown type %s", name)
+	}
+	return t
+}
+
+// checkStatement returns the type of statement value, the statement which leaves the block has empty type
+func (c *Checker) checkStatement(statement ast.Statement) *Type {
+	switch s := statement.(type) {
+	case *ast.LetStatement:
+		c.checkLet(s)
+	case *ast.ReturnStatement:
+		c.checkReturn(s.Token, c.infer(s.Value))
+		return &Type{}
+	case *ast.BreakStatement, *ast.ContinueStatement:
+		return &Type{}
+	case *ast.ExpressionStatement:
+		return c.infer(s.Expression)
+	case *ast.BlockStatement:
+		return c.checkBlock(s)
+	case *ast.StructStatement:
+		c.checkStruct(s)
+	case *ast.DeclarationStatement:
+		if include, ok := s.Declaration.(*ast.IncludeDeclaration); ok {
+			c.checkInclude(include)
+		}
+	case *ast.ExportStatement:
+		c.checkStatement(s.Statement)
+		c.export(s)
+	}
+	return anyType
+}
+
+// checkInclude declares the module alias, or the names imported from the module
+func (c *Checker) checkInclude(include *ast.IncludeDeclaration) *Type {
+	module := c.include(include)
+	switch {
+	case module.Name != "module":
+		for _, name := range include.Bound() {
+			c.declare(name, anyType, false)
+		}
+	case include.Wildcard:
+		for name, t := range module.Members {
+			if isPublic(module, name) {
+				c.declareImport(name, name, t)
+			}
+		}
+	case include.Names != nil:
+		for _, name := range include.Names {
+			c.declareImport(name.Name.Value, name.Bound().Value, c.moduleMember(module, name.Name))
+		}
+	default:
+		c.declare(include.Alias.Value, module, true)
+	}
+	return module
+}
+
+// declareImport declares the imported name, the imported struct can be used as a type by the bound name
+func (c *Checker) declareImport(name, bound string, t *Type) {
+	if t.Name == "fn" && t.Result != nil && t.Result.Name == name && t.Result.Members != nil {
+		c.structs[bound] = t.Result
+	}
+	c.declare(bound, t, false)
+}
+
+// export marks the names declared by the statement as exported names of module
+func (c *Checker) export(s *ast.ExportStatement) {
+	if c.exports == nil {
+		c.exports = map[string]bool{}
+	}
+	switch statement := s.Statement.(type) {
+	case *ast.LetStatement:
+		if statement.Pattern != nil {
+			for _, name := range ast.BoundNames(statement.Pattern) {
+				c.exports[name] = true
+			}
+		} else {
+			c.exports[statement.Name.Value] = true
+		}
+	case *ast.StructStatement:
+		c.exports[statement.Name.Value] = true
+	case *ast.DeclarationStatement:
+		include := statement.Declaration.(*ast.IncludeDeclaration)
+		if !include.Wildcard {
+			for _, name := range include.Bound() {
+				c.exports[name] = true
+			}
+			break
+		}
+		module := c.lookupModule(include)
+		for name := range module.Members {
+			if isPublic(module, name) {
+				c.exports[name] = true
+			}
+		}
+	}
+}
+
+// lookupModule returns the type of module which is already checked
+func (c *Checker) lookupModule(include *ast.IncludeDeclaration) *Type {
+	path, err := c.Loader.Resolve(include.Include.Value, include.Token.FileName)
+	if err != nil {
+		return anyType
+	}
+	key, err := filepath.Abs(path)
+	if err != nil || c.modules[key] == nil {
+		return anyType
+	}
+	return c.modules[key]
+}
+
+// moduleMember returns the type of public name of module, the same way as the interpreter resolves `alias.name`
+func (c *Checker) moduleMember(module *Type, name *ast.Identifier) *Type {
+	t, ok := module.Members[name.Value]
+	switch {
+	case !ok:
+		c.report(name.Token, "undefined name %s in the module", name.Value)
+		return anyType
+	case !isPublic(module, name.Value):
+		c.report(name.Token, "%s is private to the module", name.Value)
+		return anyType
+	}
+	return t
+}
+
+// isPublic mirrors objects.Environment.IsPublic for the type of module
+func isPublic(module *Type, name string) bool {
+	if module.Exports != nil {
+		return module.Exports[name]
+	}
+	return !strings.HasPrefix(name, "_")
+}
+
+// checkBlock checks the statements in a new scope, the type of the last statement is the value of block
+func (c *Checker) checkBlock(block *ast.BlockStatement) *Type {
+	if block == nil {
+		return nullType
+	}
+	c.openScope()
+	defer c.closeScope()
+
+	t := nullType
+	for _, statement := range block.Statements {
+		t = c.checkStatement(statement)
+	}
+	return t
+}
+
+func (c *Checker) checkLet(s *ast.LetStatement) {
+	if _, ok := s.Value.(*ast.FunctionLiteral); ok && s.Name != nil {
+		// the function can call itself
+		c.declare(s.Name.Value, anyType, false)
+	}
+	value := c.infer(s.Value)
+	if s.Type != nil {
+		t := c.annotation(s.Type)
+		if !assignable(value, t) {
+			c.report(s.Token, "cannot use %s as %s in %s %s", value, t, s.TokenLiteral(), targetName(s))
+		}
+		value = t
+	}
+	if s.Pattern != nil {
+		c.bindPattern(s.Pattern)
+		return
+	}
+	c.declare(s.Name.Value, value, s.Type != nil)
+}
+
+func targetName(s *ast.LetStatement) string {
+	if s.Pattern != nil {
+		return s.Pattern.String()
+	}
+	return s.Name.Value
+}
+
+// bindPattern declares the names of destructuring or match pattern, literals of match pattern are inferred
+func (c *Checker) bindPattern(pattern ast.Expression) {
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		c.declare(p.Value, anyType, false)
+	case *ast.ArrayPattern:
+		for _, element := range p.Elements {
+			c.bindPattern(element)
+		}
+		if p.Rest != nil {
+			c.declare(p.Rest.Value, arrayOf(anyType), false)
+		}
+	case *ast.HashPattern:
+		for _, target := range p.Targets {
+			c.bindPattern(target)
+		}
+		if p.Rest != nil {
+			c.declare(p.Rest.Value, &Type{Name: "hash"}, false)
+		}
+	case *ast.AlternativePattern:
+		for _, alternative := range p.Patterns {
+			c.bindPattern(alternative)
+		}
+	default:
+		c.infer(pattern)
+	}
+}
+
+func (c *Checker) checkReturn(token tokens.Token, t *Type) {
+	if c.function == nil {
+		return
+	}
+	if c.function.result == nil {
+		c.function.returns = join(c.function.returns, t)
+		return
+	}
+	if !assignable(t, c.function.result) {
+		c.report(token, "cannot return %s from function returning %s", t, c.function.result)
+	}
+}
+
+func isEmpty(t *Type) bool {
+	return t.Name == "" && len(t.Union) == 0
+}
+
+// infer returns the type of expression and checks its subexpressions
+func (c *Checker) infer(exp ast.Expression) *Type {
+	switch e := exp.(type) {
+	case *ast.IntegerLiteral:
+		return intType
+	case *ast.DoubleLiteral:
+		return doubleType
+	case *ast.StringLiteral:
+		return stringType
+	case *ast.TemplateLiteral:
+		for _, part := range e.Parts {
+			c.infer(part)
+		}
+		return stringType
+	case *ast.BooleanLiteral:
+		return boolType
+	case *ast.NullLiteral:
+		return nullType
+	case *ast.Identifier:
+		return c.identifier(e.Value)
+	case *ast.PrefixExpression:
+		right := c.infer(e.Right)
+		if e.Operator == "!" {
+			return boolType
+		}
+		if right.Name == "int" || right.Name == "double" {
+			return right
+		}
+		return anyType
+	case *ast.InfixExpression:
+		return c.infix(e)
+	case *ast.IfExpression:
+		c.infer(e.Condition)
+		return join(c.checkBlock(e.Consequence), c.checkBlock(e.Alternative))
+	case *ast.ForExpression:
+		c.openScope()
+		defer c.closeScope()
+		for _, part := range []ast.Expression{e.Initial, e.Condition, e.Complete} {
+			if part != nil {
+				c.infer(part)
+			}
+		}
+		c.checkBlock(e.Body)
+	case *ast.ForInExpression:
+		c.checkForIn(e)
+	case *ast.FunctionLiteral:
+		return c.functionType(e, nil)
+	case *ast.CallExpression:
+		return c.call(e)
+	case *ast.ArrayLiteral:
+		elem := &Type{}
+		for _, element := range e.Elements {
+			t := c.infer(element)
+			if _, ok := element.(*ast.SpreadExpression); ok {
+				t = t.elem()
+			}
+			elem = join(elem, t)
+		}
+		if isEmpty(elem) {
+			return &Type{Name: "array"}
+		}
+		return arrayOf(elem)
+	case *ast.HashLiteral:
+		elem := &Type{}
+		for key, value := range e.Pairs {
+			c.infer(key)
+			elem = join(elem, c.infer(value))
+		}
+		if isEmpty(elem) {
+			return &Type{Name: "hash"}
+		}
+		return &Type{Name: "hash", Elem: elem}
+	case *ast.IndexExpression:
+		left := c.infer(e.Left)
+		c.infer(e.Index)
+		return indexType(left)
+	case *ast.SliceExpression:
+		left := c.infer(e.Left)
+		for _, bound := range []ast.Expression{e.Start, e.End} {
+			if bound != nil {
+				c.infer(bound)
+			}
+		}
+		return left
+	case *ast.SpreadExpression:
+		return c.infer(e.Value)
+	case *ast.LetStatement:
+		c.checkLet(e)
+	case *ast.MultipleAssignment:
+		for _, value := range e.Values {
+			c.infer(value)
+		}
+	case *ast.MatchExpression:
+		return c.match(e)
+	case *ast.AwaitExpression:
+		c.infer(e.Value)
+	case *ast.SpawnExpression:
+		c.infer(e.Value)
+		return taskType
+	case *ast.SelectExpression:
+		for _, selectCase := range e.Cases {
+			c.infer(selectCase.Operation)
+			c.openScope()
+			if selectCase.Name != nil {
+				c.declare(selectCase.Name.Value, anyType, false)
+			}
+			c.checkBlock(selectCase.Body)
+			c.closeScope()
+		}
+		c.checkBlock(e.Default)
+	}
+	return anyType
+}
+
+func (c *Checker) identifier(name string) *Type {
+	if v := c.lookup(name); v != nil {
+		return v.t
+	}
+	if t, ok := builtinTypes[name]; ok {
+		return t
+	}
+	return anyType
+}
+
+func indexType(left *Type) *Type {
+	switch left.Name {
+	case "array", "hash":
+		return left.elem()
+	case "string":
+		return stringType
+	default:
+		return anyType
+	}
+}
+
+func (c *Checker) checkForIn(e *ast.ForInExpression) {
+	iterable := c.infer(e.Iterable)
+	key, value := anyType, anyType
+	switch iterable.Name {
+	case "array":
+		key, value = intType, iterable.elem()
+	case "string":
+		key, value = intType, stringType
+	case "range":
+		key, value = intType, intType
+	case "hash":
+		if e.Key != nil {
+			value = iterable.elem()
+		}
+	}
+
+	c.openScope()
+	defer c.closeScope()
+	if e.Key != nil {
+		c.declare(e.Key.Value, key, false)
+	}
+	c.declare(e.Value.Value, value, false)
+	c.checkBlock(e.Body)
+}
+
+func (c *Checker) match(e *ast.MatchExpression) *Type {
+	c.infer(e.Value)
+	t := &Type{}
+	for _, arm := range e.Arms {
+		c.openScope()
+		c.bindPattern(arm.Pattern)
+		if arm.Guard != nil {
+			c.infer(arm.Guard)
+		}
+		t = join(t, c.checkBlock(arm.Body))
+		c.closeScope()
+	}
+	if isEmpty(t) {
+		return anyType
+	}
+	return t
+}
+
+func (c *Checker) infix(e *ast.InfixExpression) *Type {
+	switch e.Operator {
+	case ".", "?.":
+		left := c.infer(e.Left)
+		if e.Operator == "." {
+			return c.dotted(left, e.Right)
+		}
+		t := c.dotted(withoutNull(left), e.Right)
+		if hasNull(left) {
+			t = join(t, nullType)
+		}
+		return t
+	case "=":
+		return c.assign(e)
+	case "??":
+		left, right := c.infer(e.Left), c.infer(e.Right)
+		if left.Name == "null" {
+			return right
+		}
+		return join(withoutNull(left), right)
+	}
+
+	left, right := c.infer(e.Left), c.infer(e.Right)
+	switch e.Operator {
+	case "==", "!=":
+		return boolType
+	case "..":
+		return rangeType
+	default:
+		return c.operation(e.Token, e.Operator, left, right)
+	}
+}
+
+func hasNull(t *Type) bool {
+	for _, alternative := range alternativesOf(t) {
+		if alternative.Name == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// simpleTypes are the types the operations are checked on
+var simpleTypes = map[string]bool{"int": true, "double": true, "string": true, "bool": true, "null": true}
+
+// operation infers the result of arithmetic or comparison the same way as the evaluator does
+func (c *Checker) operation(token tokens.Token, operator string, left, right *Type) *Type {
+	numbers := isNumber(left) && isNumber(right)
+	strings := left.Name == "string" && right.Name == "string"
+	switch {
+	case numbers && (operator == "<" || operator == ">"):
+		return boolType
+	case numbers && left.Name == "int" && right.Name == "int":
+		return intType
+	case numbers:
+		return doubleType
+	case strings && operator != "-" && operator != "*" && operator != "/":
+		if operator == "+" {
+			return stringType
+		}
+		return boolType
+	case operator == "*" && (left.Name == "string" && right.Name == "int" || left.Name == "int" && right.Name == "string"):
+		return stringType
+	case !simpleTypes[left.Name] || !simpleTypes[right.Name]:
+		return anyType
+	case left.Name != right.Name:
+		c.report(token, "type mismatch: %s %s %s", left, operator, right)
+	default:
+		c.report(token, "unknown operator: %s %s %s", left, operator, right)
+	}
+	return anyType
+}
+
+func isNumber(t *Type) bool {
+	return t.Name == "int" || t.Name == "double"
+}
+
+// assign checks the value assigned to annotated variable, unannotated variable gets the joined type
+func (c *Checker) assign(e *ast.InfixExpression) *Type {
+	value := c.infer(e.Right)
+	ident, ok := e.Left.(*ast.Identifier)
+	if !ok {
+		return value
+	}
+	v := c.lookup(ident.Value)
+	switch {
+	case v == nil:
+	case v.declared && !assignable(value, v.t):
+		c.report(e.Token, "cannot assign %s to %s of type %s", value, ident.Value, v.t)
+	case !v.declared:
+		v.t = join(v.t, value)
+	}
+	return value
+}
+
+// dotted infers `left.right` where right is a member optionally followed by calls and indexes
+func (c *Checker) dotted(left *Type, right ast.Expression) *Type {
+	switch n := right.(type) {
+	case *ast.Identifier:
+		return c.member(left, n)
+	case *ast.CallExpression:
+		return c.callWith(c.dotted(left, n.Function), n)
+	case *ast.IndexExpression:
+		value := c.dotted(left, n.Left)
+		c.infer(n.Index)
+		return indexType(value)
+	case *ast.SliceExpression:
+		return c.dotted(left, n.Left)
+	default:
+		return anyType
+	}
+}
+
+func (c *Checker) member(left *Type, name *ast.Identifier) *Type {
+	switch {
+	case left.Name == "module":
+		return c.moduleMember(left, name)
+	case left.Members != nil:
+		if t, ok := left.Members[name.Value]; ok {
+			return t
+		}
+		if method := methodType(left, name.Value); method != nil {
+			return method
+		}
+		c.report(name.Token, "undefined member %s of %s", name.Value, left.Name)
+		return anyType
+	case left.Name == "hash":
+		// the key of hash takes precedence over the method, so the member is unknown if there is such method
+		if methodType(left, name.Value) != nil {
+			return anyType
+		}
+		return left.elem()
+	case left.Name == "any" || len(left.Union) != 0:
+		return anyType
+	}
+	if method := methodType(left, name.Value); method != nil {
+		return method
+	}
+	return anyType
+}
+
+func (c *Checker) call(e *ast.CallExpression) *Type {
+	if ident, ok := e.Function.(*ast.Identifier); ok && (ident.Value == "eval" || ident.Value == "call") && c.lookup(ident.Value) == nil {
+		if t, ok := c.pluginCall(e); ok {
+			return t
+		}
+	}
+	return c.callWith(c.infer(e.Function), e)
+}
+
+// pluginCall checks `eval("pkg", "fn", args...)` and `call("pkg", "fn", callback, args...)` against the plugin manifest
+func (c *Checker) pluginCall(e *ast.CallExpression) (*Type, bool) {
+	if c.signatures == nil || len(e.Arguments) < 2 {
+		return nil, false
+	}
+	pkgName, ok := e.Arguments[0].(*ast.StringLiteral)
+	if !ok {
+		return nil, false
+	}
+	fnName, ok := e.Arguments[1].(*ast.StringLiteral)
+	if !ok {
+		return nil, false
+	}
+	signature, ok := c.signatures(pkgName.Value, fnName.Value)
+	if !ok {
+		return nil, false
+	}
+	fn, err := parseSignature(signature)
+	if err != nil {
+		c.report(e.Token, "invalid signature of %s.%s in plugin manifest: %v", pkgName.Value, fnName.Value, err)
+		fn = anyType
+	}
+
+	call := &ast.CallExpression{
+		Token:     e.Token,
+		Function:  &ast.Identifier{Value: pkgName.Value + "." + fnName.Value},
+		Arguments: e.Arguments[2:],
+		Named:     e.Named,
+	}
+	return c.callWith(fn, call), true
+}
+
+// callWith checks the arguments of call against the function type and returns the result type
+func (c *Checker) callWith(fn *Type, e *ast.CallExpression) *Type {
+	args := []*Type{}
+	spread := false
+	for _, argument := range e.Arguments {
+		t := c.infer(argument)
+		if _, ok := argument.(*ast.SpreadExpression); ok {
+			spread = true
+		}
+		if !spread {
+			args = append(args, t)
+		}
+	}
+	named := make([]*Type, len(e.Named))
+	for i, argument := range e.Named {
+		named[i] = c.infer(argument.Value)
+	}
+
+	callee := fn
+	if e.Optional {
+		callee = withoutNull(fn)
+	}
+	if callee.Name != "fn" {
+		return anyType
+	}
+
+	name := e.Function.String()
+	c.checkArity(callee, len(args), len(e.Named) != 0, spread, name, e.Token)
+	for i, arg := range args {
+		if i >= len(callee.Params) && !callee.Variadic {
+			break
+		}
+		if param := callee.param(i); !assignable(arg, param) {
+			c.report(e.Token, "cannot use %s as %s in argument %d of `%s`", arg, param, i+1, name)
+		}
+	}
+	for i, argument := range e.Named {
+		index := indexOf(callee.Names, argument.Name.Value)
+		if index < 0 {
+			if callee.Names != nil {
+				c.report(argument.Name.Token, "unexpected named argument %s in call of `%s`", argument.Name.Value, name)
+			}
+			continue
+		}
+		if param := callee.Params[index]; !assignable(named[i], param) {
+			c.report(e.Token, "cannot use %s as %s in argument %s of `%s`", named[i], param, argument.Name.Value, name)
+		}
+	}
+
+	result := callee.result()
+	if e.Optional && hasNull(fn) {
+		result = join(result, nullType)
+	}
+	return result
+}
+
+func (c *Checker) checkArity(fn *Type, got int, named, spread bool, name string, token tokens.Token) {
+	max := len(fn.Params)
+	required := max - fn.Optional
+	if fn.Variadic {
+		required--
+	}
+	tooFew := got < required && !named && !spread
+	tooMany := got > max && !fn.Variadic
+	if !tooFew && !tooMany {
+		return
+	}
+
+	expected := strconv.Itoa(max)
+	switch {
+	case fn.Variadic:
+		expected = ">=" + strconv.Itoa(required)
+	case required != max:
+		expected = fmt.Sprintf("%d..%d", required, max)
+	}
+	c.report(token, "wrong number of arguments to `%s`; got=%d, expected=%s", name, got, expected)
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// functionType checks the function body with parameters in a new scope and returns the type of function,
+// the receiver is the type of the first parameter of struct method
+func (c *Checker) functionType(fnLit *ast.FunctionLiteral, receiver *Type) *Type {
+	t := &Type{Name: "fn", Optional: len(fnLit.Defaults), Names: []string{}}
+
+	c.openScope()
+	defer c.closeScope()
+	for i, parameter := range fnLit.Parameters {
+		param := anyType
+		annotated, isAnnotated := fnLit.Types[i]
+		if isAnnotated {
+			param = c.annotation(annotated)
+		}
+		if i == 0 && receiver != nil {
+			param = receiver
+		}
+		if value, ok := fnLit.Defaults[i]; ok {
+			if d := c.infer(value); isAnnotated && !assignable(d, param) {
+				c.report(parameter.Token, "cannot use %s as %s in default value of %s", d, param, parameter.Value)
+			}
+		}
+		if pattern, ok := fnLit.Patterns[i]; ok {
+			c.bindPattern(pattern)
+		} else {
+			c.declare(parameter.Value, param, isAnnotated)
+		}
+		t.Params = append(t.Params, param)
+		t.Names = append(t.Names, parameter.Value)
+	}
+	if fnLit.Rest != nil {
+		c.declare(fnLit.Rest.Value, arrayOf(anyType), false)
+		t.Params = append(t.Params, anyType)
+		t.Variadic = true
+	}
+
+	outer := c.function
+	defer func() { c.function = outer }()
+	c.function = &function{returns: &Type{}}
+	if fnLit.Result != nil {
+		c.function.result = c.annotation(fnLit.Result)
+	}
+
+	last := c.checkBlock(fnLit.Body)
+	switch {
+	case c.function.result != nil:
+		t.Result = c.function.result
+		if !isEmpty(last) && !assignable(last, t.Result) {
+			c.report(fnLit.Token, "cannot return %s from function returning %s", last, t.Result)
+		}
+	case isEmpty(last):
+		t.Result = c.function.returns
+	default:
+		t.Result = join(c.function.returns, last)
+	}
+	if isEmpty(t.Result) {
+		t.Result = anyType
+	}
+	if fnLit.Async {
+		t.Result = &Type{Name: "promise"}
+	}
+	return t
+}
+
+// checkStruct declares the constructor of struct, the constructor takes the parameters of `init` method
+// or optional fields in order of declaration
+func (c *Checker) checkStruct(s *ast.StructStatement) {
+	instance, ok := c.structs[s.Name.Value]
+	if !ok {
+		instance = &Type{Name: s.Name.Value, Members: map[string]*Type{}}
+	}
+	constructor := &Type{Name: "fn", Result: instance, Names: []string{}}
+	for _, field := range s.Fields {
+		if field.Value != nil {
+			c.infer(field.Value)
+		}
+		instance.Members[field.Name.Value] = anyType
+		constructor.Params = append(constructor.Params, anyType)
+		constructor.Names = append(constructor.Names, field.Name.Value)
+	}
+	constructor.Optional = len(s.Fields)
+	c.declare(s.Name.Value, constructor, true)
+
+	for _, method := range s.Methods {
+		instance.Members[method.Name.Value] = anyType
+	}
+	for _, method := range s.Methods {
+		fn := c.functionType(method.Function, instance)
+		bound := &Type{
+			Name:     "fn",
+			Params:   fn.Params[1:],
+			Names:    fn.Names[1:],
+			Optional: fn.Optional,
+			Variadic: fn.Variadic,
+			Result:   fn.Result,
+		}
+		instance.Members[method.Name.Value] = bound
+		if method.Name.Value == "init" {
+			constructor.Params, constructor.Names = bound.Params, bound.Names
+			constructor.Optional, constructor.Variadic = bound.Optional, bound.Variadic
+		}
+	}
+}
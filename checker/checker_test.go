@@ -0,0 +1,140 @@
+package checker_test
+
+import (
+	"github.com/YReshetko/rash-lang/checker"
+	"github.com/YReshetko/rash-lang/lexer"
+	"github.com/YReshetko/rash-lang/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var signatures = map[string]map[string]string{
+	"sys": {
+		"len":  "fn(string | array | hash): int",
+		"tick": "fn(fn(string), int): null",
+	},
+}
+
+func check(t *testing.T, input string) []string {
+	p := parser.New(lexer.New(input, "test.rs"))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors(), input)
+
+	c := checker.New(func(pkgName, fnName string) (string, bool) {
+		signature, ok := signatures[pkgName][fnName]
+		return signature, ok
+	})
+	messages := []string{}
+	for _, d := range c.Check(program) {
+		messages = append(messages, d.String())
+	}
+	return messages
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let port: string = "3000"; let n: double = 1; let v: int | null = null;`, []string{}},
+		{`let port: string = 3000;`, []string{"test.rs:1: cannot use int as string in let port"}},
+		{`let p: port = 1;`, []string{"test.rs:1: unknown type port"}},
+		{`let xs: array[int] = [1, "a"];`, []string{"test.rs:1: cannot use array[int | string] as array[int] in let xs"}},
+		{`let add = fn(a: int, b: int): int { a + b }; let s: string = add(1, 2);`, []string{"test.rs:1: cannot use int as string in let s"}},
+		{`let add = fn(a: int, b: int) { a + b }; add(1, "2");`, []string{"test.rs:1: cannot use string as int in argument 2 of `add`"}},
+		{`let add = fn(a, b = 1) { a }; add(); add(1, 2, 3);`, []string{
+			"test.rs:1: wrong number of arguments to `add`; got=0, expected=1..2",
+			"test.rs:1: wrong number of arguments to `add`; got=3, expected=1..2",
+		}},
+		{`let f = fn(a, ...rest) { a }; f(); f(1, 2, 3); f(...[]);`, []string{"test.rs:1: wrong number of arguments to `f`; got=0, expected=>=1"}},
+		{`let f = fn(a: int) { a }; f(b: 1); f(a: "x");`, []string{
+			"test.rs:1: unexpected named argument b in call of `f`",
+			"test.rs:1: cannot use string as int in argument a of `f`",
+		}},
+		{`let f = fn(n: int): string { n };`, []string{"test.rs:1: cannot return int from function returning string"}},
+		{`let f = fn(n: int): string { if (n > 0) { return n; } "" };`, []string{"test.rs:1: cannot return int from function returning string"}},
+		{`let f = fn(n) { if (n > 0) { return 1; } "" }; let r: int = f(1);`, []string{"test.rs:1: cannot use int | string as int in let r"}},
+		{`let f = fn(n) { n }; let r: int = f(1);`, []string{}},
+		{`let x: int = 1; x = "a"; let y = 1; y = "b"; let z: int = y;`, []string{
+			"test.rs:1: cannot assign string to x of type int",
+			"test.rs:1: cannot use int | string as int in let z",
+		}},
+		{`"a" - 1; true + true; 1 + 2.5; "a" * 3;`, []string{
+			"test.rs:1: type mismatch: string - int",
+			"test.rs:1: unknown operator: bool + bool",
+		}},
+		{`len(1); "abc".upper(); let c: int = "abc".chars();`, []string{
+			"test.rs:1: cannot use int as string | array | hash | range in argument 1 of `len`",
+			"test.rs:1: cannot use array[string] as int in let c",
+		}},
+		{`let s: string = join(["a", 1], ","); let t = spawn fn() { 1 }; let r: int = join(t); let m: string = ["a"].join(",");`, []string{}},
+		{`let h = {"a": 1}; let v: int = h["a"] ?? 0; let w: int = h.a;`, []string{}},
+		{`let v = null; let w: int = v ?? "x";`, []string{"test.rs:1: cannot use string as int in let w"}},
+		{`for (i, s in ["a"]) { let n: int = i; let m: int = s; }`, []string{"test.rs:1: cannot use string as int in let m"}},
+		{`struct Point { x, y; fn norm(self, k: int): double { 1 } }
+		  let p: Point = Point(1, 2);
+		  let n: string = p.norm(1);
+		  p.norm("a");
+		  p.z;
+		  Point(1, 2, 3);`, []string{
+			"test.rs:3: cannot use double as string in let n",
+			"test.rs:4: cannot use string as int in argument 1 of `norm`",
+			"test.rs:5: undefined member z of Point",
+			"test.rs:6: wrong number of arguments to `Point`; got=3, expected=0..2",
+		}},
+		{`eval("sys", "len", 1); let n: string = eval("sys", "len", "abc"); eval("sys", "other", 1);`, []string{
+			"test.rs:1: cannot use int as string | array | hash in argument 1 of `sys.len`",
+			"test.rs:1: cannot use int as string in let n",
+		}},
+		{`call("sys", "tick", fn(s) { s }, "10"); call("sys", "tick", fn(a, b) { a }, 10);`, []string{
+			"test.rs:1: cannot use string as int in argument 2 of `sys.tick`",
+			"test.rs:1: cannot use fn(any, any) as fn(string) in argument 1 of `sys.tick`",
+		}},
+		{`# m "fixtures/math.rs"; m.add(1, 2.5); let s: string = m.half(1); m.sub(1); m._scale;`, []string{
+			"test.rs:1: cannot use double as int in argument 2 of `add`",
+			"test.rs:1: cannot use double as string in let s",
+			"test.rs:1: undefined name sub in the module",
+			"test.rs:1: _scale is private to the module",
+		}},
+		{`# {add, half as h} from "fixtures/math.rs"; add(1, "2"); let s: string = h(1);`, []string{
+			"test.rs:1: cannot use string as int in argument 2 of `add`",
+			"test.rs:1: cannot use double as string in let s",
+		}},
+		{`# * from "fixtures/shapes.rs"; area(1, "2"); let r: Rect = Rect(1, 2); helper(1);`, []string{
+			"test.rs:1: cannot use string as int in argument 2 of `area`",
+		}},
+		{`# s "fixtures/shapes.rs"; s.helper; s.area(1, 2); # {_unit, nope} from "fixtures/shapes.rs";`, []string{
+			"test.rs:1: helper is private to the module",
+			"test.rs:1: _unit is private to the module",
+			"test.rs:1: undefined name nope in the module",
+		}},
+		{`# m "fixtures/missing.rs"; m.add(1);`, []string{
+			"test.rs:1: unable to load included script fixtures/missing.rs: not found next to test.rs, in the project root, rash_modules or RASH_PATH",
+		}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, check(t, test.input), test.input)
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	c := checker.New(nil)
+	assert.Empty(t, c.CheckFile("fixtures/math.rs"))
+
+	messages := []string{}
+	for _, d := range checker.New(nil).CheckFile("fixtures/app.rs") {
+		messages = append(messages, d.String())
+	}
+	assert.Equal(t, []string{"fixtures/app.rs:2: cannot use int as string in let total"}, messages)
+
+	messages = []string{}
+	for _, d := range checker.New(nil).CheckFile("fixtures/cycle_a.rs") {
+		messages = append(messages, d.String())
+	}
+	assert.Equal(t, []string{"fixtures/cycle_b.rs:1: import cycle: fixtures/cycle_a.rs -> fixtures/cycle_b.rs -> fixtures/cycle_a.rs"}, messages)
+
+	diagnostics := checker.New(nil).CheckFile("fixtures/missing.rs")
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, "fixtures/missing.rs:0: unable to load module fixtures/missing.rs: open fixtures/missing.rs: no such file or directory", diagnostics[0].String())
+}
@@ -0,0 +1,2 @@
+# m "math.rs";
+let total: string = m.add(1, 2);
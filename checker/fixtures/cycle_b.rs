@@ -0,0 +1,2 @@
+# a "cycle_a.rs";
+let b = 2;
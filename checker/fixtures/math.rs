@@ -0,0 +1,4 @@
+let add = fn(a: int, b: int): int { a + b };
+let half = fn(x: double) { x / 2 };
+let name = "math";
+let _scale = 2;
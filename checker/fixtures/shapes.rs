@@ -0,0 +1,4 @@
+let _unit = 1;
+let helper = fn(x) { x };
+export let area = fn(w: int, h: int): int { w * h };
+export struct Rect { w, h; }
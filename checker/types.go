@@ -0,0 +1,231 @@
+package checker
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"strings"
+)
+
+// Type is a static type of value, it's declared by annotation or inferred from the code
+type Type struct {
+	Name     string           // any, int, double, string, bool, null, array, hash, fn, range, task, promise, channel, module or struct name
+	Elem     *Type            // optional element type of array and hash
+	Params   []*Type          // parameter types of function
+	Names    []string         // parameter names of function, they are checked against named arguments
+	Optional int              // number of the last parameters with default values
+	Variadic bool             // the last parameter type is the type of the rest arguments
+	Result   *Type            // result type of function
+	Union    []*Type          // alternatives of union type
+	Members  map[string]*Type // members of module and struct instance
+	Exports  map[string]bool  // exported names of module, nil if all names except the ones starting with underscore are public
+}
+
+var (
+	anyType    = &Type{Name: "any"}
+	intType    = &Type{Name: "int"}
+	doubleType = &Type{Name: "double"}
+	stringType = &Type{Name: "string"}
+	boolType   = &Type{Name: "bool"}
+	nullType   = &Type{Name: "null"}
+	rangeType  = &Type{Name: "range"}
+	taskType   = &Type{Name: "task"}
+)
+
+// typeNames are the names of builtin types, struct names are types of their instances
+var typeNames = map[string]bool{
+	"any": true, "int": true, "double": true, "string": true, "bool": true, "null": true,
+	"array": true, "hash": true, "range": true, "task": true, "promise": true, "channel": true,
+}
+
+func (t *Type) String() string {
+	if len(t.Union) != 0 {
+		alternatives := make([]string, len(t.Union))
+		for i, alternative := range t.Union {
+			alternatives[i] = alternative.String()
+		}
+		return strings.Join(alternatives, " | ")
+	}
+	if t.Name != "fn" {
+		if t.Elem != nil {
+			return t.Name + "[" + t.Elem.String() + "]"
+		}
+		return t.Name
+	}
+
+	params := make([]string, len(t.Params))
+	for i, param := range t.Params {
+		params[i] = param.String()
+	}
+	if t.Variadic {
+		params[len(params)-1] = "..." + params[len(params)-1]
+	}
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if t.Result != nil && t.Result != anyType {
+		out += ": " + t.Result.String()
+	}
+	return out
+}
+
+func arrayOf(elem *Type) *Type {
+	return &Type{Name: "array", Elem: elem}
+}
+
+// result returns the result type of function, any if it's unknown
+func (t *Type) result() *Type {
+	if t.Result == nil {
+		return anyType
+	}
+	return t.Result
+}
+
+// elem returns the element type of array or hash, any if it's unknown
+func (t *Type) elem() *Type {
+	if t.Elem == nil {
+		return anyType
+	}
+	return t.Elem
+}
+
+// fromAnnotation converts the annotation to type, the structs are the types of struct instances by name.
+// The unknown type names are returned to be reported
+func fromAnnotation(annotation *ast.TypeAnnotation, structs map[string]*Type) (*Type, []string) {
+	unknown := []string{}
+	var convert func(a *ast.TypeAnnotation) *Type
+	convert = func(a *ast.TypeAnnotation) *Type {
+		if len(a.Union) != 0 {
+			t := &Type{}
+			for _, alternative := range a.Union {
+				t = join(t, convert(alternative))
+			}
+			return t
+		}
+		if a.Name == "fn" {
+			t := &Type{Name: "fn", Variadic: a.Variadic, Result: anyType}
+			for _, param := range a.Parameters {
+				t.Params = append(t.Params, convert(param))
+			}
+			if a.Result != nil {
+				t.Result = convert(a.Result)
+			}
+			return t
+		}
+		if s, ok := structs[a.Name]; ok {
+			return s
+		}
+		if !typeNames[a.Name] {
+			unknown = append(unknown, a.Name)
+			return anyType
+		}
+		t := &Type{Name: a.Name}
+		if len(a.Parameters) != 0 {
+			t.Elem = convert(a.Parameters[0])
+		}
+		return t
+	}
+	return convert(annotation), unknown
+}
+
+// join returns the type of value which is either of the types, an empty type is the initial value of join
+func join(left, right *Type) *Type {
+	switch {
+	case left.Name == "" && len(left.Union) == 0:
+		return right
+	case left.Name == "any" || right.Name == "any":
+		return anyType
+	}
+
+	alternatives := append(alternativesOf(left), alternativesOf(right)...)
+	union := []*Type{}
+	seen := map[string]bool{}
+	for _, alternative := range alternatives {
+		if !seen[alternative.String()] {
+			seen[alternative.String()] = true
+			union = append(union, alternative)
+		}
+	}
+	if len(union) == 1 {
+		return union[0]
+	}
+	return &Type{Union: union}
+}
+
+func alternativesOf(t *Type) []*Type {
+	if len(t.Union) != 0 {
+		return t.Union
+	}
+	return []*Type{t}
+}
+
+// withoutNull removes null from the alternatives of union
+func withoutNull(t *Type) *Type {
+	result := &Type{}
+	for _, alternative := range alternativesOf(t) {
+		if alternative.Name != "null" {
+			result = join(result, alternative)
+		}
+	}
+	if result.Name == "" && len(result.Union) == 0 {
+		return nullType
+	}
+	return result
+}
+
+// assignable checks the value of type from can be used where the type to is expected.
+// The types are gradual: any is assignable to and from any type, integers are accepted as doubles
+func assignable(from, to *Type) bool {
+	switch {
+	case from.Name == "any" || to.Name == "any":
+		return true
+	case len(from.Union) != 0:
+		for _, alternative := range from.Union {
+			if !assignable(alternative, to) {
+				return false
+			}
+		}
+		return true
+	case len(to.Union) != 0:
+		for _, alternative := range to.Union {
+			if assignable(from, alternative) {
+				return true
+			}
+		}
+		return false
+	case from.Name == "int" && to.Name == "double":
+		return true
+	case from.Name != to.Name:
+		return false
+	case from.Name == "fn":
+		return assignableFunction(from, to)
+	case from.Elem != nil && to.Elem != nil:
+		return assignable(from.Elem, to.Elem)
+	default:
+		return true
+	}
+}
+
+// assignableFunction checks the function accepts the arguments of expected function type and its result is expected
+func assignableFunction(from, to *Type) bool {
+	required := len(from.Params) - from.Optional
+	if from.Variadic {
+		required--
+	}
+	if len(to.Params) < required || (len(to.Params) > len(from.Params) && !from.Variadic) {
+		return false
+	}
+	for i, param := range to.Params {
+		if !assignable(param, from.param(i)) {
+			return false
+		}
+	}
+	return assignable(from.result(), to.result())
+}
+
+// param returns the type of i-th argument of function, the rest arguments have the type of variadic parameter
+func (t *Type) param(i int) *Type {
+	if i < len(t.Params) {
+		return t.Params[i]
+	}
+	if t.Variadic && len(t.Params) > 0 {
+		return t.Params[len(t.Params)-1]
+	}
+	return anyType
+}
@@ -0,0 +1,363 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/YReshetko/rash-lang/debugger"
+	"github.com/YReshetko/rash-lang/evaluator"
+	"github.com/YReshetko/rash-lang/loaders"
+	"github.com/YReshetko/rash-lang/lsp"
+	"github.com/YReshetko/rash-lang/objects"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ThreadID is the only thread reported to the client, the script is evaluated by the event loop
+// and the spawned tasks never run concurrently, so all of them are stopped together
+const ThreadID = 1
+
+// Server is the debug adapter of rash scripts talking Debug Adapter Protocol over the reader and writer,
+// usually stdin and stdout. The script is launched when the client is done with the configuration,
+// its output is sent to the client by `output` events
+type Server struct {
+	// Loader resolves and evaluates the launched script and included modules
+	Loader *loaders.Loader
+
+	in         *bufio.Reader
+	out        io.Writer
+	mu         sync.Mutex // guards seq and writes, the events are sent by the script goroutine as well
+	seq        int
+	debugger   *debugger.Debugger
+	launch     *LaunchArguments
+	started    bool
+	references []interface{} // the scopes and structured values by variablesReference-1, reset when the script is resumed
+}
+
+// New creates the server reading the requests from in and writing the responses and events to out
+func New(in io.Reader, out io.Writer) *Server {
+	s := &Server{
+		Loader:   loaders.Default,
+		in:       bufio.NewReader(in),
+		out:      out,
+		debugger: debugger.New(),
+	}
+	s.debugger.OnStop = func(reason string) {
+		_ = s.event("stopped", StoppedEvent{Reason: reason, ThreadID: ThreadID, AllThreadsStopped: true})
+	}
+	return s
+}
+
+// Serve handles the requests until `disconnect` request or the end of input
+func (s *Server) Serve() error {
+	for {
+		content, err := lsp.ReadMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		req := Request{}
+		if err := json.Unmarshal(content, &req); err != nil {
+			return fmt.Errorf("invalid request: %v", err)
+		}
+		body, err := s.handle(req.Command, req.Arguments)
+		if err := s.respond(req, body, err); err != nil {
+			return err
+		}
+		switch req.Command {
+		case "initialize":
+			if err := s.event("initialized", nil); err != nil {
+				return err
+			}
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+func (s *Server) send(message func(seq int) interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return lsp.WriteMessage(s.out, message(s.seq))
+}
+
+func (s *Server) respond(req Request, body interface{}, err error) error {
+	return s.send(func(seq int) interface{} {
+		resp := Response{Seq: seq, Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+		if err != nil {
+			resp.Message = err.Error()
+		}
+		return resp
+	})
+}
+
+func (s *Server) event(name string, body interface{}) error {
+	return s.send(func(seq int) interface{} {
+		return Event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+func (s *Server) handle(command string, arguments json.RawMessage) (interface{}, error) {
+	switch command {
+	case "initialize":
+		return map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsConditionalBreakpoints":   true,
+			"supportsEvaluateForHovers":        true,
+		}, nil
+	case "launch":
+		args := &LaunchArguments{}
+		if err := decode(arguments, args); err != nil {
+			return nil, err
+		}
+		if args.Program == "" {
+			return nil, fmt.Errorf("program to debug is not set")
+		}
+		s.launch = args
+		s.debugger.StopOnEntry = args.StopOnEntry
+		return nil, nil
+	case "setBreakpoints":
+		args := SetBreakpointsArguments{}
+		if err := decode(arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args), nil
+	case "setExceptionBreakpoints":
+		return map[string]interface{}{"breakpoints": []Breakpoint{}}, nil
+	case "configurationDone":
+		return nil, s.start()
+	case "threads":
+		return map[string]interface{}{"threads": []Thread{{ID: ThreadID, Name: "main"}}}, nil
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes":
+		args := ScopesArguments{}
+		if err := decode(arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.scopes(args.FrameID)
+	case "variables":
+		args := VariablesArguments{}
+		if err := decode(arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args.VariablesReference)
+	case "evaluate":
+		args := EvaluateArguments{}
+		if err := decode(arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.evaluate(args)
+	case "continue":
+		return map[string]bool{"allThreadsContinued": true}, s.resume(s.debugger.Continue)
+	case "next":
+		return nil, s.resume(s.debugger.Next)
+	case "stepIn":
+		return nil, s.resume(s.debugger.StepIn)
+	case "stepOut":
+		return nil, s.resume(s.debugger.StepOut)
+	case "pause":
+		s.debugger.Pause()
+		return nil, nil
+	case "disconnect":
+		s.debugger.ClearBreakpoints()
+		if s.debugger.Paused() {
+			return nil, s.resume(s.debugger.Continue)
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown command %s", command)
+	}
+}
+
+func decode(arguments json.RawMessage, v interface{}) error {
+	if len(arguments) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(arguments, v); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) setBreakpoints(args SetBreakpointsArguments) map[string]interface{} {
+	requested := make([]debugger.Breakpoint, 0, len(args.Breakpoints))
+	for _, b := range args.Breakpoints {
+		requested = append(requested, debugger.Breakpoint{Line: b.Line, Condition: b.Condition})
+	}
+	breakpoints := []Breakpoint{}
+	for _, b := range s.debugger.SetBreakpoints(args.Source.Path, requested) {
+		breakpoints = append(breakpoints, Breakpoint{Verified: b.Verified, Line: b.Line, Message: b.Message, Source: args.Source})
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}
+}
+
+// start runs the launched script with the debugger attached, `exited` and `terminated` events are sent when it's done
+func (s *Server) start() error {
+	if s.launch == nil {
+		return fmt.Errorf("the script is not launched")
+	}
+	if s.started {
+		return nil
+	}
+	s.started = true
+	if s.launch.Root != "" {
+		s.Loader.Root = s.launch.Root
+	}
+	evaluator.Strict = s.launch.Strict
+	evaluator.Debug = s.debugger
+	stdout := evaluator.Output
+	evaluator.Output = &output{server: s, category: "stdout"}
+
+	go func() {
+		exitCode := 0
+		if err := s.run(s.launch.Program); err != nil {
+			_ = s.event("output", OutputEvent{Category: "stderr", Output: err.Error() + "\n"})
+			exitCode = 1
+		}
+		evaluator.Debug = nil
+		evaluator.Output = stdout
+		_ = s.event("exited", ExitedEvent{ExitCode: exitCode})
+		_ = s.event("terminated", nil)
+	}()
+	return nil
+}
+
+func (s *Server) run(program string) error {
+	obj, err := s.Loader.Run(program, objects.NewEnvironment())
+	if err != nil {
+		return err
+	}
+	if errObj, ok := obj.(*objects.Error); ok {
+		return scriptError(errObj)
+	}
+	if errObj := evaluator.RunEventLoop(); errObj != nil {
+		return scriptError(errObj)
+	}
+	return nil
+}
+
+func scriptError(errObj *objects.Error) error {
+	return fmt.Errorf("%s\nStackTrace:\n%s", errObj.Inspect(), strings.Join(errObj.Stack, ";\n"))
+}
+
+// resume forgets the variable references as the values may be changed by the script
+func (s *Server) resume(step func() error) error {
+	s.references = nil
+	return step()
+}
+
+func (s *Server) stackTrace() (StackTraceResponse, error) {
+	frames, err := s.debugger.Frames()
+	if err != nil {
+		return StackTraceResponse{}, err
+	}
+	stack := make([]StackFrame, 0, len(frames))
+	for _, f := range frames {
+		stack = append(stack, StackFrame{
+			ID:     f.ID,
+			Name:   f.Name,
+			Source: source(f.File),
+			Line:   f.Line,
+			Column: 1,
+		})
+	}
+	return StackTraceResponse{StackFrames: stack, TotalFrames: len(stack)}, nil
+}
+
+func source(file string) Source {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		path = file
+	}
+	return Source{Name: filepath.Base(file), Path: path}
+}
+
+func (s *Server) scopes(frameID int) (map[string]interface{}, error) {
+	scopes, err := s.debugger.Scopes(frameID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		result = append(result, Scope{Name: scope.Name, VariablesReference: s.reference(scope.Variables)})
+	}
+	return map[string]interface{}{"scopes": result}, nil
+}
+
+func (s *Server) variables(reference int) (map[string]interface{}, error) {
+	if reference < 1 || reference > len(s.references) {
+		return nil, fmt.Errorf("unknown variables reference %d", reference)
+	}
+	var vars []debugger.Variable
+	switch r := s.references[reference-1].(type) {
+	case []debugger.Variable:
+		vars = r
+	case objects.Object:
+		vars = debugger.Children(r)
+	}
+	result := make([]Variable, 0, len(vars))
+	for _, v := range vars {
+		result = append(result, Variable{
+			Name:               v.Name,
+			Value:              debugger.Value(v.Value),
+			Type:               string(v.Value.Type()),
+			VariablesReference: s.structured(v.Value),
+		})
+	}
+	return map[string]interface{}{"variables": result}, nil
+}
+
+func (s *Server) evaluate(args EvaluateArguments) (EvaluateResponse, error) {
+	frameID := args.FrameID
+	if frameID == 0 {
+		frameID = 1
+		if frames, err := s.debugger.Frames(); err == nil && len(frames) != 0 {
+			frameID = frames[0].ID
+		}
+	}
+	result, err := s.debugger.Evaluate(args.Expression, frameID)
+	if err != nil {
+		return EvaluateResponse{}, err
+	}
+	if errObj, ok := result.(*objects.Error); ok {
+		return EvaluateResponse{}, errors.New(errObj.Message)
+	}
+	return EvaluateResponse{
+		Result:             debugger.Value(result),
+		Type:               string(result.Type()),
+		VariablesReference: s.structured(result),
+	}, nil
+}
+
+func (s *Server) reference(v interface{}) int {
+	s.references = append(s.references, v)
+	return len(s.references)
+}
+
+// structured returns the reference of value which has elements or fields, or 0 otherwise
+func (s *Server) structured(value objects.Object) int {
+	if len(debugger.Children(value)) == 0 {
+		return 0
+	}
+	return s.reference(value)
+}
+
+// output sends the text printed by the script to the client
+type output struct {
+	server   *Server
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if err := o.server.event("output", OutputEvent{Category: o.category, Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,204 @@
+package dap_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/YReshetko/rash-lang/dap"
+	"github.com/YReshetko/rash-lang/loaders"
+	"github.com/YReshetko/rash-lang/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+const script = `let add = fn(a, b) {
+    let sum = a + b;
+    sum
+};
+let total = 0;
+for (i in 0..3) {
+    total = add(total, i);
+}
+println(total);
+`
+
+// client is a scripted debug adapter client talking to the server over pipes
+type client struct {
+	t      *testing.T
+	in     io.WriteCloser
+	out    *bufio.Reader
+	seq    int
+	done   chan error
+	events []dap.Event // events received while waiting for responses
+}
+
+func start(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	server := dap.New(inR, outW)
+	server.Loader = loaders.New(fstest.MapFS{"main.rs": {Data: []byte(script)}})
+
+	c := &client{t: t, in: inW, out: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		c.done <- server.Serve()
+		_ = outW.Close()
+	}()
+	return c
+}
+
+// message is either a response or an event
+type message struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func (c *client) read() message {
+	content, err := lsp.ReadMessage(c.out)
+	require.NoError(c.t, err)
+	msg := message{}
+	require.NoError(c.t, json.Unmarshal(content, &msg))
+	return msg
+}
+
+// request sends the command and decodes the body of successful response, the response is returned to check errors
+func (c *client) request(command string, arguments interface{}, body interface{}) message {
+	c.seq++
+	require.NoError(c.t, lsp.WriteMessage(c.in, map[string]interface{}{
+		"seq": c.seq, "type": "request", "command": command, "arguments": arguments,
+	}))
+	for {
+		msg := c.read()
+		if msg.Type == "event" {
+			c.events = append(c.events, dap.Event{Event: msg.Event, Body: msg.Body})
+			continue
+		}
+		require.Equal(c.t, c.seq, msg.RequestSeq)
+		if body != nil {
+			require.True(c.t, msg.Success, msg.Message)
+			require.NoError(c.t, json.Unmarshal(msg.Body, body))
+		}
+		return msg
+	}
+}
+
+// event waits for the event and returns its body
+func (c *client) event(name string) json.RawMessage {
+	for {
+		var e dap.Event
+		if len(c.events) != 0 {
+			e, c.events = c.events[0], c.events[1:]
+		} else {
+			msg := c.read()
+			require.Equal(c.t, "event", msg.Type, "unexpected response to %d", msg.RequestSeq)
+			e = dap.Event{Event: msg.Event, Body: msg.Body}
+		}
+		if e.Event == name {
+			body, _ := e.Body.(json.RawMessage)
+			return body
+		}
+	}
+}
+
+func (c *client) stopped() string {
+	stopped := dap.StoppedEvent{}
+	require.NoError(c.t, json.Unmarshal(c.event("stopped"), &stopped))
+	return stopped.Reason
+}
+
+func (c *client) variables(reference int) map[string]string {
+	body := struct{ Variables []dap.Variable }{}
+	c.request("variables", dap.VariablesArguments{VariablesReference: reference}, &body)
+	vars := map[string]string{}
+	for _, v := range body.Variables {
+		vars[v.Name] = v.Value
+	}
+	return vars
+}
+
+func TestServer(t *testing.T) {
+	path, err := filepath.Abs("main.rs")
+	require.NoError(t, err)
+
+	c := start(t)
+	capabilities := map[string]bool{}
+	c.request("initialize", map[string]string{"adapterID": "rash"}, &capabilities)
+	assert.True(t, capabilities["supportsConditionalBreakpoints"])
+	c.event("initialized")
+
+	msg := c.request("launch", map[string]interface{}{}, nil)
+	assert.False(t, msg.Success)
+	assert.Equal(t, "program to debug is not set", msg.Message)
+	c.request("launch", dap.LaunchArguments{Program: "main.rs"}, nil)
+
+	breakpoints := struct{ Breakpoints []dap.Breakpoint }{}
+	c.request("setBreakpoints", dap.SetBreakpointsArguments{
+		Source:      dap.Source{Path: path},
+		Breakpoints: []dap.SourceBreakpoint{{Line: 7, Condition: "i == 2"}, {Line: 3, Condition: "a =="}},
+	}, &breakpoints)
+	require.Len(t, breakpoints.Breakpoints, 2)
+	assert.True(t, breakpoints.Breakpoints[0].Verified)
+	assert.False(t, breakpoints.Breakpoints[1].Verified)
+	assert.NotEmpty(t, breakpoints.Breakpoints[1].Message)
+
+	c.request("configurationDone", nil, nil)
+	assert.Equal(t, "breakpoint", c.stopped())
+
+	stack := dap.StackTraceResponse{}
+	c.request("stackTrace", map[string]int{"threadId": dap.ThreadID}, &stack)
+	assert.Equal(t, []dap.StackFrame{{ID: 1, Name: "main", Source: dap.Source{Name: "main.rs", Path: path}, Line: 7, Column: 1}}, stack.StackFrames)
+
+	result := dap.EvaluateResponse{}
+	c.request("evaluate", dap.EvaluateArguments{Expression: "total + i"}, &result)
+	assert.Equal(t, dap.EvaluateResponse{Result: "3", Type: "INTEGER"}, result)
+
+	c.request("stepIn", map[string]int{"threadId": dap.ThreadID}, nil)
+	assert.Equal(t, "step", c.stopped())
+	c.request("stackTrace", map[string]int{"threadId": dap.ThreadID}, &stack)
+	require.Len(t, stack.StackFrames, 2)
+	assert.Equal(t, "add", stack.StackFrames[0].Name)
+	assert.Equal(t, 2, stack.StackFrames[0].Line)
+
+	scopes := struct{ Scopes []dap.Scope }{}
+	c.request("scopes", dap.ScopesArguments{FrameID: stack.StackFrames[0].ID}, &scopes)
+	require.Len(t, scopes.Scopes, 2)
+	assert.Equal(t, "Locals", scopes.Scopes[0].Name)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, c.variables(scopes.Scopes[0].VariablesReference))
+	assert.Equal(t, map[string]string{"add": "fn(a, b)", "total": "1"}, c.variables(scopes.Scopes[1].VariablesReference))
+
+	c.request("evaluate", dap.EvaluateArguments{Expression: `[a, {"name": "b"}]`, FrameID: stack.StackFrames[0].ID}, &result)
+	require.NotZero(t, result.VariablesReference)
+	assert.Equal(t, map[string]string{"[0]": "1", "[1]": `{name:b}`}, c.variables(result.VariablesReference))
+
+	msg = c.request("evaluate", dap.EvaluateArguments{Expression: "unknown"}, nil)
+	assert.False(t, msg.Success)
+	assert.Equal(t, "identifier not found: unknown", msg.Message)
+
+	c.request("stepOut", map[string]int{"threadId": dap.ThreadID}, nil)
+	assert.Equal(t, "step", c.stopped())
+	c.request("stackTrace", map[string]int{"threadId": dap.ThreadID}, &stack)
+	assert.Equal(t, 9, stack.StackFrames[0].Line)
+
+	c.request("continue", map[string]int{"threadId": dap.ThreadID}, nil)
+	output := dap.OutputEvent{}
+	require.NoError(t, json.Unmarshal(c.event("output"), &output))
+	assert.Equal(t, dap.OutputEvent{Category: "stdout", Output: "3\n"}, output)
+	exited := dap.ExitedEvent{}
+	require.NoError(t, json.Unmarshal(c.event("exited"), &exited))
+	assert.Equal(t, 0, exited.ExitCode)
+	c.event("terminated")
+
+	msg = c.request("stackTrace", map[string]int{"threadId": dap.ThreadID}, nil)
+	assert.Equal(t, "the script is running", msg.Message)
+	msg = c.request("unknown", nil, nil)
+	assert.Equal(t, "unknown command unknown", msg.Message)
+
+	c.request("disconnect", nil, nil)
+	assert.NoError(t, <-c.done)
+}
@@ -0,0 +1,471 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/evaluator"
+	"github.com/YReshetko/rash-lang/lexer"
+	"github.com/YReshetko/rash-lang/objects"
+	"github.com/YReshetko/rash-lang/parser"
+	"github.com/YReshetko/rash-lang/tokens"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The reasons the script is stopped
+const (
+	StopEntry      = "entry"      // the first statement with StopOnEntry
+	StopBreakpoint = "breakpoint" // a breakpoint is hit and its condition is true
+	StopStep       = "step"       // a step is done
+	StopPause      = "pause"      // the script is paused on request
+)
+
+const (
+	run = iota
+	stepIn
+	stepOver
+	stepOut
+)
+
+// ErrRunning is returned by the requests which need the script to be paused
+var ErrRunning = errors.New("the script is running")
+
+// Breakpoint stops the script before the statement starting on the line is evaluated
+type Breakpoint struct {
+	Line int
+	// Condition is an optional expression, the script is stopped only if it's evaluated to a truthy value
+	Condition string
+	// Verified is set by SetBreakpoints, it's false if the condition can't be parsed
+	Verified bool
+	// Message explains why the breakpoint isn't verified
+	Message string
+
+	condition *ast.Program
+}
+
+// Frame is the script function being evaluated, the bottom frame is the main script
+type Frame struct {
+	ID          int // the position of frame in the stack starting with 1
+	Name        string
+	File        string
+	Line        int
+	Environment *objects.Environment // the environment of the current statement
+
+	fn        *objects.Function
+	base      *objects.Environment // the environment of the function call, nil for the main script
+	statement ast.Statement        // the current statement
+}
+
+// Scope is a group of variables visible in the frame
+type Scope struct {
+	Name      string
+	Variables []Variable
+}
+
+// Variable is the name bound to the value, the elements of arrays and hashes and the fields of instances as well
+type Variable struct {
+	Name  string
+	Value objects.Object
+}
+
+// Debugger is attached to the evaluator by evaluator.Debug, it stops the script on breakpoints, steps and pauses.
+// The stopped script is blocked until it's resumed by Continue or a step request
+type Debugger struct {
+	// StopOnEntry stops the script before the first statement
+	StopOnEntry bool
+	// OnStop is called with the reason when the script is stopped, it must not block
+	OnStop func(reason string)
+
+	mu          sync.Mutex
+	breakpoints map[string][]Breakpoint // by absolute path of the script
+	paths       map[string]string       // absolute paths of the script file names
+	frames      []*Frame
+	mode        int
+	depth       int // number of frames when the step is requested
+	started     bool
+	pause       bool
+	paused      bool
+	evaluating  bool             // the hooks are ignored while the debugger evaluates an expression
+	commands    chan func() bool // run by the stopped script, it's resumed if true is returned
+}
+
+// New creates the debugger, it stops the script when it's attached by evaluator.Debug
+func New() *Debugger {
+	return &Debugger{
+		breakpoints: map[string][]Breakpoint{},
+		paths:       map[string]string{},
+		frames:      []*Frame{{Name: "main"}},
+		commands:    make(chan func() bool),
+	}
+}
+
+// SetBreakpoints replaces the breakpoints of the script, the breakpoints are returned with the verification result
+func (d *Debugger) SetBreakpoints(file string, breakpoints []Breakpoint) []Breakpoint {
+	verified := make([]Breakpoint, 0, len(breakpoints))
+	for _, b := range breakpoints {
+		b.Verified, b.Message, b.condition = true, "", nil
+		if strings.TrimSpace(b.Condition) != "" {
+			p := parser.New(lexer.New(b.Condition, "<condition>"))
+			b.condition = p.ParseProgram()
+			if len(p.Errors()) != 0 {
+				b.Verified, b.Message = false, strings.Join(p.Errors(), "; ")
+			}
+		}
+		verified = append(verified, b)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[d.abs(file)] = verified
+	return verified
+}
+
+// Breakpoints returns the breakpoints by the absolute path of the script
+func (d *Debugger) Breakpoints() map[string][]Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	breakpoints := make(map[string][]Breakpoint, len(d.breakpoints))
+	for file, b := range d.breakpoints {
+		breakpoints[file] = append([]Breakpoint{}, b...)
+	}
+	return breakpoints
+}
+
+// ClearBreakpoints removes all the breakpoints
+func (d *Debugger) ClearBreakpoints() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = map[string][]Breakpoint{}
+}
+
+// Continue resumes the script until the next breakpoint
+func (d *Debugger) Continue() error { return d.resume(run) }
+
+// Next resumes the script until the next statement of the current or a caller function
+func (d *Debugger) Next() error { return d.resume(stepOver) }
+
+// StepIn resumes the script until the next statement, including the statements of called functions
+func (d *Debugger) StepIn() error { return d.resume(stepIn) }
+
+// StepOut resumes the script until the current function returns to the caller
+func (d *Debugger) StepOut() error { return d.resume(stepOut) }
+
+// Pause stops the script before the next statement
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pause = true
+}
+
+// Paused checks if the script is stopped
+func (d *Debugger) Paused() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.paused
+}
+
+func (d *Debugger) resume(mode int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.paused {
+		return ErrRunning
+	}
+	d.paused = false
+	d.mode, d.depth = mode, len(d.frames)
+	d.commands <- func() bool { return true }
+	return nil
+}
+
+// Frames returns the stack of the stopped script, the topmost frame goes first
+func (d *Debugger) Frames() ([]Frame, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.paused {
+		return nil, ErrRunning
+	}
+	frames := make([]Frame, 0, len(d.frames))
+	for i := len(d.frames) - 1; i >= 0; i-- {
+		f := *d.frames[i]
+		f.ID = i + 1
+		if f.fn != nil {
+			f.Name = functionName(f.fn)
+		}
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
+
+// Scopes returns the local variables of the frame and the global ones of the script the frame belongs to.
+// The names hidden by inner scopes are not listed
+func (d *Debugger) Scopes(frameID int) ([]Scope, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	frame, err := d.frame(frameID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	locals := Scope{Name: "Locals"}
+	env := frame.Environment
+	for ; env != nil && (frame.base == nil || env != frame.base.Outer()); env = env.Outer() {
+		locals.Variables = append(locals.Variables, variables(env, seen)...)
+	}
+	scopes := []Scope{locals}
+	if env == nil {
+		return scopes, nil
+	}
+	for env.Outer() != nil {
+		env = env.Outer()
+	}
+	return append(scopes, Scope{Name: "Globals", Variables: variables(env, seen)}), nil
+}
+
+func variables(env *objects.Environment, seen map[string]bool) []Variable {
+	vars := []Variable{}
+	for _, name := range env.Names() {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		value, _ := env.Get(name)
+		vars = append(vars, Variable{Name: name, Value: value})
+	}
+	return vars
+}
+
+// Evaluate evaluates the expression in the environment of the frame by the stopped script.
+// The expression may declare variables or call functions, the breakpoints are not hit meanwhile
+func (d *Debugger) Evaluate(expression string, frameID int) (objects.Object, error) {
+	p := parser.New(lexer.New(expression, "<eval>"))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "; "))
+	}
+
+	d.mu.Lock()
+	frame, err := d.frame(frameID)
+	if err != nil {
+		d.mu.Unlock()
+		return nil, err
+	}
+	result := make(chan objects.Object, 1)
+	d.commands <- func() bool {
+		result <- d.eval(program, frame.Environment)
+		return false
+	}
+	d.mu.Unlock()
+	return <-result, nil
+}
+
+// frame returns the frame by ID, d.mu must be held
+func (d *Debugger) frame(id int) (*Frame, error) {
+	if !d.paused {
+		return nil, ErrRunning
+	}
+	if id < 1 || id > len(d.frames) {
+		return nil, fmt.Errorf("unknown frame %d", id)
+	}
+	return d.frames[id-1], nil
+}
+
+func (d *Debugger) eval(program *ast.Program, env *objects.Environment) objects.Object {
+	d.evaluating = true
+	defer func() { d.evaluating = false }()
+	result := evaluator.Evaluate(program, env)
+	if result == nil {
+		return objects.NULL
+	}
+	return result
+}
+
+// Statement stops the script before the statement if it's requested by a step or a breakpoint is hit
+func (d *Debugger) Statement(statement ast.Statement, environment *objects.Environment) {
+	if d.evaluating {
+		return
+	}
+	token := statementToken(statement)
+
+	d.mu.Lock()
+	top := d.frames[len(d.frames)-1]
+	// A line with several statements hits the breakpoint once, but the statement repeated by a loop hits it again
+	moved := top.File != token.FileName || top.Line != token.LineNumber || top.statement == statement
+	top.File, top.Line, top.Environment, top.statement = token.FileName, token.LineNumber, environment, statement
+	reason := d.reason()
+	var candidates []Breakpoint
+	if reason == "" && moved {
+		for _, b := range d.breakpoints[d.abs(token.FileName)] {
+			if b.Line == token.LineNumber && b.Verified {
+				candidates = append(candidates, b)
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	for _, b := range candidates {
+		if b.condition == nil || truthy(d.eval(b.condition, environment)) {
+			reason = StopBreakpoint
+			break
+		}
+	}
+	if reason != "" {
+		d.stop(reason)
+	}
+}
+
+// reason checks if the script is stopped by entry, pause or step, d.mu must be held
+func (d *Debugger) reason() string {
+	depth := len(d.frames)
+	switch {
+	case !d.started:
+		d.started = true
+		if d.StopOnEntry {
+			return StopEntry
+		}
+	case d.pause:
+		return StopPause
+	case d.mode == stepIn,
+		d.mode == stepOver && depth <= d.depth,
+		d.mode == stepOut && depth < d.depth:
+		return StopStep
+	}
+	return ""
+}
+
+// stop blocks the script and runs the commands until it's resumed
+func (d *Debugger) stop(reason string) {
+	d.mu.Lock()
+	d.paused, d.pause, d.mode = true, false, run
+	d.mu.Unlock()
+
+	if d.OnStop != nil {
+		d.OnStop(reason)
+	}
+	for command := range d.commands {
+		if command() {
+			return
+		}
+	}
+}
+
+// Call pushes the frame of called function
+func (d *Debugger) Call(fn *objects.Function, environment *objects.Environment) {
+	if d.evaluating {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.frames = append(d.frames, &Frame{
+		File:        fn.Body.Token.FileName,
+		Line:        fn.Body.Token.LineNumber,
+		Environment: environment,
+		fn:          fn,
+		base:        environment,
+	})
+}
+
+// Return pops the frame of the function. Spawned tasks may interleave their calls,
+// so the topmost frame of the function is removed
+func (d *Debugger) Return(fn *objects.Function, _ objects.Object) {
+	if d.evaluating {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := len(d.frames) - 1; i > 0; i-- {
+		if d.frames[i].fn == fn {
+			d.frames = append(d.frames[:i], d.frames[i+1:]...)
+			return
+		}
+	}
+}
+
+// abs returns the absolute path of the script file name, d.mu must be held
+func (d *Debugger) abs(file string) string {
+	if path, ok := d.paths[file]; ok {
+		return path
+	}
+	path, err := filepath.Abs(file)
+	if err != nil {
+		path = file
+	}
+	d.paths[file] = path
+	return path
+}
+
+// Children returns the elements of arrays, the pairs of hashes sorted by key and the fields of instances
+func Children(obj objects.Object) []Variable {
+	children := []Variable{}
+	switch o := obj.(type) {
+	case *objects.Array:
+		for i, e := range o.Elements {
+			children = append(children, Variable{Name: fmt.Sprintf("[%d]", i), Value: e})
+		}
+	case *objects.Hash:
+		for _, pair := range o.Pairs {
+			children = append(children, Variable{Name: pair.Key.Inspect(), Value: pair.Value})
+		}
+		sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+	case *objects.Instance:
+		for i, field := range o.Struct.Fields {
+			children = append(children, Variable{Name: field, Value: o.Values[i]})
+		}
+	}
+	return children
+}
+
+// Value is the short presentation of the value, the functions are shown by signature
+func Value(obj objects.Object) string {
+	switch o := obj.(type) {
+	case *objects.Function:
+		return "fn(" + ast.FormatParameters(o.Parameters, nil, o.Defaults, o.Rest) + ")"
+	case *objects.String:
+		return fmt.Sprintf("%q", o.Value)
+	case nil:
+		return "null"
+	}
+	return obj.Inspect()
+}
+
+// functionName finds the name the function is bound to where it's declared, or returns the signature
+func functionName(fn *objects.Function) string {
+	if fn.Environment != nil {
+		for _, name := range fn.Environment.Names() {
+			if value, _ := fn.Environment.Get(name); value == fn {
+				return name
+			}
+		}
+	}
+	return Value(fn)
+}
+
+func truthy(obj objects.Object) bool {
+	return obj != objects.NULL && obj != objects.FALSE && obj.Type() != objects.ERROR_OBJ
+}
+
+func statementToken(statement ast.Statement) tokens.Token {
+	switch s := statement.(type) {
+	case *ast.LetStatement:
+		return s.Token
+	case *ast.ReturnStatement:
+		return s.Token
+	case *ast.BreakStatement:
+		return s.Token
+	case *ast.ContinueStatement:
+		return s.Token
+	case *ast.ExpressionStatement:
+		return s.Token
+	case *ast.DeclarationStatement:
+		return s.Token
+	case *ast.BlockStatement:
+		return s.Token
+	case *ast.StructStatement:
+		return s.Token
+	case *ast.ExportStatement:
+		return s.Token
+	}
+	return tokens.Token{}
+}
@@ -0,0 +1,282 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/YReshetko/rash-lang/loaders"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const help = `Commands:
+  break [<file>:]<line> [if <condition>]  set the breakpoint, the script being debugged is used if file is omitted
+  clear [<file>:]<line>                   remove the breakpoint
+  breakpoints                             list the breakpoints
+  continue | c                            resume until the next breakpoint
+  next | n                                step over the function calls
+  step | s                                step into the function calls
+  out | o                                 step out of the current function
+  backtrace | bt                          print the stack frames
+  frame <id>                              select the frame to print the variables and expressions in
+  vars                                    print the variables of the selected frame
+  print | p <expression>                  evaluate the expression in the selected frame
+  quit | q                                stop debugging
+`
+
+// Terminal is the command line front end of the debugger, the commands are read when the script is stopped
+type Terminal struct {
+	// Loader reads the source lines the script is stopped on
+	Loader *loaders.Loader
+
+	debugger *Debugger
+	file     string // the script the breakpoints are set to by default
+	in       *bufio.Scanner
+	out      io.Writer
+	stops    chan string
+	frame    int // the selected frame
+	sources  map[string][]string
+}
+
+// NewTerminal creates the front end of the debugger of the script, the debugger is stopped on entry
+// to let the breakpoints be set
+func NewTerminal(d *Debugger, file string, in io.Reader, out io.Writer) *Terminal {
+	t := &Terminal{
+		Loader:   loaders.Default,
+		debugger: d,
+		file:     file,
+		in:       bufio.NewScanner(in),
+		out:      out,
+		stops:    make(chan string, 1),
+		sources:  map[string][]string{},
+	}
+	d.StopOnEntry = true
+	d.OnStop = func(reason string) {
+		t.stops <- reason
+	}
+	return t
+}
+
+// Run serves the commands until the script is done or `quit` command, done receives the result of the script
+func (t *Terminal) Run(done <-chan error) error {
+	for {
+		select {
+		case err := <-done:
+			return err
+		case reason := <-t.stops:
+			t.where(reason)
+			if t.prompt() {
+				return nil
+			}
+		}
+	}
+}
+
+// prompt reads the commands until the script is resumed, true is returned to quit
+func (t *Terminal) prompt() bool {
+	for {
+		fmt.Fprint(t.out, "(rash) ")
+		if !t.in.Scan() {
+			fmt.Fprintln(t.out)
+			return true
+		}
+		command, arg := split(strings.TrimSpace(t.in.Text()))
+		var err error
+		switch command {
+		case "":
+			continue
+		case "help", "h":
+			fmt.Fprint(t.out, help)
+		case "break", "b":
+			err = t.setBreakpoint(arg)
+		case "clear":
+			err = t.clearBreakpoint(arg)
+		case "breakpoints":
+			t.listBreakpoints()
+		case "continue", "c":
+			return t.resume(t.debugger.Continue)
+		case "next", "n":
+			return t.resume(t.debugger.Next)
+		case "step", "s":
+			return t.resume(t.debugger.StepIn)
+		case "out", "o":
+			return t.resume(t.debugger.StepOut)
+		case "backtrace", "bt":
+			err = t.backtrace()
+		case "frame":
+			err = t.selectFrame(arg)
+		case "vars":
+			err = t.vars()
+		case "print", "p":
+			err = t.print(arg)
+		case "quit", "q":
+			return true
+		default:
+			err = fmt.Errorf("unknown command %s, type `help` to list the commands", command)
+		}
+		if err != nil {
+			fmt.Fprintln(t.out, err)
+		}
+	}
+}
+
+func (t *Terminal) resume(step func() error) bool {
+	if err := step(); err != nil {
+		fmt.Fprintln(t.out, err)
+	}
+	return false
+}
+
+// where prints the location the script is stopped at and selects the topmost frame
+func (t *Terminal) where(reason string) {
+	frames, err := t.debugger.Frames()
+	if err != nil || len(frames) == 0 {
+		return
+	}
+	top := frames[0]
+	t.frame = top.ID
+	fmt.Fprintf(t.out, "stopped (%s) in %s at %s:%d\n", reason, top.Name, top.File, top.Line)
+	if line, ok := t.source(top.File, top.Line); ok {
+		fmt.Fprintf(t.out, "%5d  %s\n", top.Line, line)
+	}
+}
+
+func (t *Terminal) source(file string, line int) (string, bool) {
+	lines, ok := t.sources[file]
+	if !ok {
+		if src, err := t.Loader.ReadFile(file); err == nil {
+			lines = strings.Split(string(src), "\n")
+		}
+		t.sources[file] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return "", false
+	}
+	return strings.TrimSpace(lines[line-1]), true
+}
+
+// location parses [<file>:]<line>, the file is resolved to the absolute path the breakpoints are kept by
+func (t *Terminal) location(arg string) (string, int, error) {
+	file, lineText := t.file, arg
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		file, lineText = arg[:i], arg[i+1:]
+	}
+	line, err := strconv.Atoi(lineText)
+	if err != nil || line < 1 {
+		return "", 0, fmt.Errorf("invalid location %q, expected [<file>:]<line>", arg)
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return file, line, nil
+}
+
+func (t *Terminal) setBreakpoint(arg string) error {
+	location, condition := arg, ""
+	if i := strings.Index(arg, " if "); i >= 0 {
+		location, condition = strings.TrimSpace(arg[:i]), strings.TrimSpace(arg[i+len(" if "):])
+	}
+	file, line, err := t.location(location)
+	if err != nil {
+		return err
+	}
+	breakpoints := []Breakpoint{}
+	for _, b := range t.debugger.Breakpoints()[file] {
+		if b.Line != line {
+			breakpoints = append(breakpoints, b)
+		}
+	}
+	breakpoints = append(breakpoints, Breakpoint{Line: line, Condition: condition})
+	for _, b := range t.debugger.SetBreakpoints(file, breakpoints) {
+		if b.Line == line && !b.Verified {
+			return fmt.Errorf("invalid condition of breakpoint: %s", b.Message)
+		}
+	}
+	fmt.Fprintf(t.out, "breakpoint at %s:%d\n", file, line)
+	return nil
+}
+
+func (t *Terminal) clearBreakpoint(arg string) error {
+	file, line, err := t.location(arg)
+	if err != nil {
+		return err
+	}
+	breakpoints := []Breakpoint{}
+	for _, b := range t.debugger.Breakpoints()[file] {
+		if b.Line != line {
+			breakpoints = append(breakpoints, b)
+		}
+	}
+	t.debugger.SetBreakpoints(file, breakpoints)
+	return nil
+}
+
+func (t *Terminal) listBreakpoints() {
+	for file, breakpoints := range t.debugger.Breakpoints() {
+		for _, b := range breakpoints {
+			if b.Condition != "" {
+				fmt.Fprintf(t.out, "%s:%d if %s\n", file, b.Line, b.Condition)
+				continue
+			}
+			fmt.Fprintf(t.out, "%s:%d\n", file, b.Line)
+		}
+	}
+}
+
+func (t *Terminal) backtrace() error {
+	frames, err := t.debugger.Frames()
+	if err != nil {
+		return err
+	}
+	for _, f := range frames {
+		marker := " "
+		if f.ID == t.frame {
+			marker = "*"
+		}
+		fmt.Fprintf(t.out, "%s %d %s at %s:%d\n", marker, f.ID, f.Name, f.File, f.Line)
+	}
+	return nil
+}
+
+func (t *Terminal) selectFrame(arg string) error {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return fmt.Errorf("invalid frame %q", arg)
+	}
+	if _, err := t.debugger.Scopes(id); err != nil {
+		return err
+	}
+	t.frame = id
+	return nil
+}
+
+func (t *Terminal) vars() error {
+	scopes, err := t.debugger.Scopes(t.frame)
+	if err != nil {
+		return err
+	}
+	for _, scope := range scopes {
+		fmt.Fprintf(t.out, "%s:\n", scope.Name)
+		for _, v := range scope.Variables {
+			fmt.Fprintf(t.out, "  %s = %s\n", v.Name, Value(v.Value))
+		}
+	}
+	return nil
+}
+
+func (t *Terminal) print(expression string) error {
+	result, err := t.debugger.Evaluate(expression, t.frame)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(t.out, Value(result))
+	return nil
+}
+
+func split(line string) (string, string) {
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		return line[:i], strings.TrimSpace(line[i+1:])
+	}
+	return line, ""
+}
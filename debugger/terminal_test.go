@@ -0,0 +1,103 @@
+package debugger_test
+
+import (
+	"bytes"
+	"github.com/YReshetko/rash-lang/debugger"
+	"github.com/YReshetko/rash-lang/evaluator"
+	"github.com/YReshetko/rash-lang/loaders"
+	"github.com/YReshetko/rash-lang/objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+const script = `let add = fn(a, b) {
+    let sum = a + b;
+    sum
+};
+let total = 0;
+for (i in 0..3) {
+    total = add(total, i);
+}
+println(total);
+`
+
+// output is written by the terminal and the debugged script
+type output struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.Write(p)
+}
+
+func TestTerminal(t *testing.T) {
+	loader := loaders.New(fstest.MapFS{"main.rs": {Data: []byte(script)}})
+	commands := strings.Join([]string{
+		"break 7 if i == 2",
+		"breakpoints",
+		"c",
+		"print total",
+		"step",
+		"bt",
+		"vars",
+		"next",
+		"out",
+		"print total * 10",
+		"unknown",
+		"c",
+	}, "\n")
+	out := &output{}
+
+	d := debugger.New()
+	terminal := debugger.NewTerminal(d, "main.rs", strings.NewReader(commands), out)
+	terminal.Loader = loader
+	evaluator.Debug, evaluator.Output = d, out
+	defer func() { evaluator.Debug, evaluator.Output = nil, nil }()
+
+	done := make(chan error, 1)
+	go func() {
+		obj, err := loader.Run("main.rs", objects.NewEnvironment())
+		if err == nil && obj.Type() == objects.ERROR_OBJ {
+			err = assert.AnError
+		}
+		done <- err
+	}()
+	require.NoError(t, terminal.Run(done))
+
+	path, err := filepath.Abs("main.rs")
+	require.NoError(t, err)
+	expected := `stopped (entry) in main at main.rs:1
+    1  let add = fn(a, b) {
+(rash) breakpoint at ` + path + `:7
+(rash) ` + path + `:7 if i == 2
+(rash) stopped (breakpoint) in main at main.rs:7
+    7  total = add(total, i);
+(rash) 1
+(rash) stopped (step) in add at main.rs:2
+    2  let sum = a + b;
+(rash) * 2 add at main.rs:2
+  1 main at main.rs:7
+(rash) Locals:
+  a = 1
+  b = 2
+Globals:
+  add = fn(a, b)
+  total = 1
+(rash) stopped (step) in add at main.rs:3
+    3  sum
+(rash) stopped (step) in main at main.rs:9
+    9  println(total);
+(rash) 30
+(rash) unknown command unknown, type ` + "`help`" + ` to list the commands
+(rash) 3
+`
+	assert.Equal(t, expected, out.buf.String())
+}
@@ -0,0 +1,143 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+	"reflect"
+	"time"
+)
+
+// Event loop builtins: timers and promises
+func init() {
+	builtins["setTimeout"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		return scheduleTimer("setTimeout", false, args)
+	}}
+	builtins["setInterval"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		return scheduleTimer("setInterval", true, args)
+	}}
+	builtins["clearTimer"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments to `clearTimer`; got=%d, expected=%d", len(args), 1)
+		}
+		id, ok := args[0].(*objects.Integer)
+		if !ok {
+			return newError("`clearTimer` expects integer timer id, but got %s", args[0].Type())
+		}
+		return nativeBoolean(loop.clearTimer(id.Value))
+	}}
+	builtins["promise"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments to `promise`; got=%d, expected=%d", len(args), 1)
+		}
+		promise := objects.NewPromise()
+		resolve := &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+			var value objects.Object = objects.NULL
+			if len(args) > 0 {
+				value = args[0]
+			}
+			settlePromise(promise, value)
+			return objects.NULL
+		}}
+		reject := &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+			var reason objects.Object = objects.NULL
+			if len(args) > 0 {
+				reason = args[0]
+			}
+			promise.Reject(rejection(reason))
+			return objects.NULL
+		}}
+		result := applyFunction(args[0], []objects.Object{resolve, reject})
+		if isError(result) {
+			promise.Reject(result.(*objects.Error))
+		}
+		return promise
+	}}
+}
+
+// scheduleTimer expects a callback, a delay in milliseconds and optional arguments passed to the callback
+func scheduleTimer(name string, repeat bool, args []objects.Object) objects.Object {
+	if len(args) < 2 {
+		return newError("wrong number of arguments to `%s`; got=%d, expected>=%d", name, len(args), 2)
+	}
+	fn := args[0]
+	if fn.Type() != objects.FUNCTION_OBJ && fn.Type() != objects.BUILTIN_OBJ {
+		return newError("`%s` expects function as first argument, but got %s", name, fn.Type())
+	}
+	delay, ok := args[1].(*objects.Integer)
+	if !ok {
+		return newError("`%s` expects integer delay in milliseconds as second argument, but got %s", name, args[1].Type())
+	}
+	fnArgs := args[2:]
+
+	id := loop.setTimer(time.Duration(delay.Value)*time.Millisecond, repeat, func() objects.Object {
+		return applyFunction(fn, fnArgs)
+	})
+	return &objects.Integer{Value: id}
+}
+
+// applyAsyncFunction schedules the function body on the event loop and returns the promise of its result
+func applyAsyncFunction(fn *objects.Function, args []objects.Object, named map[string]objects.Object) objects.Object {
+	promise := objects.NewPromise()
+	loop.post(func() objects.Object {
+		var evaluated objects.Object
+		if extendedEnv, err := extendFunctionEnvironment(fn, args, named, eval); err != nil {
+			evaluated = err
+		} else {
+			evaluated = unwrapReturnValue(evalBody(fn, extendedEnv, eval))
+		}
+		if isError(evaluated) {
+			promise.Reject(evaluated.(*objects.Error))
+			return nil
+		}
+		settlePromise(promise, evaluated)
+		return nil
+	})
+	return promise
+}
+
+// settlePromise resolves the promise with the value, if the value is a promise itself it's adopted
+func settlePromise(promise *objects.Promise, value objects.Object) {
+	inner, ok := value.(*objects.Promise)
+	if !ok {
+		promise.Resolve(value)
+		return
+	}
+	inner.OnSettle(func(p *objects.Promise) {
+		state, result := p.Result()
+		if state == objects.REJECTED {
+			promise.Reject(result.(*objects.Error))
+			return
+		}
+		promise.Resolve(result)
+	})
+}
+
+func evalAwaitExpression(node *ast.AwaitExpression, environment *objects.Environment) objects.Object {
+	value := eval(node.Value, environment)
+	if isError(value) {
+		return value
+	}
+	promise, ok := value.(*objects.Promise)
+	if !ok {
+		return value
+	}
+
+	done := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(promise.Done())}
+	if _, _, _, err := loop.await([]reflect.SelectCase{done}, false); err != nil {
+		return err
+	}
+
+	state, result := promise.Result()
+	if state == objects.REJECTED {
+		err := result.(*objects.Error)
+		return &objects.Error{Message: err.Message, Stack: append([]string{}, err.Stack...)}
+	}
+	return result
+}
+
+func rejection(reason objects.Object) *objects.Error {
+	if err, ok := reason.(*objects.Error); ok {
+		return err
+	}
+	return newError("promise rejected: %s", reason.Inspect())
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/YReshetko/rash-lang/ast"
 	"github.com/YReshetko/rash-lang/extensions"
 	"github.com/YReshetko/rash-lang/objects"
+	"sort"
+	"sync"
 )
 
 var registry *extensions.Registry
@@ -15,8 +17,11 @@ func InitRegistry(r *extensions.Registry) {
 
 type Evaluator func(node ast.Node, environment *objects.Environment) objects.Object
 
+// Evaluate evaluates the node by the goroutine which holds the interpreter lock
 var Evaluate Evaluator
 
+// builtins are the functions available in every script. The builtins which evaluate script functions are
+// registered on init in their files to avoid initialization cycle builtins -> applyFunction -> builtins
 var builtins = map[string]*objects.Builtin{
 	"eval": { // eval function expects at leas two arguments plugin_name and called_function, all others will be passed to plugin as function call
 		Fn: func(args ...objects.Object) objects.Object {
@@ -37,7 +42,11 @@ var builtins = map[string]*objects.Builtin{
 				inArgs = append(inArgs, getValue(args[i]))
 			}
 
-			returnVal, err := registry.Eval(pkgName.Value, fnName.Value, inArgs...)
+			var returnVal []interface{}
+			var err error
+			loop.block(func() {
+				returnVal, err = registry.Eval(pkgName.Value, fnName.Value, inArgs...)
+			})
 
 			if err != nil {
 				return newError("plugin `%s` err: %v", pkgName.Value, err)
@@ -70,7 +79,13 @@ var builtins = map[string]*objects.Builtin{
 				inArgs = append(inArgs, getValue(args[i]))
 			}
 
-			retValue, err := registry.Call(pkgName.Value, fnName.Value, NewCallback(fn), inArgs...)
+			var retValue []interface{}
+			var err error
+			loop.ref()
+			loop.block(func() {
+				retValue, err = registry.Call(pkgName.Value, fnName.Value, NewCallback(fn), inArgs...)
+			})
+			loop.unref()
 			if err != nil {
 				return newError("plugin `%s` err: %v", pkgName.Value, err)
 			}
@@ -83,6 +98,39 @@ var builtins = map[string]*objects.Builtin{
 	},
 }
 
+// IsBuiltin checks the name is a builtin function, which is available in every script
+func IsBuiltin(name string) bool {
+	_, ok := builtins[name]
+	return ok
+}
+
+// Builtins returns the sorted names of builtin functions
+func Builtins() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	extensions.Keep = keep
+	Evaluate = eval
+}
+
+// keep holds the event loop alive until the release function is called, the release is done once
+func keep() func() {
+	loop.ref()
+	var once sync.Once
+	return func() {
+		once.Do(loop.unref)
+	}
+}
+
+// NewCallback wraps the function to be called by a plugin. The plugin may call it from any goroutine,
+// the call is scheduled on the event loop and the plugin is blocked until the function is evaluated.
+// The event loop is kept alive by the callback only during the plugin call, see extensions.Keep.
 func NewCallback(fn *objects.Function) func(args ...interface{}) ([]interface{}, error) {
 	return func(args ...interface{}) ([]interface{}, error) {
 		prepArgs := make([]objects.Object, len(args))
@@ -90,11 +138,25 @@ func NewCallback(fn *objects.Function) func(args ...interface{}) ([]interface{},
 			prepArgs[i] = retVal(v)
 		}
 
-		if len(prepArgs) != len(fn.Parameters) {
-			return nil, errors.New("unexpected number of arguments")
+		if err := checkArity(fn, prepArgs, nil); err != nil {
+			return nil, errors.New(err.Message)
+		}
+
+		result := make(chan objects.Object, 1)
+		loop.post(func() objects.Object {
+			extendedEnv, err := extendFunctionEnvironment(fn, prepArgs, nil, Evaluate)
+			if err != nil {
+				result <- err
+				return nil
+			}
+			result <- evalBody(fn, extendedEnv, Evaluate)
+			return nil
+		})
+		evaluated := <-result
+
+		if isError(evaluated) {
+			return nil, errors.New(evaluated.Inspect())
 		}
-		extendedEnv := extendFunctionEnvironment(fn, prepArgs)
-		evaluated := Evaluate(fn.Body, extendedEnv)
 
 		outValues := []interface{}{}
 
@@ -181,6 +243,8 @@ func retVal(val interface{}) objects.Object {
 			arr.Elements[i] = retVal(value)
 		}
 		return arr
+	case extensions.Iterator:
+		return nativeIterator(v)
 	default:
 		return objects.NULL
 	}
@@ -0,0 +1,409 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/objects"
+	"sort"
+)
+
+// Higher-order builtins apply script functions to the elements of arrays and hashes
+func init() {
+	builtins["map"] = &objects.Builtin{Fn: builtinMap}
+	builtins["filter"] = &objects.Builtin{Fn: builtinFilter}
+	builtins["reduce"] = &objects.Builtin{Fn: builtinReduce}
+	builtins["each"] = &objects.Builtin{Fn: builtinEach}
+	builtins["find"] = &objects.Builtin{Fn: builtinFind}
+	builtins["any"] = &objects.Builtin{Fn: builtinAny}
+	builtins["all"] = &objects.Builtin{Fn: builtinAll}
+	builtins["sort"] = &objects.Builtin{Fn: builtinSort}
+	builtins["sortBy"] = &objects.Builtin{Fn: builtinSortBy}
+	builtins["zip"] = &objects.Builtin{Fn: builtinZip}
+	builtins["groupBy"] = &objects.Builtin{Fn: builtinGroupBy}
+	builtins["unique"] = &objects.Builtin{Fn: builtinUnique}
+	builtins["flatten"] = &objects.Builtin{Fn: builtinFlatten}
+}
+
+// callback applies the function to the arguments. User defined function also receives as many optional arguments
+// as it declares, so both fn(x) and fn(x, i) are allowed as callbacks
+func callback(fn objects.Object, args []objects.Object, optional ...objects.Object) objects.Object {
+	if function, ok := fn.(*objects.Function); ok {
+		for i := 0; i < len(optional) && len(args) < len(function.Parameters); i++ {
+			args = append(args, optional[i])
+		}
+	}
+	return applyFunction(fn, args)
+}
+
+// arrayAndFunction validates arguments of builtins with signature name(array, function)
+func arrayAndFunction(name string, args []objects.Object) (*objects.Array, objects.Object, *objects.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments to `%s`; got=%d, expected=%d", name, len(args), 2)
+	}
+	arr, ok := args[0].(*objects.Array)
+	if !ok {
+		return nil, nil, newError("`%s` expects array as first argument, but got %s", name, args[0].Type())
+	}
+	if !isCallable(args[1]) {
+		return nil, nil, newError("`%s` expects function as second argument, but got %s", name, args[1].Type())
+	}
+	return arr, args[1], nil
+}
+
+func isCallable(obj objects.Object) bool {
+	return obj.Type() == objects.FUNCTION_OBJ || obj.Type() == objects.BUILTIN_OBJ || obj.Type() == objects.STRUCT_OBJ
+}
+
+func builtinMap(args ...objects.Object) objects.Object {
+	arr, fn, err := arrayAndFunction("map", args)
+	if err != nil {
+		return err
+	}
+	result := make([]objects.Object, len(arr.Elements))
+	for i, element := range arr.Elements {
+		value := callback(fn, []objects.Object{element}, &objects.Integer{Value: int64(i)})
+		if isError(value) {
+			return value
+		}
+		result[i] = value
+	}
+	return &objects.Array{Elements: result}
+}
+
+func builtinFilter(args ...objects.Object) objects.Object {
+	arr, fn, err := arrayAndFunction("filter", args)
+	if err != nil {
+		return err
+	}
+	result := []objects.Object{}
+	for i, element := range arr.Elements {
+		value := callback(fn, []objects.Object{element}, &objects.Integer{Value: int64(i)})
+		if isError(value) {
+			return value
+		}
+		if isTruthy(value) {
+			result = append(result, element)
+		}
+	}
+	return &objects.Array{Elements: result}
+}
+
+// builtinReduce folds the array with fn(accumulator, element, index), the first element is used if initial value is omitted
+func builtinReduce(args ...objects.Object) objects.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments to `reduce`; got=%d, expected=%d or %d", len(args), 2, 3)
+	}
+	arr, fn, err := arrayAndFunction("reduce", args[:2])
+	if err != nil {
+		return err
+	}
+
+	elements := arr.Elements
+	var acc objects.Object
+	start := 0
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		if len(elements) == 0 {
+			return newError("`reduce` of empty array with no initial value")
+		}
+		acc = elements[0]
+		start = 1
+	}
+
+	for i := start; i < len(elements); i++ {
+		acc = callback(fn, []objects.Object{acc, elements[i]}, &objects.Integer{Value: int64(i)})
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// builtinEach calls fn(element, index) for arrays and fn(key, value) for hashes, returns the collection
+func builtinEach(args ...objects.Object) objects.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments to `each`; got=%d, expected=%d", len(args), 2)
+	}
+	if !isCallable(args[1]) {
+		return newError("`each` expects function as second argument, but got %s", args[1].Type())
+	}
+	switch collection := args[0].(type) {
+	case *objects.Array:
+		for i, element := range collection.Elements {
+			if value := callback(args[1], []objects.Object{element}, &objects.Integer{Value: int64(i)}); isError(value) {
+				return value
+			}
+		}
+	case *objects.Hash:
+		for _, pair := range collection.SortedPairs() {
+			if value := callback(args[1], []objects.Object{pair.Key, pair.Value}); isError(value) {
+				return value
+			}
+		}
+	default:
+		return newError("`each` expects array or hash as first argument, but got %s", args[0].Type())
+	}
+	return args[0]
+}
+
+func builtinFind(args ...objects.Object) objects.Object {
+	arr, fn, err := arrayAndFunction("find", args)
+	if err != nil {
+		return err
+	}
+	index, findErr := findIndex(arr, fn, true)
+	if findErr != nil {
+		return findErr
+	}
+	if index < 0 {
+		return objects.NULL
+	}
+	return arr.Elements[index]
+}
+
+func builtinAny(args ...objects.Object) objects.Object {
+	arr, fn, err := arrayAndFunction("any", args)
+	if err != nil {
+		return err
+	}
+	index, findErr := findIndex(arr, fn, true)
+	if findErr != nil {
+		return findErr
+	}
+	return nativeBoolean(index >= 0)
+}
+
+func builtinAll(args ...objects.Object) objects.Object {
+	arr, fn, err := arrayAndFunction("all", args)
+	if err != nil {
+		return err
+	}
+	index, findErr := findIndex(arr, fn, false)
+	if findErr != nil {
+		return findErr
+	}
+	return nativeBoolean(index < 0)
+}
+
+// findIndex returns index of the first element for which the predicate truthiness equals to expected, -1 if not found
+func findIndex(arr *objects.Array, fn objects.Object, expected bool) (int, *objects.Error) {
+	for i, element := range arr.Elements {
+		value := callback(fn, []objects.Object{element}, &objects.Integer{Value: int64(i)})
+		if err, ok := value.(*objects.Error); ok {
+			return -1, err
+		}
+		if isTruthy(value) == expected {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// builtinSort returns a new sorted array. The optional comparator fn(a, b) returns
+// either a boolean (a goes before b) or an integer (negative if a goes before b)
+func builtinSort(args ...objects.Object) objects.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments to `sort`; got=%d, expected=%d or %d", len(args), 1, 2)
+	}
+	arr, ok := args[0].(*objects.Array)
+	if !ok {
+		return newError("`sort` expects array as first argument, but got %s", args[0].Type())
+	}
+	if len(args) == 1 {
+		return sortArray(arr.Elements, arr.Elements)
+	}
+
+	if !isCallable(args[1]) {
+		return newError("`sort` expects function as second argument, but got %s", args[1].Type())
+	}
+	result := arr.Slice(0, len(arr.Elements))
+	var sortErr objects.Object
+	sort.SliceStable(result.Elements, func(i, j int) bool {
+		if sortErr != nil {
+			return false
+		}
+		value := callback(args[1], []objects.Object{result.Elements[i], result.Elements[j]})
+		switch v := value.(type) {
+		case *objects.Boolean:
+			return v.Value
+		case *objects.Integer:
+			return v.Value < 0
+		case *objects.Error:
+			sortErr = v
+		default:
+			sortErr = newError("`sort` comparator must return BOOLEAN or INTEGER, but got %s", value.Type())
+		}
+		return false
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+	return result
+}
+
+// builtinSortBy returns a new array sorted by keys calculated by fn(element)
+func builtinSortBy(args ...objects.Object) objects.Object {
+	arr, fn, err := arrayAndFunction("sortBy", args)
+	if err != nil {
+		return err
+	}
+	keys := make([]objects.Object, len(arr.Elements))
+	for i, element := range arr.Elements {
+		key := callback(fn, []objects.Object{element})
+		if isError(key) {
+			return key
+		}
+		keys[i] = key
+	}
+	return sortArray(arr.Elements, keys)
+}
+
+// sortArray sorts elements by the keys with natural order of numbers and strings
+func sortArray(elements []objects.Object, keys []objects.Object) objects.Object {
+	indexes := make([]int, len(elements))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	var sortErr *objects.Error
+	sort.SliceStable(indexes, func(i, j int) bool {
+		if sortErr != nil {
+			return false
+		}
+		less, err := lessObjects(keys[indexes[i]], keys[indexes[j]])
+		if err != nil {
+			sortErr = err
+		}
+		return less
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+
+	result := make([]objects.Object, len(elements))
+	for i, index := range indexes {
+		result[i] = elements[index]
+	}
+	return &objects.Array{Elements: result}
+}
+
+func lessObjects(left, right objects.Object) (bool, *objects.Error) {
+	switch {
+	case isNumbers(left.Type(), right.Type()):
+		return left.(objects.Comparable).Lt(right), nil
+	case left.Type() == objects.STRING_OBJ && right.Type() == objects.STRING_OBJ:
+		return left.(*objects.String).Value < right.(*objects.String).Value, nil
+	default:
+		return false, newError("unable to compare %s and %s", left.Type(), right.Type())
+	}
+}
+
+// builtinZip combines arrays into an array of tuples, the result length is the length of the shortest array
+func builtinZip(args ...objects.Object) objects.Object {
+	if len(args) < 2 {
+		return newError("wrong number of arguments to `zip`; got=%d, expected>=%d", len(args), 2)
+	}
+	length := -1
+	arrays := make([]*objects.Array, len(args))
+	for i, arg := range args {
+		arr, ok := arg.(*objects.Array)
+		if !ok {
+			return newError("`zip` expects arrays, but got %s", arg.Type())
+		}
+		arrays[i] = arr
+		if length < 0 || len(arr.Elements) < length {
+			length = len(arr.Elements)
+		}
+	}
+
+	result := make([]objects.Object, length)
+	for i := 0; i < length; i++ {
+		tuple := make([]objects.Object, len(arrays))
+		for j, arr := range arrays {
+			tuple[j] = arr.Elements[i]
+		}
+		result[i] = &objects.Array{Elements: tuple}
+	}
+	return &objects.Array{Elements: result}
+}
+
+// builtinGroupBy returns a hash of arrays grouped by keys calculated by fn(element)
+func builtinGroupBy(args ...objects.Object) objects.Object {
+	arr, fn, err := arrayAndFunction("groupBy", args)
+	if err != nil {
+		return err
+	}
+	result := &objects.Hash{Pairs: map[objects.HashKey]objects.HashPair{}}
+	for _, element := range arr.Elements {
+		key := callback(fn, []objects.Object{element})
+		if isError(key) {
+			return key
+		}
+		hashable, ok := key.(objects.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+		group, ok := result.Get(hashable)
+		if !ok {
+			group = &objects.Array{Elements: []objects.Object{}}
+			result.Set(key, group)
+		}
+		group.(*objects.Array).Push(element)
+	}
+	return result
+}
+
+// builtinUnique returns a new array without duplicates, the first occurrence is kept
+func builtinUnique(args ...objects.Object) objects.Object {
+	if err := expectArgs("unique", args, 1); err != nil {
+		return err
+	}
+	arr, ok := args[0].(*objects.Array)
+	if !ok {
+		return newError("`unique` expects array, but got %s", args[0].Type())
+	}
+	seen := map[objects.HashKey]bool{}
+	result := []objects.Object{}
+	for _, element := range arr.Elements {
+		hashable, ok := element.(objects.Hashable)
+		if !ok {
+			return newError("`unique` expects hashable elements, but got %s", element.Type())
+		}
+		if seen[hashable.HashKey()] {
+			continue
+		}
+		seen[hashable.HashKey()] = true
+		result = append(result, element)
+	}
+	return &objects.Array{Elements: result}
+}
+
+// builtinFlatten flattens nested arrays up to the depth, which is 1 by default
+func builtinFlatten(args ...objects.Object) objects.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments to `flatten`; got=%d, expected=%d or %d", len(args), 1, 2)
+	}
+	arr, ok := args[0].(*objects.Array)
+	if !ok {
+		return newError("`flatten` expects array as first argument, but got %s", args[0].Type())
+	}
+	depth := int64(1)
+	if len(args) == 2 {
+		d, ok := args[1].(*objects.Integer)
+		if !ok {
+			return newError("`flatten` expects integer depth, but got %s", args[1].Type())
+		}
+		depth = d.Value
+	}
+	return &objects.Array{Elements: flatten(arr.Elements, depth)}
+}
+
+func flatten(elements []objects.Object, depth int64) []objects.Object {
+	result := []objects.Object{}
+	for _, element := range elements {
+		if nested, ok := element.(*objects.Array); ok && depth > 0 {
+			result = append(result, flatten(nested.Elements, depth-1)...)
+			continue
+		}
+		result = append(result, element)
+	}
+	return result
+}
@@ -0,0 +1,32 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+)
+
+// Debugger is notified about the evaluated statements and function calls.
+// The hooks are called by the goroutine which holds the interpreter lock,
+// so the debugger pauses the whole script by blocking in a hook
+type Debugger interface {
+	// Statement is called before each statement of a program, a module or a block is evaluated
+	Statement(statement ast.Statement, environment *objects.Environment)
+	// Call is called before the body of a script function is evaluated in the extended environment
+	Call(fn *objects.Function, environment *objects.Environment)
+	// Return is called when the body of a script function is evaluated
+	Return(fn *objects.Function, result objects.Object)
+}
+
+// Debug is the debugger attached to the interpreter, nil if the script isn't debugged
+var Debug Debugger
+
+// evalBody evaluates the body of the applied function and notifies the debugger about the call and return
+func evalBody(fn *objects.Function, environment *objects.Environment, eval Evaluator) objects.Object {
+	if Debug == nil {
+		return eval(fn.Body, environment)
+	}
+	Debug.Call(fn, environment)
+	evaluated := eval(fn.Body, environment)
+	Debug.Return(fn, unwrapReturnValue(evaluated))
+	return evaluated
+}
@@ -0,0 +1,129 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+)
+
+// binder binds the name to the value, it's Environment.Set for declarations and parameters
+type binder func(name string, value objects.Object) objects.Object
+
+// destructure binds parts of the value to the names of pattern, the value must have the same shape as the pattern
+func destructure(pattern ast.Expression, value objects.Object, bind binder) *objects.Error {
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		bind(p.Value, value)
+		return nil
+	case *ast.ArrayPattern:
+		return destructureArray(p, value, bind)
+	case *ast.HashPattern:
+		return destructureHash(p, value, bind)
+	default:
+		return newError("unsupported destructuring pattern: %s", pattern.String())
+	}
+}
+
+func destructureArray(pattern *ast.ArrayPattern, value objects.Object, bind binder) *objects.Error {
+	arr, ok := value.(*objects.Array)
+	if !ok {
+		return newError("unable to destructure %s as array", value.Type())
+	}
+	expected := len(pattern.Elements)
+	if pattern.Rest == nil && len(arr.Elements) != expected {
+		return newError("array destructuring mismatch: expected=%d, got=%d", expected, len(arr.Elements))
+	}
+	if len(arr.Elements) < expected {
+		return newError("array destructuring mismatch: expected>=%d, got=%d", expected, len(arr.Elements))
+	}
+
+	for i, element := range pattern.Elements {
+		if err := destructure(element, arr.Elements[i], bind); err != nil {
+			return err
+		}
+	}
+	if pattern.Rest != nil {
+		bind(pattern.Rest.Value, arr.Slice(expected, len(arr.Elements)))
+	}
+	return nil
+}
+
+// destructureHash takes values of hash by string keys or values of struct instance by field names
+func destructureHash(pattern *ast.HashPattern, value objects.Object, bind binder) *objects.Error {
+	get, members, ok := hashMembers(value)
+	if !ok {
+		return newError("unable to destructure %s as hash", value.Type())
+	}
+
+	for i, key := range pattern.Keys {
+		member, ok := get(key.Value)
+		if !ok {
+			return newError("missing key %s in destructuring of %s", key.Value, value.Type())
+		}
+		if err := destructure(pattern.Targets[i], member, bind); err != nil {
+			return err
+		}
+	}
+	if pattern.Rest != nil {
+		bind(pattern.Rest.Value, restMembers(members(), pattern.Keys))
+	}
+	return nil
+}
+
+// hashMembers returns the getter of hash values by string keys or instance fields by names,
+// and the function to copy all members to a new hash
+func hashMembers(value objects.Object) (func(key string) (objects.Object, bool), func() *objects.Hash, bool) {
+	switch obj := value.(type) {
+	case *objects.Hash:
+		get := func(key string) (objects.Object, bool) {
+			return obj.Get(&objects.String{Value: key})
+		}
+		return get, func() *objects.Hash {
+			members := &objects.Hash{Pairs: map[objects.HashKey]objects.HashPair{}}
+			for _, pair := range obj.Pairs {
+				members.Set(pair.Key, pair.Value)
+			}
+			return members
+		}, true
+	case *objects.Instance:
+		return obj.Get, func() *objects.Hash {
+			members := &objects.Hash{Pairs: map[objects.HashKey]objects.HashPair{}}
+			for i, field := range obj.Struct.Fields {
+				members.Set(&objects.String{Value: field}, obj.Values[i])
+			}
+			return members
+		}, true
+	default:
+		return nil, nil, false
+	}
+}
+
+// restMembers removes the keys of pattern from the members
+func restMembers(members *objects.Hash, keys []*ast.Identifier) *objects.Hash {
+	for _, key := range keys {
+		members.Delete(&objects.String{Value: key.Value})
+	}
+	return members
+}
+
+// evalMultipleAssignment evaluates all values before the assignment, so `a, b = b, a` swaps the values.
+// The only value is unpacked if it's an array: `a, b = pair`
+func evalMultipleAssignment(node *ast.MultipleAssignment, environment *objects.Environment) objects.Object {
+	values := evalExpressions(node.Values, environment)
+	if len(values) == 1 && isError(values[0]) {
+		return values[0]
+	}
+	if arr, ok := values[0].(*objects.Array); ok && len(values) == 1 {
+		values = arr.Elements
+	}
+	if len(values) != len(node.Targets) {
+		return newError("assignment mismatch: %d targets, but %d values", len(node.Targets), len(values))
+	}
+
+	for i, target := range node.Targets {
+		result := assign(target, values[i], environment)
+		if isError(result) {
+			return result
+		}
+	}
+	return objects.NULL
+}
@@ -7,24 +7,31 @@ import (
 	"github.com/YReshetko/rash-lang/objects"
 )
 
-type scriptLoader func(path string) (*objects.Environment, error)
+// scriptLoader loads the script included by the script from and returns the environment of evaluated script
+type scriptLoader func(path, from string) (*objects.Environment, error)
 
-var ScriptLoader scriptLoader = func(path string) (*objects.Environment, error) {
+var ScriptLoader scriptLoader = func(path, from string) (*objects.Environment, error) {
 	return nil, errors.New("script loader is not defined")
 }
 
-func Eval(node ast.Node, environment *objects.Environment) objects.Object {
+// Strict mode reports missing hash keys and out of range indexes as errors instead of returning null,
+// optional access `a?[k]` and `a?.k` still returns null
+var Strict bool
+
+func eval(node ast.Node, environment *objects.Environment) objects.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node.Statements, environment)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, environment)
+		return eval(node.Expression, environment)
 	case *ast.DeclarationStatement:
 		return evalDeclarationStatement(node, environment)
+	case *ast.ExportStatement:
+		return evalExportStatement(node, environment)
 	case *ast.BlockStatement:
 		return evalStatements(node.Statements, environment)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, environment)
+		right := eval(node.Right, environment)
 		if isError(right) {
 			return right
 		}
@@ -41,38 +48,72 @@ func Eval(node ast.Node, environment *objects.Environment) objects.Object {
 		return &objects.Double{Value: node.Value}
 	case *ast.StringLiteral:
 		return &objects.String{Value: node.Value}
+	case *ast.TemplateLiteral:
+		return evalTemplateLiteral(node, environment)
 	case *ast.BooleanLiteral:
 		return nativeBoolean(node.Value)
+	case *ast.NullLiteral:
+		return objects.NULL
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, environment)
+	case *ast.ForInExpression:
+		return evalForInExpression(node, environment)
+	case *ast.BreakStatement:
+		return &objects.Break{Label: labelName(node.Label)}
+	case *ast.ContinueStatement:
+		return &objects.Continue{Label: labelName(node.Label)}
 	case *ast.ReturnStatement:
-		result := Eval(node.Value, environment)
+		result := eval(node.Value, environment)
 		if isError(result) {
 			return result
 		}
 		return &objects.ReturnValue{Value: result}
 	case *ast.LetStatement:
-		val := Eval(node.Value, environment)
+		val := eval(node.Value, environment)
 		if isError(val) {
 			return val
 		}
-		environment.Set(node.Name.Value, val)
+		bind := environment.Set
+		if node.IsConst() {
+			bind = environment.SetConst
+		}
+		if node.Pattern != nil {
+			if err := destructure(node.Pattern, val, bind); err != nil {
+				return err
+			}
+		} else {
+			bind(node.Name.Value, val)
+		}
+	case *ast.MultipleAssignment:
+		return evalMultipleAssignment(node, environment)
+	case *ast.StructStatement:
+		evalStructStatement(node, environment)
 	case *ast.Identifier:
 		return evalIdentifier(node, environment)
 	case *ast.FunctionLiteral:
 		return &objects.Function{
 			Parameters:  node.Parameters,
+			Patterns:    node.Patterns,
+			Defaults:    node.Defaults,
+			Rest:        node.Rest,
 			Body:        node.Body,
 			Environment: environment,
+			Async:       node.Async,
 		}
+	case *ast.AwaitExpression:
+		return evalAwaitExpression(node, environment)
+	case *ast.SpawnExpression:
+		return evalSpawnExpression(node, environment)
+	case *ast.SelectExpression:
+		return evalSelectExpression(node, environment)
 	case *ast.CallExpression:
-		function := Eval(node.Function, environment)
+		function := eval(node.Function, environment)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, environment)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-		return applyFunction(function, args)
+		return evalCall(function, node, environment)
+	case *ast.SpreadExpression:
+		return newError("spread is allowed only in call arguments and array literals")
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, environment)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -82,15 +123,13 @@ func Eval(node ast.Node, environment *objects.Environment) objects.Object {
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, environment)
 	case *ast.IndexExpression:
-		left := Eval(node.Left, environment)
+		left := eval(node.Left, environment)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, environment)
-		if isError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
+		return evalIndex(left, node, environment)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, environment)
 	}
 
 	return objects.NULL
@@ -102,7 +141,7 @@ func evalHashLiteral(node *ast.HashLiteral, environment *objects.Environment) ob
 	}
 
 	for keyExp, valueExp := range node.Pairs {
-		key := Eval(keyExp, environment)
+		key := eval(keyExp, environment)
 		if isError(key) {
 			return key
 		}
@@ -110,7 +149,7 @@ func evalHashLiteral(node *ast.HashLiteral, environment *objects.Environment) ob
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
-		value := Eval(valueExp, environment)
+		value := eval(valueExp, environment)
 		if isError(value) {
 			return value
 		}
@@ -124,18 +163,72 @@ func evalHashLiteral(node *ast.HashLiteral, environment *objects.Environment) ob
 	return hash
 }
 
-func evalIndexExpression(left objects.Object, index objects.Object) objects.Object {
+// evalIndex evaluates the index of already evaluated left value, optional index of null is null
+func evalIndex(left objects.Object, node *ast.IndexExpression, environment *objects.Environment) objects.Object {
+	if node.Optional && left == objects.NULL {
+		return objects.NULL
+	}
+	index := eval(node.Index, environment)
+	if isError(index) {
+		return index
+	}
+	return evalIndexExpression(left, index, Strict && !node.Optional)
+}
+
+// evalIndexExpression returns the element by index, in strict mode a missing element is an error instead of null
+func evalIndexExpression(left objects.Object, index objects.Object, strict bool) objects.Object {
 	switch {
 	case left.Type() == objects.ARRAY_OBJ && index.Type() == objects.INTEGER_OBJ:
-		return evalArrayIndexExpression(left, index)
+		return evalArrayIndexExpression(left, index, strict)
+	case left.Type() == objects.STRING_OBJ && index.Type() == objects.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index, strict)
 	case left.Type() == objects.HASH_OBJ:
-		return evalHashIndexExpression(left, index)
+		return evalHashIndexExpression(left, index, strict)
 	default:
 		return newError("index operator not supported for: %s", left.Type())
 	}
 }
 
-func evalHashIndexExpression(left objects.Object, index objects.Object) objects.Object {
+// evalStringIndexExpression returns the character by its rune index
+func evalStringIndexExpression(left objects.Object, index objects.Object, strict bool) objects.Object {
+	runes := []rune(left.(*objects.String).Value)
+	ind := index.(*objects.Integer).Value
+	if 0 > ind || ind >= int64(len(runes)) {
+		if strict {
+			return newError("index %d out of range for STRING of length %d", ind, len(runes))
+		}
+		return objects.NULL
+	}
+	return &objects.String{Value: string(runes[ind])}
+}
+
+func evalSliceExpression(node *ast.SliceExpression, environment *objects.Environment) objects.Object {
+	left := eval(node.Left, environment)
+	if isError(left) {
+		return left
+	}
+	return evalSlice(left, node, environment)
+}
+
+// evalSlice slices already evaluated left value of the slice expression
+func evalSlice(left objects.Object, node *ast.SliceExpression, environment *objects.Environment) objects.Object {
+	if node.Optional && left == objects.NULL {
+		return objects.NULL
+	}
+	bounds := make([]objects.Object, 2)
+	for i, exp := range []ast.Expression{node.Start, node.End} {
+		if exp == nil {
+			continue
+		}
+		bounds[i] = eval(exp, environment)
+		if isError(bounds[i]) {
+			return bounds[i]
+		}
+	}
+	return sliceObject(left, bounds[0], bounds[1])
+}
+
+func evalHashIndexExpression(left objects.Object, index objects.Object, strict bool) objects.Object {
 	hash := left.(*objects.Hash)
 	ind, ok := index.(objects.Hashable)
 	if !ok {
@@ -143,16 +236,22 @@ func evalHashIndexExpression(left objects.Object, index objects.Object) objects.
 	}
 	pair, ok := hash.Pairs[ind.HashKey()]
 	if !ok {
+		if strict {
+			return newError("key %s not found in HASH", index.Inspect())
+		}
 		return objects.NULL
 	}
 	return pair.Value
 }
 
-func evalArrayIndexExpression(left objects.Object, index objects.Object) objects.Object {
+func evalArrayIndexExpression(left objects.Object, index objects.Object, strict bool) objects.Object {
 	arr := left.(*objects.Array)
 	ind := index.(*objects.Integer).Value
 	max := int64(len(arr.Elements) - 1)
 	if 0 > ind || ind > max {
+		if strict {
+			return newError("index %d out of range for ARRAY of length %d", ind, len(arr.Elements))
+		}
 		return objects.NULL
 	}
 	return arr.Elements[ind]
@@ -164,31 +263,21 @@ func evalDeclarationStatement(node *ast.DeclarationStatement, environment *objec
 		return newError("unknown declaration type: %s", node.Declaration.String())
 	}
 
-	extEnv, err := ScriptLoader(include.Include.Value)
+	extEnv, err := ScriptLoader(include.Include.Value, include.Token.FileName)
 	if err != nil {
 		return newError("unable preload external script:\n%s", err.Error())
 	}
 
-	environment.AddExternalEnvironment(include.Alias.Value, extEnv)
-
-	return &objects.ExternalEnvironment{Environment: extEnv}
-}
-
-func applyFunction(function objects.Object, args []objects.Object) objects.Object {
-	switch fn := function.(type) {
-	case *objects.Function:
-		if len(args) != len(fn.Parameters) {
-			return newError("number of function parameters mismatch: expected=%d, got=%d", len(fn.Parameters), len(args))
-		}
-		extendedEnv := extendFunctionEnvironment(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
-	case *objects.Builtin:
-		return fn.Fn(args...)
-	default:
-		return newError("not a function: %s", function.Type())
+	module := &objects.ExternalEnvironment{Environment: extEnv}
+	switch {
+	case include.Wildcard:
+		return evalImportAll(module, environment)
+	case include.Names != nil:
+		return evalImportNames(module, include.Names, environment)
 	}
+	environment.AddExternalEnvironment(include.Alias.Value, extEnv)
 
+	return module
 }
 
 func unwrapReturnValue(evaluated objects.Object) objects.Object {
@@ -198,24 +287,27 @@ func unwrapReturnValue(evaluated objects.Object) objects.Object {
 	return evaluated
 }
 
-func extendFunctionEnvironment(fn *objects.Function, args []objects.Object) *objects.Environment {
-	newEnv := objects.NewEnclosedEnvironment(fn.Environment)
-
-	for i, parameter := range fn.Parameters {
-		newEnv.Set(parameter.Value, args[i])
-	}
-
-	return newEnv
-}
-
+// evalExpressions evaluates the list of expressions, spread arrays are expanded into the list
 func evalExpressions(arguments []ast.Expression, environment *objects.Environment) []objects.Object {
-	result := make([]objects.Object, len(arguments))
-	for i, argument := range arguments {
-		evaluated := Eval(argument, environment)
+	result := make([]objects.Object, 0, len(arguments))
+	for _, argument := range arguments {
+		spread, isSpread := argument.(*ast.SpreadExpression)
+		if isSpread {
+			argument = spread.Value
+		}
+		evaluated := eval(argument, environment)
 		if isError(evaluated) {
 			return []objects.Object{evaluated}
 		}
-		result[i] = evaluated
+		if !isSpread {
+			result = append(result, evaluated)
+			continue
+		}
+		arr, ok := evaluated.(*objects.Array)
+		if !ok {
+			return []objects.Object{newError("spread expects array, but got %s", evaluated.Type())}
+		}
+		result = append(result, arr.Elements...)
 	}
 	return result
 }
@@ -237,15 +329,15 @@ func evalIdentifier(node *ast.Identifier, environment *objects.Environment) obje
 }
 
 func evalIfExpression(node *ast.IfExpression, environment *objects.Environment) objects.Object {
-	condition := Eval(node.Condition, environment)
+	condition := eval(node.Condition, environment)
 	if isError(condition) {
 		return condition
 	}
 	newEnv := objects.NewEnclosedEnvironment(environment)
 	if isTruthy(condition) {
-		return Eval(node.Consequence, newEnv)
+		return eval(node.Consequence, newEnv)
 	} else if node.Alternative != nil {
-		return Eval(node.Alternative, newEnv)
+		return eval(node.Alternative, newEnv)
 	}
 	return objects.NULL
 }
@@ -254,13 +346,13 @@ func evalForExpression(node *ast.ForExpression, environment *objects.Environment
 	newEnv := objects.NewEnclosedEnvironment(environment)
 
 	if node.Initial != nil {
-		Eval(node.Initial, newEnv)
+		eval(node.Initial, newEnv)
 	}
 
 	var value objects.Object = objects.NULL
 	for {
 		if node.Condition != nil {
-			cond := Eval(node.Condition, newEnv)
+			cond := eval(node.Condition, newEnv)
 			if isError(cond) {
 				return cond
 			}
@@ -269,18 +361,15 @@ func evalForExpression(node *ast.ForExpression, environment *objects.Environment
 			}
 		}
 
-		value = Eval(node.Body, newEnv)
-		if isError(value) {
-			return value
-		}
-
-		if _, ok := value.(*objects.ReturnValue); ok {
+		var stop bool
+		value, stop = loopControl(eval(node.Body, newEnv), value, node.Label)
+		if stop {
 			return value
 		}
 
 		if node.Complete != nil {
-			compl := Eval(node.Complete, newEnv)
-			if isError(value) {
+			compl := eval(node.Complete, newEnv)
+			if isError(compl) {
 				return compl
 			}
 		}
@@ -300,19 +389,31 @@ func evalInfixExpression(node *ast.InfixExpression, environment *objects.Environ
 
 	switch node.Operator {
 	case ".":
-		left := Eval(node.Left, environment)
+		left := eval(node.Left, environment)
 		if isError(left) {
 			return left
 		}
-		return evalDottedExpression(left, node.Right, environment)
+		return evalDottedExpression(left, node.Right, environment, false)
+	case "?.":
+		left := eval(node.Left, environment)
+		if isError(left) || left == objects.NULL {
+			return left
+		}
+		return evalDottedExpression(left, node.Right, environment, true)
+	case "??":
+		left := eval(node.Left, environment)
+		if left != objects.NULL {
+			return left
+		}
+		return eval(node.Right, environment)
 	case "=":
 		return evalAssignExpression(node, environment)
 	default:
-		left := Eval(node.Left, environment)
+		left := eval(node.Left, environment)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, environment)
+		right := eval(node.Right, environment)
 		if isError(right) {
 			return right
 		}
@@ -321,36 +422,49 @@ func evalInfixExpression(node *ast.InfixExpression, environment *objects.Environ
 }
 
 func evalAssignExpression(node *ast.InfixExpression, environment *objects.Environment) objects.Object {
-	val := Eval(node.Right, environment)
+	val := eval(node.Right, environment)
 	if isError(val) {
 		return val
 	}
-	switch n := node.Left.(type) {
+	return assign(node.Left, val, environment)
+}
+
+// assign sets the value to the assignment target: variable, element of array or hash, or member of struct or hash
+func assign(target ast.Expression, val objects.Object, environment *objects.Environment) objects.Object {
+	switch n := target.(type) {
 	case *ast.Identifier:
+		if environment.IsConst(n.Value) {
+			return newError("cannot assign to constant %s", n.Value)
+		}
 		value, ok := environment.Update(n.Value, val)
 		if !ok {
 			return newError("identifier not defined: %s", n.Value)
 		}
 		return value
 	case *ast.IndexExpression:
-		left := Eval(n.Left, environment)
+		left := eval(n.Left, environment)
 		if isError(left) {
 			return left
 		}
-		index := Eval(n.Index, environment)
+		index := eval(n.Index, environment)
 		if isError(index) {
 			return index
 		}
 		return evalAssignIndexExpression(left, index, val)
 	case *ast.InfixExpression:
-		return newError("unsupported multiple/inner/crosspackage assignments: %s", node.Right.TokenLiteral())
+		if n.Operator == "." {
+			return evalAssignDottedExpression(n, val, environment)
+		}
+		return newError("unsupported multiple/inner/crosspackage assignments: %s", target.TokenLiteral())
 	default:
-		return newError("unsupported assignment type receiver: %s", node.Right.TokenLiteral())
+		return newError("unsupported assignment type receiver: %s", target.TokenLiteral())
 	}
 }
 
 func evalAssignIndexExpression(left objects.Object, index, value objects.Object) objects.Object {
 	switch {
+	case objects.IsFrozen(left):
+		return frozenError(left)
 	case left.Type() == objects.ARRAY_OBJ && index.Type() == objects.INTEGER_OBJ:
 		return evalAssignArrayIndexExpression(left, index, value)
 	case left.Type() == objects.HASH_OBJ:
@@ -384,46 +498,51 @@ func evalAssignArrayIndexExpression(left objects.Object, index, value objects.Ob
 	return value
 }
 
-func evalDottedExpression(left objects.Object, right ast.Expression, environment *objects.Environment) objects.Object {
-	switch leftExp := left.(type) {
-	case *objects.ExternalEnvironment:
-		switch n := right.(type) {
-		case *ast.Identifier:
-			return evalIdentifier(n, leftExp.Environment)
-		case *ast.CallExpression:
-			function := Eval(n.Function, leftExp.Environment)
-			if isError(function) {
-				return function
-			}
-			args := evalExpressions(n.Arguments, environment)
-			if len(args) == 1 && isError(args[0]) {
-				return args[0]
-			}
-			return applyFunction(function, args)
-		case *ast.IndexExpression:
-			left := Eval(n.Left, leftExp.Environment)
-			if isError(left) {
-				return left
-			}
-			index := Eval(n.Index, environment)
-			if isError(index) {
-				return index
-			}
-			return evalIndexExpression(left, index)
-		default:
-			return newError("unsupported reference call %s", right.TokenLiteral())
+// evalDottedExpression resolves `left.right`, where right is a member name optionally followed by calls and indexes.
+// The member is resolved in the external environment of included script, by the string key of a hash
+// or by the method table of the left value type (see methods.go). Optional member of hash is null in strict mode as well
+func evalDottedExpression(left objects.Object, right ast.Expression, environment *objects.Environment, optional bool) objects.Object {
+	switch n := right.(type) {
+	case *ast.Identifier:
+		return evalMember(left, n, Strict && !optional)
+	case *ast.CallExpression:
+		function := evalDottedExpression(left, n.Function, environment, optional)
+		if isError(function) {
+			return function
+		}
+		return evalCall(function, n, environment)
+	case *ast.IndexExpression:
+		value := evalDottedExpression(left, n.Left, environment, optional)
+		if isError(value) {
+			return value
+		}
+		return evalIndex(value, n, environment)
+	case *ast.SliceExpression:
+		value := evalDottedExpression(left, n.Left, environment, optional)
+		if isError(value) {
+			return value
 		}
+		return evalSlice(value, n, environment)
 	default:
-		return newError("unsupported reference call on :%s", left.Type())
+		return newError("unsupported reference call %s", right.TokenLiteral())
 	}
 }
 
 func evalNativeInfixExpression(operator string, left objects.Object, right objects.Object) objects.Object {
 	switch {
+	case operator == "..":
+		return newRange(left, right)
 	case isNumbers(left.Type(), right.Type()):
 		return evalNumberInfixExpression(operator, left, right)
 	case left.Type() == objects.STRING_OBJ && right.Type() == objects.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case operator == "*" && left.Type() == objects.STRING_OBJ && right.Type() == objects.INTEGER_OBJ:
+		return repeatString(left.(*objects.String), right.(*objects.Integer))
+	case operator == "*" && left.Type() == objects.INTEGER_OBJ && right.Type() == objects.STRING_OBJ:
+		return repeatString(right.(*objects.String), left.(*objects.Integer))
+	case left.Type() == objects.INSTANCE_OBJ && right.Type() == objects.INSTANCE_OBJ && (operator == "==" || operator == "!="):
+		equal := left.(*objects.Instance).Equal(right.(*objects.Instance))
+		return nativeBoolean(equal == (operator == "=="))
 	case operator == "==":
 		return nativeBoolean(left == right)
 	case operator == "!=":
@@ -478,6 +597,10 @@ func evalStringInfixExpression(operator string, left objects.Object, right objec
 		return nativeBoolean(leftVal == rightVal)
 	case "!=":
 		return nativeBoolean(leftVal != rightVal)
+	case "<":
+		return nativeBoolean(leftVal < rightVal)
+	case ">":
+		return nativeBoolean(leftVal > rightVal)
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
@@ -526,7 +649,10 @@ func evalProgram(stmts []ast.Statement, environment *objects.Environment) object
 	var result objects.Object
 
 	for _, stmt := range stmts {
-		result = Eval(stmt, environment)
+		if Debug != nil {
+			Debug.Statement(stmt, environment)
+		}
+		result = eval(stmt, environment)
 		switch res := result.(type) {
 		case *objects.ReturnValue:
 			return res.Value
@@ -542,8 +668,11 @@ func evalStatements(statements []ast.Statement, environment *objects.Environment
 	var result objects.Object
 
 	for _, stmt := range statements {
-		result = Eval(stmt, environment)
-		if result == nil || (result.Type() != objects.RETURN_VALUE_OBJ && result.Type() != objects.ERROR_OBJ) {
+		if Debug != nil {
+			Debug.Statement(stmt, environment)
+		}
+		result = eval(stmt, environment)
+		if result == nil || !isInterruption(result) {
 			continue
 		}
 		if result.Type() == objects.ERROR_OBJ {
@@ -1,7 +1,11 @@
 package evaluator_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/YReshetko/rash-lang/evaluator"
+	"github.com/YReshetko/rash-lang/extensions"
 	"github.com/YReshetko/rash-lang/lexer"
 	"github.com/YReshetko/rash-lang/loaders"
 	"github.com/YReshetko/rash-lang/objects"
@@ -9,7 +13,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"math"
+	"os"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -309,13 +315,13 @@ func TestIncludeDeclarations(t *testing.T) {
 		`, 12},
 		{`
 			# test "fixtures/test.rs"; 
-			let a = test.const - 9;
+			let a = test.constant - 9;
 			a;
 		`, 120},
 		{`
 			# test "fixtures/test.rs";
-			let valOne = test.const - 120;
-			let valTwo = test.const;
+			let valOne = test.constant - 120;
+			let valTwo = test.constant;
 			let add = fn(x, y){
 				return x + y;
 			};
@@ -323,8 +329,8 @@ func TestIncludeDeclarations(t *testing.T) {
 		`, 276},
 		{`
 			# test "fixtures/test.rs";
-			let valOne = test.const - test.const * 2;
-			let valTwo = fn(v){return v - 120}(test.const);
+			let valOne = test.constant - test.constant * 2;
+			let valTwo = fn(v){return v - 120}(test.constant);
 			let add = fn(x, y){
 				return x + y;
 			};
@@ -408,7 +414,7 @@ func TestArrayDefinition(t *testing.T) {
 	}{
 		{"[1, 2 * 2, 3 == 3];", []interface{}{1, 4, true}},
 		{`let func = fn(a, b) {a + b}; [1, func("hello ", "world"), 3 == 3];`, []interface{}{1, "hello world", true}},
-		{`# test "fixtures/test.rs"; [1, "hello " + "world", test.const];`, []interface{}{1, "hello world", 129}},
+		{`# test "fixtures/test.rs"; [1, "hello " + "world", test.constant];`, []interface{}{1, "hello world", 129}},
 	}
 	for _, test := range tests {
 		obj := testEval(t, test.input)
@@ -605,16 +611,7 @@ func assertArrayObject(t *testing.T, obj objects.Object, value []interface{}) {
 	require.True(t, ok)
 	require.Equal(t, len(value), len(arr.Elements))
 	for i, v := range value {
-		switch exp := v.(type) {
-		case int:
-			assertIntegerObject(t, arr.Elements[i], int64(exp))
-		case string:
-			assertStringObject(t, arr.Elements[i], exp)
-		case bool:
-			assertBooleanObject(t, arr.Elements[i], exp)
-		default:
-			assert.Fail(t, "unsupported type %T", v)
-		}
+		assertValue(t, arr.Elements[i], v)
 	}
 }
 
@@ -664,6 +661,7 @@ func assertStringObject(t *testing.T, obj objects.Object, expectedValue string)
 }
 
 func testEval(t *testing.T, input string) objects.Object {
+	loaders.Reset()
 	l := lexer.New(input, "non-file")
 	require.NotNil(t, l)
 
@@ -673,5 +671,973 @@ func testEval(t *testing.T, input string) objects.Object {
 	node := p.ParseProgram()
 	require.Empty(t, p.Errors())
 
-	return evaluator.Eval(node, objects.NewEnvironment())
+	return evaluator.Execute(node, objects.NewEnvironment())
+}
+
+func TestAsyncFunction(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{"let f = async fn(x) { return x * 2; }; await f(21);", 42},
+		{"let f = async fn() { 1 }; let g = async fn() { await f() + 1 }; await g();", 2},
+		{"let f = async fn(x) { x }; let p = f(1); await p + await p;", 2},
+		{"await 5", 5},
+		{"let f = async fn() { 1 + true }; await f();", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = async fn() { 1 }; f();", "promise<pending>"},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		switch exp := test.value.(type) {
+		case int:
+			assertIntegerObject(t, obj, int64(exp))
+		case string:
+			if obj.Type() == objects.ERROR_OBJ {
+				assertError(t, obj, exp)
+			} else {
+				assert.Equal(t, exp, obj.Inspect())
+			}
+		}
+	}
+}
+
+func TestPromiseAndTimers(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`await promise(fn(resolve, reject){ resolve(5); });`, 5},
+		{`await promise(fn(resolve, reject){ reject("boom"); });`, "promise rejected: boom"},
+		{`await promise(fn(resolve, reject){ setTimeout(resolve, 5, 7); });`, 7},
+		{`
+			let order = "";
+			let push = fn(v) { order = order + v };
+			let all = promise(fn(resolve, reject){
+				setTimeout(fn(){ push("3"); resolve([order]); }, 30);
+				setTimeout(fn(){ push("2"); }, 15);
+				setTimeout(fn(){ push("1"); }, 1);
+			});
+			await all;
+		`, []interface{}{"123"}},
+		{`
+			let count = 0;
+			await promise(fn(resolve, reject){
+				let id = 0;
+				id = setInterval(fn(){
+					count = count + 1;
+					if (count == 3) {
+						clearTimer(id);
+						resolve(count);
+					}
+				}, 1);
+			});
+		`, 3},
+		{`let id = setTimeout(fn(){}, 1000); clearTimer(id);`, true},
+		{`clearTimer(1000000);`, false},
+		{`await promise(fn(resolve, reject){});`, "deadlock: all tasks are asleep"},
+		{`setTimeout(1, 1);`, "`setTimeout` expects function as first argument, but got INTEGER"},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		switch exp := test.value.(type) {
+		case int:
+			assertIntegerObject(t, obj, int64(exp))
+		case bool:
+			assertBooleanObject(t, obj, exp)
+		case string:
+			assertError(t, obj, exp)
+		case []interface{}:
+			assertArrayObject(t, obj, exp)
+		}
+	}
+}
+
+func TestSpawnAndChannels(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`let t = spawn fn(){ 40 + 2 }; join(t);`, 42},
+		{`let add = fn(a, b){ a + b }; join(spawn add(40, 2));`, 42},
+		{`let t = spawn fn(){ 1 + true }; join(t);`, "type mismatch: INTEGER + BOOLEAN"},
+		{`spawn 5`, "spawn expects a function, but got INTEGER"},
+		{`
+			let ch = channel();
+			spawn fn(){ send(ch, 5); };
+			recv(ch);
+		`, 5},
+		{`
+			let ch = channel(3);
+			send(ch, 1); send(ch, 2); send(ch, 3);
+			close(ch);
+			[recv(ch), recv(ch), recv(ch)];
+		`, []interface{}{1, 2, 3}},
+		{`let ch = channel(1); close(ch); recv(ch);`, nil},
+		{`let ch = channel(1); close(ch); close(ch);`, "close of closed channel"},
+		{`let ch = channel(1); close(ch); send(ch, 1);`, "send on closed channel"},
+		{`let ch = channel(); recv(ch);`, "deadlock: all tasks are asleep"},
+		{`
+			let results = channel();
+			let worker = fn(n) { send(results, n * n); };
+			for (let i = 1; i < 5; i = i + 1) {
+				spawn worker(i);
+			}
+			let sum = 0;
+			for (let i = 1; i < 5; i = i + 1) {
+				sum = sum + recv(results);
+			}
+			sum;
+		`, 30},
+		{`
+			let ch = channel();
+			let t = spawn fn(){
+				let sum = 0;
+				for (let v = recv(ch); v != 0; v = recv(ch)) {
+					sum = sum + v;
+				}
+				sum;
+			};
+			send(ch, 1); send(ch, 2); send(ch, 3); send(ch, 0);
+			join(t);
+		`, 6},
+		{`
+			let ch = channel(1);
+			setTimeout(fn(){ send(ch, 7) }, 5);
+			recv(ch);
+		`, 7},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		switch exp := test.value.(type) {
+		case int:
+			assertIntegerObject(t, obj, int64(exp))
+		case nil:
+			assertNullObject(t, obj)
+		case string:
+			assertError(t, obj, exp)
+		case []interface{}:
+			assertArrayObject(t, obj, exp)
+		}
+	}
+}
+
+func TestSelectExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`
+			let a = channel(1);
+			let b = channel(1);
+			send(b, "b");
+			select {
+				case v = recv(a) { "a: " + v }
+				case v = recv(b) { "b: " + v }
+			}
+		`, "b: b"},
+		{`
+			let a = channel();
+			select {
+				case v = recv(a) { v }
+				default { "default" }
+			}
+		`, "default"},
+		{`
+			let a = channel(1);
+			select {
+				case send(a, 1) { recv(a) + 1 }
+			}
+		`, 2},
+		{`
+			let a = channel();
+			spawn fn(){ send(a, 10); };
+			select {
+				case v = recv(a) { v }
+			}
+		`, 10},
+		{`
+			let a = channel(1);
+			close(a);
+			select {
+				case v = recv(a) { v }
+			}
+		`, nil},
+		{`
+			let timeout = channel();
+			let never = channel();
+			setTimeout(fn(){ close(timeout) }, 5);
+			select {
+				case v = recv(never) { "value" }
+				case recv(timeout) { "timeout" }
+			}
+		`, "timeout"},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		switch exp := test.value.(type) {
+		case int:
+			assertIntegerObject(t, obj, int64(exp))
+		case nil:
+			assertNullObject(t, obj)
+		case string:
+			assertStringObject(t, obj, exp)
+		}
+	}
+}
+
+func TestCoreBuiltins(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`len("")`, 0},
+		{`len("hello")`, 5},
+		{`len([1, 2, 3])`, 3},
+		{`len({"a": 1})`, 1},
+		{`len(1)`, errorValue("`len` expects string, array, hash or range, but got INTEGER")},
+		{`len("a", "b")`, errorValue("wrong number of arguments to `len`; got=2, expected=1")},
+		{`type(1)`, "INTEGER"},
+		{`type(fn(){})`, "FUNCTION"},
+		{`type(len)`, "BUILTIN"},
+		{`str(12)`, "12"},
+		{`str([1, "a"])`, "[1, a]"},
+		{`str({"b": 2, "a": 1})`, "{a:1, b:2}"},
+		{`int("42")`, 42},
+		{`int(" -7 ")`, -7},
+		{`int(3.99)`, 3},
+		{`int(true)`, 1},
+		{`int("x")`, errorValue("`int` unable to convert \"x\" to integer")},
+		{`int("010")`, 10},
+		{`int("0x1f")`, errorValue("`int` unable to convert \"0x1f\" to integer")},
+		{`int("1_000")`, errorValue("`int` unable to convert \"1_000\" to integer")},
+		{`float("1.5") * 2`, 3},
+		{`bool(0)`, true},
+		{`bool(false)`, false},
+		{`keys({"b": 2, "a": 1, "c": 3})`, []interface{}{"a", "b", "c"}},
+		{`keys({3: "c", 10: "j", 1: "a"})`, []interface{}{1, 3, 10}},
+		{`values({"b": 2, "a": 1, "c": 3})`, []interface{}{1, 2, 3}},
+		{`keys([])`, errorValue("`keys` expects hash, but got ARRAY")},
+		{`has({"a": 1}, "a")`, true},
+		{`has({"a": 1}, "b")`, false},
+		{`has({"a": 1}, [])`, errorValue("unusable as a hash key: ARRAY")},
+		{`let h = {"a": 1, "b": 2}; let v = delete(h, "a"); [v, len(h)]`, []interface{}{1, 1}},
+		{`delete({}, "a")`, nil},
+		{`let a = [1]; push(a, 2, 3); a`, []interface{}{1, 2, 3}},
+		{`let a = [1, 2]; let v = pop(a); [v, len(a)]`, []interface{}{2, 1}},
+		{`pop([])`, nil},
+		{`slice([1, 2, 3, 4], 1, 3)`, []interface{}{2, 3}},
+		{`slice([1, 2, 3, 4], 2)`, []interface{}{3, 4}},
+		{`slice([1, 2, 3, 4], -2)`, []interface{}{3, 4}},
+		{`slice([1, 2, 3, 4], 3, 1)`, []interface{}{}},
+		{`slice("hello", 1, 3)`, "el"},
+		{`slice(1, 1)`, errorValue("slice operator not supported for: INTEGER")},
+		{`concat([1], [2, 3], [])`, []interface{}{1, 2, 3}},
+		{`concat("a", "b")`, "ab"},
+		{`concat([1], "b")`, errorValue("`concat` expects all arguments to be ARRAY, but got STRING")},
+		{`range(3)`, []interface{}{0, 1, 2}},
+		{`range(2, 5)`, []interface{}{2, 3, 4}},
+		{`range(5, 0, -2)`, []interface{}{5, 3, 1}},
+		{`range(1, 2, 0)`, errorValue("`range` step must not be zero")},
+		{`assert(1 == 1)`, nil},
+		{`assert(1 == 2, "numbers differ")`, errorValue("assertion failed: numbers differ")},
+		{`assert(false)`, errorValue("assertion failed")},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestPrintBuiltins(t *testing.T) {
+	out := &bytes.Buffer{}
+	evaluator.Output = out
+
+	testEval(t, `print("a", 1, [true]); println(); println("b", {"k": 2.5});`)
+	assert.Equal(t, "a 1 [true]\nb {k:2.500000}\n", out.String())
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`len("привет")`, 6},
+		{`"привет"[1]`, "р"},
+		{`"abc"[-1]`, nil},
+		{`"abc"[3]`, nil},
+		{`"привет"[1:3]`, "ри"},
+		{`"hello"[:2]`, "he"},
+		{`"hello"[-3:]`, "llo"},
+		{`"hello"[:]`, "hello"},
+		{`[1, 2, 3, 4][1:-1]`, []interface{}{2, 3}},
+		{`slice("привет", 1, 3)`, "ри"},
+		{`"a"[1:"b"]`, errorValue("slice expects integer bounds, but got STRING")},
+		{`5[1:2]`, errorValue("slice operator not supported for: INTEGER")},
+		{`"abc" < "abd"`, true},
+		{`"b" > "abc"`, true},
+		{`"ab" * 3`, "ababab"},
+		{`2 * "xy"`, "xyxy"},
+		{`"ab" * -1`, errorValue("negative repeat count: -1")},
+		{`"ab" - "b"`, errorValue("unknown operator: STRING - STRING")},
+		{`split("a,b,,c", ",")`, []interface{}{"a", "b", "", "c"}},
+		{`split("abc", "")`, []interface{}{"a", "b", "c"}},
+		{`join(["a", 1, true], "-")`, "a-1-true"},
+		{`join("a", "-")`, errorValue("wrong number of arguments to `join`; got=2, expected=1")},
+		{`join("a")`, errorValue("`join` expects task or array, but got STRING")},
+		{`join(["a"])`, errorValue("wrong number of arguments to `join`; got=1, expected=2")},
+		{`join(["a"], 1)`, errorValue("`join` expects string as second argument, but got INTEGER")},
+		{`replace("aaa", "a", "b")`, "bbb"},
+		{`replace("aaa", "a", "b", 2)`, "bba"},
+		{`trim("  a b \n")`, "a b"},
+		{`trim("xxaxx", "x")`, "a"},
+		{`trimLeft("  a  ")`, "a  "},
+		{`trimRight("  a  ")`, "  a"},
+		{`trimRight("a!?", "!?")`, "a"},
+		{`trimPrefix("prefix-a", "prefix-")`, "a"},
+		{`trimSuffix("a.rs", ".rs")`, "a"},
+		{`upper("привет")`, "ПРИВЕТ"},
+		{`lower("ABC")`, "abc"},
+		{`contains("hello", "ell")`, true},
+		{`contains("hello", 1)`, errorValue("`contains` expects string arguments, but got INTEGER")},
+		{`startsWith("hello", "he")`, true},
+		{`endsWith("hello", "he")`, false},
+		{`indexOf("привет", "вет")`, 3},
+		{`indexOf("hello", "x")`, -1},
+		{`repeat("-", 3)`, "---"},
+		{`repeat("-", -3)`, errorValue("negative repeat count: -3")},
+		{`chars("при")`, []interface{}{"п", "р", "и"}},
+		{`format("%s=%d (%.1f) %v", "a", 1, 2.25, true)`, "a=1 (2.2) true"},
+		{`format("%v %s", [1, 2], {"a": 1})`, "[1, 2] {a:1}"},
+		{`format(1)`, errorValue("`format` expects string as first argument, but got INTEGER")},
+		{`upper()`, errorValue("wrong number of arguments to `upper`; got=0, expected=1")},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestTemplateLiteral(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`let name = "rash"; "hello, ${name}!"`, "hello, rash!"},
+		{`let a = 2; "${a} * ${a} = ${a * a}"`, "2 * 2 = 4"},
+		{`"${[1, "a"]} ${ {"k": true} } ${1.5}"`, "[1, a] {k:true} 1.500000"},
+		{`let f = fn(x){ "<${x}>" }; "${f("${1 + 1}")}"`, "<2>"},
+		{`"\${a}"`, "${a}"},
+		{`"${unknown}"`, errorValue("identifier not found: unknown")},
+		{"`multi\\nline\n${x}`", "multi\\nline\n${x}"},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`"a,b".split(",")`, []interface{}{"a", "b"}},
+		{`let s = " Hello "; s.trim().lower().len()`, 5},
+		{`"привет"[1:].upper()`, "РИВЕТ"},
+		{`"%s-%d".format("a", 1)`, "a-1"},
+		{`let a = [3, 1, 2]; a.push(4); a`, []interface{}{3, 1, 2, 4}},
+		{`[1, 2, 3].map(fn(x){ x * 2 }).filter(fn(x){ x > 2 }).reduce(fn(acc, x){ acc + x }, 0)`, 10},
+		{`["a", "b"].join("-")`, "a-b"},
+		{`let h = {"b": 2, "a": 1}; h.keys()`, []interface{}{"a", "b"}},
+		{`let h = {"name": "rash", "items": [1, 2, 3]}; [h.name, h.items[1], h.items[1:].len()]`, []interface{}{"rash", 2, 2}},
+		{`let h = {"keys": fn(){ "own" }}; h.keys()`, "own"},
+		{`let h = {"inner": {"value": 42}}; h.inner.value`, 42},
+		{`let h = {}; h.missing`, nil},
+		{`let h = {"add": fn(a, b){ a + b }}; h.add(1, 2)`, 3},
+		{`let server = {"start": fn(){ "started" }}; server.start()`, "started"},
+		{`let x = 42; x.str() + x.float().str()`, "4242.000000"},
+		{`let f = "abc".upper; f()`, "ABC"},
+		{`let ch = channel(1); ch.send(5); ch.recv()`, 5},
+		{`let t = spawn fn(){ 7 }; t.join()`, 7},
+		{`let x = 1; x.push(2)`, errorValue("undefined method push for INTEGER")},
+		{`"abc".split()`, errorValue("wrong number of arguments to `split`; got=1, expected=2")},
+		{`let h = {"x": 1}; h.x()`, errorValue("not a function: INTEGER")},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestStructs(t *testing.T) {
+	point := `
+struct Point {
+	x, y = 0;
+	fn len2(self) { self.x * self.x + self.y * self.y }
+	fn move(self, dx, dy) { self.x = self.x + dx; self.y = self.y + dy; self }
+}
+`
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{point + `let p = Point(3, 4); p.len2()`, 25},
+		{point + `let p = Point(1); [p.x, p.y]`, []interface{}{1, 0}},
+		{point + `Point().x`, nil},
+		{point + `let p = Point(1, 2); p.move(1, 1).move(1, 1); [p.x, p.y]`, []interface{}{3, 4}},
+		{point + `let p = Point(1, 2); p.x = 10; p.x`, 10},
+		{point + `str(Point(1, [2]))`, "Point{x: 1, y: [2]}"},
+		{point + `Point(1, 2).str()`, "Point{x: 1, y: 2}"},
+		{point + `[type(Point(1, 2)), type(Point)]`, []interface{}{"Point", "STRUCT"}},
+		{point + `let p = Point(1, 2); [p == p, p != p]`, []interface{}{true, false}},
+		{point + `Point(1, 2) == Point(1, 2)`, false},
+		{point + `Point(1, 2) != Point(1, 2)`, true},
+		{point + `let p = Point(1, 2); let q = p; q.x = 3; p == q`, true},
+		{point + `let p = Point(1, 2); let h = {p: "a"}; p.x = 5; h[p]`, "a"},
+		{point + `let h = {Point(1, 2): "a"}; h[Point(1, 2)]`, nil},
+		{point + `let p = Point(1, 2); let q = Point(1, 2); [p == q, len(unique([p, q])), len(unique([p, p])), {p: 1}[q]]`, []interface{}{false, 2, 1, nil}},
+		{`struct P { a }; let x = P(1); x.a = x; [x == x, x == P(x), x == P(1)]`, []interface{}{true, false, false}},
+		{point + `map([1, 2], Point).map(fn(p){ p.x })`, []interface{}{1, 2}},
+		{point + `let m = Point(1, 2).len2; m()`, 5},
+		{point + `Point(1, 2, 3)`, errorValue("too many arguments to create Point; got=3, expected<=2")},
+		{point + `Point(1, 2).z`, errorValue("undefined member z of Point")},
+		{point + `let p = Point(1, 2); p.z = 1`, errorValue("undefined field z of Point")},
+		{point + `Point(1, 2).move(1)`, errorValue("number of function parameters mismatch: expected=2, got=1")},
+		{`struct Counter {
+			count, step;
+			fn init(self, step) { self.count = 0; self.step = step; }
+			fn inc(self) { self.count = self.count + self.step; }
+		}
+		let c = Counter(5); c.inc(); c.inc(); c.count`, 10},
+		{`struct Failing { fn init(self) { assert(false, "init failed") } }; Failing()`, errorValue("assertion failed: init failed")},
+		{`let base = 100; struct Box { items = [], limit = base }; let a = Box(); let b = Box(); a.items.push(1); [len(b.items), a.limit]`, []interface{}{0, 100}},
+		{`struct Node { value, children = [] }; let n = Node(1); n.children[0] = 5`, errorValue("index outbound: len=0, ind=0")},
+		{`let h = {"a": 1}; h.b = 2; h.a = h.a + h.b; h.a`, 3},
+		{`let h = {"items": [1, 2]}; h.items[1] = 5; h.items`, []interface{}{1, 5}},
+		{`let x = 1; x.y = 2`, errorValue("unsupported member assignment on: INTEGER")},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`let [a, b] = [1, 2]; a + b`, 3},
+		{`let [a, [b, c]] = [1, [2, 3]]; [a, b, c]`, []interface{}{1, 2, 3}},
+		{`let [first, ...rest] = [1, 2, 3]; [first, rest]`, []interface{}{1, []interface{}{2, 3}}},
+		{`let [a, ...rest] = [1]; rest`, []interface{}{}},
+		{`let {name, port} = {"name": "srv", "port": 80, "debug": true}; "${name}:${port}"`, "srv:80"},
+		{`let {name: n, ...other} = {"name": "srv", "port": 80}; [n, other.port, len(other)]`, []interface{}{"srv", 80, 1}},
+		{`let {server: {port}} = {"server": {"port": 8080}}; port`, 8080},
+		{`struct P { x, y }; let {x, y} = P(1, 2); x + y`, 3},
+		{`let [a, b] = [1]`, errorValue("array destructuring mismatch: expected=2, got=1")},
+		{`let [a, b, ...c] = [1]`, errorValue("array destructuring mismatch: expected>=2, got=1")},
+		{`let [a] = {"a": 1}`, errorValue("unable to destructure HASH as array")},
+		{`let {a} = [1]`, errorValue("unable to destructure ARRAY as hash")},
+		{`let {a} = {"b": 1}`, errorValue("missing key a in destructuring of HASH")},
+		{`let a = 1; let b = 2; a, b = b, a; [a, b]`, []interface{}{2, 1}},
+		{`let a = 0; let b = 0; a, b = [3, 4]; a * b`, 12},
+		{`let arr = [1, 2]; let h = {}; arr[0], h.x = arr[1], arr[0]; [arr, h.x]`, []interface{}{[]interface{}{2, 2}, 1}},
+		{`let a = 0; let b = 0; a, b = 1, 2, 3`, errorValue("assignment mismatch: 2 targets, but 3 values")},
+		{`let a = 0; a, c = 1, 2`, errorValue("identifier not defined: c")},
+		{`let f = fn([a, b], {c}) { a + b + c }; f([1, 2], {"c": 3})`, 6},
+		{`let f = fn([a, b]) { a + b }; f([1])`, errorValue("array destructuring mismatch: expected=2, got=1")},
+		{`map([[1, 2], [3, 4]], fn([a, b]) { a * b })`, []interface{}{2, 12}},
+		{`let f = async fn([a, b]) { a + b }; await f([1, 2])`, 3},
+		{`let f = async fn([a, b]) { a + b }; await f(1)`, errorValue("unable to destructure INTEGER as array")},
+		{`struct V { x; fn add(self, {x}) { self.x + x } }; V(1).add(V(2))`, 3},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestModuleAssignment(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`# c "fixtures/config.rs"; c.counter = 5; c.next()`, 6},
+		{`# c "fixtures/config.rs"; c.next(); c.next(); c.counter`, 2},
+		{`# c "fixtures/config.rs"; c.settings["debug"] = true; c.settings.debug`, true},
+		{`# c "fixtures/config.rs"; c.settings.level = 3; c.settings["level"]`, 3},
+		{`# c "fixtures/config.rs"; c.version`, "1.0"},
+		{`# c "fixtures/config.rs"; c.version = "2.0"`, errorValue("cannot assign to constant version")},
+		{`# c "fixtures/config.rs"; c.missing = 1`, errorValue("undefined name missing in the module")},
+		{`# * from "fixtures/config.rs"; version = "2.0"`, errorValue("cannot assign to constant version")},
+		{`# {version} from "fixtures/config.rs"; version = "2.0"`, errorValue("cannot assign to constant version")},
+		{`# {version as v} from "fixtures/config.rs"; v = "2.0"`, errorValue("cannot assign to constant v")},
+		{`# {counter} from "fixtures/config.rs"; counter = 5; counter`, 5},
+		{`# c "fixtures/config.rs"; c._secret`, errorValue("_secret is private to the module")},
+		{`# c "fixtures/config.rs"; c._secret = ""`, errorValue("_secret is private to the module")},
+		{`# c "fixtures/config.rs"; c.secret()`, "hidden"},
+		{`const a = 1; let f = fn() { let a = 2; a = 3; a }; f()`, 3},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestModuleResolution(t *testing.T) {
+	require.NoError(t, os.Setenv("RASH_PATH", "fixtures/lib"))
+	defer os.Unsetenv("RASH_PATH")
+	loaders.Default.Root = "fixtures/project"
+	defer func() { loaders.Default.Root = "" }()
+
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`# a "answer.rs"; a.answer`, 42},
+		{`# g "greet"; g.hello("rash")`, "hello rash"},
+		{`# {bye} from "greet/bye.rs"; bye`, "bye"},
+		{`# a "fixtures/lib/answer.rs"; a.answer`, 42},
+		{`# c "fixtures/lib/counter.rs"; c.next()`, 1},
+		{`# c "fixtures/config.rs"; # l "counter.rs"; c.next(); l.next(); c.counter`, 2},
+		{`# c "fixtures/config.rs"; # d "fixtures/config.rs"; c.counter = 10; d.counter`, 10},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`# m "missing.rs"; m`, "unable to load included script missing.rs: not found next to non-file, in the project root, rash_modules or RASH_PATH"},
+		{`# a "fixtures/cycle_a.rs"; a`, "import cycle: fixtures/cycle_a.rs -> fixtures/cycle_b.rs -> fixtures/cycle_a.rs"},
+	}
+	for _, test := range errors {
+		obj := testEval(t, test.input)
+		errObj, ok := obj.(*objects.Error)
+		require.True(t, ok, test.input)
+		assert.Contains(t, errObj.Message, test.expected, test.input)
+	}
+}
+
+func TestSelectiveImports(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`# {double, limit} from "fixtures/exports.rs"; double(limit)`, 20},
+		{`# {double as twice} from "fixtures/exports.rs"; twice(2)`, 4},
+		{`# * from "fixtures/exports.rs"; [double(1), limit, first, second]`, []interface{}{2, 10, 1, 2}},
+		{`# * from "fixtures/exports.rs"; helper`, errorValue("identifier not found: helper")},
+		{`# {Pair} from "fixtures/exports.rs"; Pair(1, 2).b`, 2},
+		{`# {cfg} from "fixtures/exports.rs"; cfg.version`, "1.0"},
+		{`# e "fixtures/exports.rs"; e.helper`, errorValue("helper is private to the module")},
+		{`# e "fixtures/exports.rs"; e.helper = 1`, errorValue("helper is private to the module")},
+		{`# {helper} from "fixtures/exports.rs"`, errorValue("helper is private to the module")},
+		{`# {counter, next} from "fixtures/config.rs"; next(); next()`, 2},
+		{`# {missing} from "fixtures/config.rs"`, errorValue("undefined name missing in the module")},
+		{`# {_secret} from "fixtures/config.rs"`, errorValue("_secret is private to the module")},
+		{`# * from "fixtures/config.rs"; [version, secret()]`, []interface{}{"1.0", "hidden"}},
+		{`# * from "fixtures/config.rs"; _secret`, errorValue("identifier not found: _secret")},
+		{`# r "fixtures/reexport.rs"; [r.double(3), r.answer, r.bump(), r.cfg.counter]`, []interface{}{6, 42, 1, 1}},
+		{`# r "fixtures/reexport.rs"; r.helper`, errorValue("helper is private to the module")},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`let a = freeze([1, [2]]); a[0] = 5`, errorValue("cannot modify frozen ARRAY")},
+		{`let a = freeze([1, [2]]); a[1][0] = 5`, errorValue("cannot modify frozen ARRAY")},
+		{`let h = freeze({"a": {"b": 1}}); h["a"]["b"] = 2`, errorValue("cannot modify frozen HASH")},
+		{`let h = freeze({"a": 1}); h.a = 2`, errorValue("cannot modify frozen HASH")},
+		{`let h = freeze({"a": 1}); delete(h, "a")`, errorValue("cannot modify frozen HASH")},
+		{`let a = [1].freeze(); push(a, 2)`, errorValue("cannot modify frozen ARRAY")},
+		{`let a = freeze([1]); a.pop()`, errorValue("cannot modify frozen ARRAY")},
+		{`let a = freeze([3, 1, 2]); [sort(a), a.map(fn(x) { x * 2 })]`, []interface{}{[]interface{}{1, 2, 3}, []interface{}{6, 2, 4}}},
+		{`let a = freeze([1]); [frozen(a), frozen(slice(a, 0, 1)), frozen([1]), frozen(5)]`, []interface{}{true, false, false, false}},
+		{`let a = [1]; push(a, a); freeze(a); frozen(a[1])`, true},
+		{`freeze(5)`, 5},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestBreakContinue(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`let i = 0; for () { i = i + 1; if (i == 5) { break; } }; i`, 5},
+		{`let s = 0; for (let i = 0; i < 5; i = i + 1) { if (i == 2) { continue }; s = s + i }; s`, 8},
+		{`let r = []; outer: for (let i = 0; i < 3; i = i + 1) { for (let j = 0; j < 3; j = j + 1) { if (j == 2) { continue outer }; if (i == 2) { break outer }; push(r, [i, j]) } }; r`,
+			[]interface{}{[]interface{}{0, 0}, []interface{}{0, 1}, []interface{}{1, 0}, []interface{}{1, 1}}},
+		{`let f = fn() { for () { return 7; } }; f()`, 7},
+		{`let i = 0; for () { i = i + 1; select { default { if (i > 2) { break } } } }; i`, 3},
+		{`let i = 0; let v = for (i < 10) { i = i + 1; if (i == 3) { break }; i * 10 }; v`, 20},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestForIn(t *testing.T) {
+	registry := extensions.New()
+	registry.Register(&streamPlugin{})
+	evaluator.InitRegistry(registry)
+
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`let s = 0; for (x in [1, 2, 3]) { s = s + x }; s`, 6},
+		{`let r = []; for (i, x in ["a", "b"]) { push(r, str(i) + x) }; r`, []interface{}{"0a", "1b"}},
+		{`let r = []; for (k in {"b": 2, "a": 1}) { push(r, k) }; r`, []interface{}{"a", "b"}},
+		{`let r = []; for (k, v in {"b": 2, "a": 1}) { push(r, k + str(v)) }; r`, []interface{}{"a1", "b2"}},
+		{`let r = []; for (i, ch in "héj") { push(r, str(i) + ch) }; r`, []interface{}{"0h", "1é", "2j"}},
+		{`let s = 0; for (i in 0..5) { s = s + i }; s`, 10},
+		{`let r = []; for (i, v in 3..5) { push(r, [i, v]) }; r`, []interface{}{[]interface{}{0, 3}, []interface{}{1, 4}}},
+		{`let s = 0; for (i in 5..0) { s = s + 1 }; s`, 0},
+		{`[len(0..10), str(2..4), type(1..2)]`, []interface{}{10, "2..4", "RANGE"}},
+		{`1.5..3`, errorValue("range expects integer bounds, but got DOUBLE")},
+		{`for (x in 5) {}`, errorValue("unable to iterate over INTEGER")},
+		{`let a = [1, 2]; for (x in a) { push(a, x) }; a`, []interface{}{1, 2, 1, 2}},
+		{`let s = 0; for (i in 0..10) { if (i == 3) { continue }; if (i == 5) { break }; s = s + i }; s`, 7},
+		{`let r = []; outer: for (i in 0..3) { for (j in 0..3) { if (j > i) { continue outer }; push(r, j) } }; r`, []interface{}{0, 0, 1, 0, 1, 2}},
+		{`let fs = []; for (i in 0..3) { push(fs, fn() { i }) }; map(fs, fn(f) { f() })`, []interface{}{0, 1, 2}},
+		{`let f = fn() { for (x in [1, 2]) { return x * 10 } }; f()`, 10},
+		{`let r = []; for (v in eval("stream", "values", 3)) { push(r, v) }; r`, []interface{}{"v0", "v1", "v2"}},
+		{`for (v in eval("stream", "broken")) {}`, errorValue("iterator err: broken stream")},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+// streamPlugin returns iterators over generated values
+type streamPlugin struct{}
+
+type streamIterator struct {
+	index, count int
+	err          error
+}
+
+func (s *streamIterator) Next() (interface{}, bool, error) {
+	if s.err != nil {
+		return nil, false, s.err
+	}
+	if s.index >= s.count {
+		return nil, false, nil
+	}
+	s.index++
+	return fmt.Sprintf("v%d", s.index-1), true, nil
+}
+
+func (s *streamPlugin) Eval(fnName string, args ...interface{}) ([]interface{}, error) {
+	if fnName == "broken" {
+		return []interface{}{&streamIterator{err: errors.New("broken stream")}}, nil
+	}
+	return []interface{}{&streamIterator{count: int(args[0].(int64))}}, nil
+}
+
+func (s *streamPlugin) Call(string, func(args ...interface{}) ([]interface{}, error), ...interface{}) ([]interface{}, error) {
+	return nil, nil
+}
+
+func (s *streamPlugin) Package() string     { return "stream" }
+func (s *streamPlugin) Version() string     { return "0.0.1" }
+func (s *streamPlugin) Description() string { return "test iterators" }
+
+// callbackPlugin calls the callback during the plugin call with "now",
+// or keeps it and calls it after the plugin call returns with "later".
+// Eval "meet" waits for another concurrent call of "meet", the iterator returned by "stream" meets on each value
+type callbackPlugin struct {
+	meet chan struct{}
+}
+
+type meetIterator struct {
+	plugin *callbackPlugin
+	count  int
+}
+
+func (m *meetIterator) Next() (interface{}, bool, error) {
+	if m.count == 0 {
+		return nil, false, nil
+	}
+	m.count--
+	values, err := m.plugin.Eval("meet")
+	if err != nil {
+		return nil, false, err
+	}
+	return values[0], true, nil
+}
+
+func (c *callbackPlugin) Eval(fnName string, _ ...interface{}) ([]interface{}, error) {
+	if fnName == "stream" {
+		return []interface{}{&meetIterator{plugin: c, count: 2}}, nil
+	}
+	select {
+	case c.meet <- struct{}{}:
+		return []interface{}{"sent"}, nil
+	case <-c.meet:
+		return []interface{}{"received"}, nil
+	case <-time.After(time.Second):
+		return nil, errors.New("plugin calls are not concurrent")
+	}
+}
+
+func (c *callbackPlugin) Call(fnName string, callback func(args ...interface{}) ([]interface{}, error), args ...interface{}) ([]interface{}, error) {
+	if fnName == "now" {
+		return callback(args...)
+	}
+	release := extensions.Keep()
+	go func() {
+		defer release()
+		time.Sleep(10 * time.Millisecond)
+		_, _ = callback(args...)
+	}()
+	return nil, nil
+}
+
+func (c *callbackPlugin) Package() string     { return "cb" }
+func (c *callbackPlugin) Version() string     { return "0.0.1" }
+func (c *callbackPlugin) Description() string { return "test callbacks" }
+
+func TestPluginCallbacks(t *testing.T) {
+	registry := extensions.New()
+	registry.Register(&callbackPlugin{meet: make(chan struct{})})
+	evaluator.InitRegistry(registry)
+
+	obj := testEval(t, `let r = 0; call("cb", "now", fn(x) { r = x; x * 2 }, 21) + r`)
+	assertValue(t, obj, 63)
+	assert.Nil(t, evaluator.RunEventLoop())
+
+	obj = testEval(t, `call("cb", "now", fn(x) { x }, 1); let ch = channel(); recv(ch)`)
+	assertValue(t, obj, errorValue("deadlock: all tasks are asleep"))
+
+	obj = testEval(t, `let r = []; call("cb", "later", fn(x) { push(r, x) }, 7); r`)
+	assertValue(t, obj, []interface{}{})
+	assert.Nil(t, evaluator.RunEventLoop())
+	assertValue(t, obj, []interface{}{7})
+
+	obj = testEval(t, `let meet = fn() { eval("cb", "meet") }; let a = spawn meet(); let b = spawn meet(); sort([join(a), join(b)])`)
+	assertValue(t, obj, []interface{}{"received", "sent"})
+
+	obj = testEval(t, `let t = spawn fn() { eval("cb", "meet") + eval("cb", "meet") }; let r = ""; for (v in eval("cb", "stream")) { r = r + v }; len(join(t) + r)`)
+	assertValue(t, obj, 24)
+}
+
+func TestEvalTakesInterpreter(t *testing.T) {
+	registry := extensions.New()
+	registry.Register(&callbackPlugin{meet: make(chan struct{})})
+	evaluator.InitRegistry(registry)
+
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`call("cb", "now", fn(x) { x * 2 }, 21)`, 42},
+		{`let t = spawn fn() { 42 }; join(t)`, 42},
+		{`let ch = channel(1); send(ch, 42); recv(ch)`, 42},
+		{`let f = async fn() { 42 }; await f()`, 42},
+		{`let meet = fn() { eval("cb", "meet") }; let a = spawn meet(); let b = spawn meet(); sort([join(a), join(b)])`, []interface{}{"received", "sent"}},
+	}
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		program := p.ParseProgram()
+		require.Empty(t, p.Errors())
+		assertValue(t, evaluator.Eval(program, objects.NewEnvironment()), test.value)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "other" }`, "two"},
+		{`let fib = fn(val) { match (val) { 1 => 0, 2 => 1, n => fib(n - 2) + fib(n - 1), } }; map([1, 2, 3, 10], fib)`, []interface{}{0, 1, 1, 34}},
+		{`match (5) { 1 => "one", _ => "other" }`, "other"},
+		{`match ("y") { "x" | "y" => "xy", _ => "other" }`, "xy"},
+		{`match (-1) { -1 => "minus one", _ => "other" }`, "minus one"},
+		{`match (1.5) { 1 => "int", 1.5 => "double" }`, "double"},
+		{`match (1) { 1.0 => "double", 1 => "int" }`, "int"},
+		{`match (true) { false => 0, true => 1 }`, 1},
+		{`match ([1, 2]) { [a] => a, [a, b] => a + b }`, 3},
+		{`match ([1, [2, 3], 4, 5]) { [1, [_, x], ...rest] => [x, rest] }`, []interface{}{3, []interface{}{4, 5}}},
+		{`match ({"k": 7, "z": 1}) { {"x": v} => v, {"k": v, ...rest} => [v, len(rest)] }`, []interface{}{7, 1}},
+		{`match ({"kind": "circle", "r": 2}) { {"kind": "square", side} => side * side, {"kind": "circle", r} => 3 * r * r }`, 12},
+		{`struct P { x, y }; match (P(1, 2)) { {x: 0} => "zero", {x, y} => x + y }`, 3},
+		{`match (15) { n if n > 10 => "big " + str(n), n => "small" }`, "big 15"},
+		{`match (5) { n if n > 10 => "big", n => "small " + str(n) }`, "small 5"},
+		{`let n = 1; match (2) { n => n }; n`, 1},
+		{`match (3) { 1 => "one" }`, errorValue("non-exhaustive match: no pattern matches 3")},
+		{`match ([1]) { [a, b] => a }`, errorValue("non-exhaustive match: no pattern matches [1]")},
+		{`match (1) { n if n.x => 1 }`, errorValue("undefined method x for INTEGER")},
+		{`match (2) { 2 => { let a = 5; a * 2 } }`, 10},
+		{`let f = fn(v) { match (v) { 0 => { return "zero" } }; "other" }; [f(0), f(1)]`, errorValue("non-exhaustive match: no pattern matches 1")},
+		{`let s = 0; for (x in [1, 2, 3, 4]) { match (x) { 2 => { continue }, 4 => { break }, _ => { s = s + x } } }; s`, 4},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`let f = fn(a, b = 2) { a * b }; [f(3), f(3, 4)]`, []interface{}{6, 12}},
+		{`let f = fn(a, b = a + 1) { [a, b] }; f(1)`, []interface{}{1, 2}},
+		{`let f = fn(a, ...rest) { [a, rest] }; [f(1), f(1, 2, 3)]`, []interface{}{[]interface{}{1, []interface{}{}}, []interface{}{1, []interface{}{2, 3}}}},
+		{`let f = fn(a, b, c) { a + b + c }; let args = [2, 3]; f(1, ...args)`, 6},
+		{`let a = [1, 2]; [0, ...a, ...[], 3]`, []interface{}{0, 1, 2, 3}},
+		{`let f = fn(a, b = 2, c = 3) { [a, b, c] }; f(1, c: 5)`, []interface{}{1, 2, 5}},
+		{`let f = fn(a, b) { a - b }; f(b: 1, a: 5)`, 4},
+		{`let f = fn([a, b] = [1, 2]) { a + b }; [f(), f([3, 4])]`, []interface{}{3, 7}},
+		{`let f = fn(x = y) { x }; let y = 5; f()`, 5},
+		{`struct P { x = 0, y = 0 }; let p = P(y: 2); [p.x, p.y]`, []interface{}{0, 2}},
+		{`struct P { x, y, fn init(self, x, y = 10) { self.x = x; self.y = y } }; let p = P(1); [p.x, p.y]`, []interface{}{1, 10}},
+		{`struct P { x, fn add(self, d = 1) { self.x + d } }; P(1).add(d: 5)`, 6},
+		{`let f = fn(a, b = 2) { a }; f()`, errorValue("number of function parameters mismatch: expected=1..2, got=0")},
+		{`let f = fn(a, b = 2) { a }; f(1, 2, 3)`, errorValue("number of function parameters mismatch: expected=1..2, got=3")},
+		{`let f = fn(a, b, ...rest) { a }; f(1)`, errorValue("number of function parameters mismatch: expected>=2, got=1")},
+		{`let f = fn(a) { a }; f(b: 1)`, errorValue("unexpected named argument b")},
+		{`let f = fn(a) { a }; f(1, a: 2)`, errorValue("multiple values for argument a")},
+		{`let f = fn(a, b) { a }; f(b: 2)`, errorValue("missing argument a")},
+		{`let f = fn(a) { a }; f(...5)`, errorValue("spread expects array, but got INTEGER")},
+		{`let a = ...[1]`, errorValue("spread is allowed only in call arguments and array literals")},
+		{`len(x: "abc")`, errorValue("named arguments are not supported by builtin functions")},
+		{`struct P { x }; P(z: 1)`, errorValue("undefined field z of P")},
+		{`struct P { x }; P(1, x: 2)`, errorValue("multiple values for field x")},
+		{`let f = fn(a = b) { a }; f()`, errorValue("identifier not found: b")},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+func TestNullSafety(t *testing.T) {
+	tests := []struct {
+		input  string
+		value  interface{}
+		strict bool
+	}{
+		{`null`, nil, false},
+		{`let a = null; a == null`, true, false},
+		{`null ?? 5`, 5, false},
+		{`0 ?? 5`, 0, false},
+		{`false ?? 5`, false, false},
+		{`let h = {"a": {"b": 2}}; [h?.a?.b, h?.x?.b, h.x?.b ?? -1]`, []interface{}{2, nil, -1}, false},
+		{`let a = null; a?.b?.c`, nil, false},
+		{`let a = null; a?.b.c`, errorValue("undefined method c for NULL"), false},
+		{`let a = null; a?[0]`, nil, false},
+		{`let a = null; a?[1:]`, nil, false},
+		{`let a = [[1, 2]]; [a?[0]?[1], a?[5]?[1]]`, []interface{}{2, nil}, false},
+		{`let f = null; f?.(1)`, nil, false},
+		{`let f = fn(x) { x * 2 }; f?.(2)`, 4, false},
+		{`let h = {"f": null}; h.f?.()`, nil, false},
+		{`let calls = 0; let f = fn() { calls = calls + 1 }; let a = null; a?[f()]; null?.(f()); 1 ?? f(); calls`, 0, false},
+		{`match (null) { 1 => "one", null => "null" }`, "null", false},
+		{`let a = null; a.b`, errorValue("undefined method b for NULL"), false},
+		{`{"a": 1}["b"]`, errorValue("key b not found in HASH"), true},
+		{`{"a": 1}.b`, errorValue("key b not found in HASH"), true},
+		{`[1, 2][2]`, errorValue("index 2 out of range for ARRAY of length 2"), true},
+		{`"ab"[-1]`, errorValue("index -1 out of range for STRING of length 2"), true},
+		{`[{"a": 1}?["b"], {"a": 1}?.b, [1]?[3], {"a": 1}["b"] ?? 0]`, errorValue("key b not found in HASH"), true},
+		{`[{"a": 1}?["b"], {"a": 1}?.b, [1]?[3], {"a": 1}?["b"] ?? 0]`, []interface{}{nil, nil, nil, 0}, true},
+	}
+	defer func() { evaluator.Strict = false }()
+	for _, test := range tests {
+		evaluator.Strict = test.strict
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
+}
+
+type errorValue string
+
+// assertValue checks the object against expected Go value, errorValue is used to expect error message
+func assertValue(t *testing.T, obj objects.Object, value interface{}) {
+	switch exp := value.(type) {
+	case int:
+		assertIntegerObject(t, obj, int64(exp))
+	case float64:
+		assertDoubleObject(t, obj, exp)
+	case string:
+		assertStringObject(t, obj, exp)
+	case bool:
+		assertBooleanObject(t, obj, exp)
+	case nil:
+		assertNullObject(t, obj)
+	case errorValue:
+		assertError(t, obj, string(exp))
+	case []interface{}:
+		assertArrayObject(t, obj, exp)
+	default:
+		assert.Fail(t, "unsupported type %T", value)
+	}
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{`map([1, 2, 3], fn(x){ x * 2 })`, []interface{}{2, 4, 6}},
+		{`map([1, 2, 3], fn(x, i){ return x * i; })`, []interface{}{0, 2, 6}},
+		{`map([1, 2], str)`, []interface{}{"1", "2"}},
+		{`map([], fn(x){ x })`, []interface{}{}},
+		{`map([1, 2], fn(x){ x + true })`, errorValue("type mismatch: INTEGER + BOOLEAN")},
+		{`map([1, 2], fn(){ 1 })`, errorValue("number of function parameters mismatch: expected=0, got=1")},
+		{`map(1, str)`, errorValue("`map` expects array as first argument, but got INTEGER")},
+		{`map([1], 1)`, errorValue("`map` expects function as second argument, but got INTEGER")},
+		{`filter(range(10), fn(x){ x > 6 })`, []interface{}{7, 8, 9}},
+		{`reduce([1, 2, 3, 4], fn(acc, x){ acc + x })`, 10},
+		{`reduce([1, 2, 3], fn(acc, x){ acc + str(x) }, "")`, "123"},
+		{`reduce([], fn(acc, x){ acc + x })`, errorValue("`reduce` of empty array with no initial value")},
+		{`let sum = 0; each([1, 2, 3], fn(x){ sum = sum + x }); sum`, 6},
+		{`let s = ""; each({"b": 2, "a": 1}, fn(k, v){ s = s + k + str(v) }); s`, "a1b2"},
+		{`find([1, 5, 10], fn(x){ x > 3 })`, 5},
+		{`find([1, 5, 10], fn(x){ x > 30 })`, nil},
+		{`find([1, 5, 10], fn(x){ if (x > 3) { return true; } false })`, 5},
+		{`any([1, 5, 10], fn(x){ x > 3 })`, true},
+		{`any([], fn(x){ true })`, false},
+		{`all([1, 5, 10], fn(x){ x > 0 })`, true},
+		{`all([1, 5, 10], fn(x){ x > 3 })`, false},
+		{`sort([3, 1.5, 2])[0]`, 1.5},
+		{`sort(["b", "c", "a"])`, []interface{}{"a", "b", "c"}},
+		{`let a = [3, 1, 2]; sort(a); a`, []interface{}{3, 1, 2}},
+		{`sort([3, 1, 2], fn(a, b){ a > b })`, []interface{}{3, 2, 1}},
+		{`sort([3, 1, 2], fn(a, b){ a - b })`, []interface{}{1, 2, 3}},
+		{`sort([1, "a"])`, errorValue("unable to compare STRING and INTEGER")},
+		{`sort([1, 2], fn(a, b){ "x" })`, errorValue("`sort` comparator must return BOOLEAN or INTEGER, but got STRING")},
+		{`sortBy(["ccc", "a", "bb"], len)`, []interface{}{"a", "bb", "ccc"}},
+		{`map(zip([1, 2, 3], ["a", "b"]), fn(t){ str(t[0]) + t[1] })`, []interface{}{"1a", "2b"}},
+		{`let g = groupBy([1, 2, 3, 4, 5], fn(x){ x > 3 }); [len(g[true]), len(g[false])]`, []interface{}{2, 3}},
+		{`unique([1, 2, 1, "a", 2, "a"])`, []interface{}{1, 2, "a"}},
+		{`unique([[1]])`, errorValue("`unique` expects hashable elements, but got ARRAY")},
+		{`flatten([1, [2, [3]], 4])[2]`, []interface{}{3}},
+		{`flatten([1, [2, [3]], 4], 2)`, []interface{}{1, 2, 3, 4}},
+	}
+	for _, test := range tests {
+		obj := testEval(t, test.input)
+		assertValue(t, obj, test.value)
+	}
 }
@@ -0,0 +1,10 @@
+let counter = 0;
+let settings = {"debug": false};
+const version = "1.0";
+let _secret = "hidden";
+
+let next = fn() {
+    counter = counter + 1;
+    return counter;
+};
+let secret = fn() { _secret };
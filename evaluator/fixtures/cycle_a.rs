@@ -0,0 +1,2 @@
+# b "cycle_b.rs";
+let a = 1;
@@ -1,4 +1,4 @@
-# test "fixtures/test.rs";
+# test "test.rs";
 
 let valueOne = 15;
 let func = fn(a, testFunc) {return test.anotherFn(a, valueOne, testFunc);};
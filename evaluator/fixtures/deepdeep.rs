@@ -1,4 +1,4 @@
-# deep "fixtures/deep.rs";
+# deep "deep.rs";
 
 let valueOne = 14;
 let func = fn(testFunc) {return deep.func(valueOne, testFunc);};
@@ -0,0 +1,11 @@
+export # cfg "config.rs";
+export # {next as bump} from "config.rs";
+
+let helper = fn(x) { x * 2 };
+export let double = fn(x) { helper(x) };
+export const limit = 10;
+export let [first, second] = [1, 2];
+
+export struct Pair {
+    a, b;
+}
@@ -0,0 +1 @@
+let answer = 42;
@@ -0,0 +1,2 @@
+# c "../config.rs";
+let next = fn() { c.next() };
@@ -0,0 +1 @@
+export let bye = "bye";
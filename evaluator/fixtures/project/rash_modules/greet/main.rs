@@ -0,0 +1 @@
+export let hello = fn(name) { "hello " + name };
@@ -0,0 +1,2 @@
+export # * from "exports.rs";
+export # {answer} from "lib/answer.rs";
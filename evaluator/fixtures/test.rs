@@ -1,3 +1,3 @@
 let testFn = fn(){return 100;};
 let anotherFn = fn(x, y, func) { return func(x, y) + func(y, x)};
-let const = 129;
\ No newline at end of file
+let constant = 129;
\ No newline at end of file
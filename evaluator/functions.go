@@ -0,0 +1,151 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+)
+
+// evalCall evaluates the call arguments and applies the function to them, optional call of null is null
+func evalCall(function objects.Object, node *ast.CallExpression, environment *objects.Environment) objects.Object {
+	if node.Optional && function == objects.NULL {
+		return objects.NULL
+	}
+	args := evalExpressions(node.Arguments, environment)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	named, err := evalNamedArguments(node.Named, environment)
+	if err != nil {
+		return err
+	}
+	return callFunction(function, args, named)
+}
+
+func evalNamedArguments(arguments []*ast.NamedArgument, environment *objects.Environment) (map[string]objects.Object, objects.Object) {
+	if len(arguments) == 0 {
+		return nil, nil
+	}
+	named := make(map[string]objects.Object, len(arguments))
+	for _, argument := range arguments {
+		value := eval(argument.Value, environment)
+		if isError(value) {
+			return nil, value
+		}
+		named[argument.Name.Value] = value
+	}
+	return named, nil
+}
+
+func applyFunction(function objects.Object, args []objects.Object) objects.Object {
+	return callFunction(function, args, nil)
+}
+
+// callFunction applies the function to positional and named arguments, builtins don't accept named arguments
+func callFunction(function objects.Object, args []objects.Object, named map[string]objects.Object) objects.Object {
+	switch fn := function.(type) {
+	case *objects.Function:
+		if err := checkArity(fn, args, named); err != nil {
+			return err
+		}
+		if fn.Async {
+			return applyAsyncFunction(fn, args, named)
+		}
+		extendedEnv, err := extendFunctionEnvironment(fn, args, named, eval)
+		if err != nil {
+			return err
+		}
+		evaluated := evalBody(fn, extendedEnv, eval)
+		return unwrapReturnValue(evaluated)
+	case *objects.Builtin:
+		if len(named) != 0 {
+			return newError("named arguments are not supported by builtin functions")
+		}
+		return fn.Fn(args...)
+	case *objects.Struct:
+		return newInstance(fn, args, named)
+	default:
+		return newError("not a function: %s", function.Type())
+	}
+}
+
+// checkArity validates the arguments against the parameters before the function is applied.
+// Parameters with default values are optional and the rest parameter takes any number of extra arguments
+func checkArity(fn *objects.Function, args []objects.Object, named map[string]objects.Object) *objects.Error {
+	max := len(fn.Parameters)
+	min := max - len(fn.Defaults)
+	if (len(args) > max && fn.Rest == nil) || (len(args) < min && len(named) == 0) {
+		switch {
+		case fn.Rest != nil:
+			return newError("number of function parameters mismatch: expected>=%d, got=%d", min, len(args))
+		case min != max:
+			return newError("number of function parameters mismatch: expected=%d..%d, got=%d", min, max, len(args))
+		default:
+			return newError("number of function parameters mismatch: expected=%d, got=%d", max, len(args))
+		}
+	}
+
+	for name := range named {
+		index := parameterIndex(fn, name)
+		if index < 0 {
+			return newError("unexpected named argument %s", name)
+		}
+		if index < len(args) {
+			return newError("multiple values for argument %s", name)
+		}
+	}
+	for i := len(args); i < min; i++ {
+		if _, ok := named[fn.Parameters[i].Value]; !ok {
+			return newError("missing argument %s", fn.Parameters[i].Value)
+		}
+	}
+	return nil
+}
+
+// parameterIndex returns the index of the parameter by name, destructured parameters can't be named
+func parameterIndex(fn *objects.Function, name string) int {
+	for i, parameter := range fn.Parameters {
+		if _, ok := fn.Patterns[i]; !ok && parameter.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// extendFunctionEnvironment binds the arguments to the parameters, destructured parameters may return an error.
+// Default values are evaluated by eval in the new environment, so they may refer to the preceding parameters
+func extendFunctionEnvironment(fn *objects.Function, args []objects.Object, named map[string]objects.Object, eval Evaluator) (*objects.Environment, *objects.Error) {
+	newEnv := objects.NewEnclosedEnvironment(fn.Environment)
+
+	for i, parameter := range fn.Parameters {
+		pattern, isPattern := fn.Patterns[i]
+		value, ok := named[parameter.Value]
+		switch {
+		case i < len(args):
+			value = args[i]
+		case ok && !isPattern:
+		default:
+			value = eval(fn.Defaults[i], newEnv)
+			if err, ok := value.(*objects.Error); ok {
+				return nil, err
+			}
+		}
+
+		if isPattern {
+			if err := destructure(pattern, value, newEnv.Set); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		newEnv.Set(parameter.Value, value)
+	}
+
+	if fn.Rest != nil {
+		rest := &objects.Array{Elements: []objects.Object{}}
+		if len(args) > len(fn.Parameters) {
+			rest.Push(args[len(fn.Parameters):]...)
+		}
+		newEnv.Set(fn.Rest.Value, rest)
+	}
+
+	return newEnv, nil
+}
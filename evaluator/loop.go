@@ -0,0 +1,359 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// task is a unit of work scheduled on the event loop. Returned error is treated as unhandled.
+type task func() objects.Object
+
+type timer struct {
+	id       int64
+	at       time.Time
+	interval time.Duration
+	repeat   bool
+	fn       task
+}
+
+// eventLoop owns all asynchronous work of the interpreter: timers, plugin callbacks and async function bodies.
+// The work is always evaluated by the goroutine which holds the interpreter lock,
+// so scheduled callbacks never run concurrently with each other or with the main script.
+// Spawned tasks run on their own goroutines and hold the interpreter lock as well,
+// the lock is given away only when a task is blocked on a channel, a task or a promise.
+type eventLoop struct {
+	interp sync.Mutex // interpreter lock, held while rash code is evaluated
+
+	mu     sync.Mutex
+	tasks  []task
+	timers map[int64]*timer
+	lastID int64
+	refs   int           // number of callbacks held by plugins and running tasks, they keep the loop alive
+	signal chan struct{} // closed and replaced each time a new work is scheduled
+	err    *objects.Error
+}
+
+var loop = newEventLoop()
+
+func newEventLoop() *eventLoop {
+	return &eventLoop{
+		timers: map[int64]*timer{},
+		signal: make(chan struct{}),
+	}
+}
+
+// Execute evaluates the node holding the interpreter lock,
+// it must be used when the event loop is served in background (see ServeEventLoop)
+func Execute(node ast.Node, environment *objects.Environment) objects.Object {
+	loop.interp.Lock()
+	defer loop.interp.Unlock()
+	return eval(node, environment)
+}
+
+// Eval evaluates the node holding the interpreter lock the same way as Execute.
+// The code which is already evaluated by the interpreter, like modules and plugin callbacks, uses Evaluate
+func Eval(node ast.Node, environment *objects.Environment) objects.Object {
+	return Execute(node, environment)
+}
+
+// RunEventLoop evaluates scheduled tasks and timers until the loop drains.
+// The first error returned by a scheduled callback stops the loop.
+func RunEventLoop() *objects.Error {
+	for {
+		loop.interp.Lock()
+		loop.runOnce(true)
+		err := loop.takeError()
+		loop.interp.Unlock()
+		if err != nil {
+			return err
+		}
+		if !loop.wait() {
+			return nil
+		}
+	}
+}
+
+// ServeEventLoop evaluates scheduled work in background, errors of scheduled callbacks are passed to the handler
+func ServeEventLoop(handler func(*objects.Error)) {
+	go func() {
+		for {
+			loop.interp.Lock()
+			loop.runOnce(true)
+			err := loop.takeError()
+			loop.interp.Unlock()
+			if err != nil && handler != nil {
+				handler(err)
+			}
+			loop.waitWork()
+		}
+	}()
+}
+
+func (l *eventLoop) post(t task) {
+	l.mu.Lock()
+	l.tasks = append(l.tasks, t)
+	l.notify()
+	l.mu.Unlock()
+}
+
+func (l *eventLoop) setTimer(delay time.Duration, repeat bool, fn task) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastID++
+	l.timers[l.lastID] = &timer{
+		id:       l.lastID,
+		at:       time.Now().Add(delay),
+		interval: delay,
+		repeat:   repeat,
+		fn:       fn,
+	}
+	l.notify()
+	return l.lastID
+}
+
+func (l *eventLoop) clearTimer(id int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.timers[id]
+	delete(l.timers, id)
+	return ok
+}
+
+func (l *eventLoop) ref() {
+	l.mu.Lock()
+	l.refs++
+	l.mu.Unlock()
+}
+
+func (l *eventLoop) unref() {
+	l.mu.Lock()
+	l.refs--
+	l.notify()
+	l.mu.Unlock()
+}
+
+// notify wakes up all waiters, must be called under l.mu
+func (l *eventLoop) notify() {
+	close(l.signal)
+	l.signal = make(chan struct{})
+}
+
+// runOnce evaluates all queued tasks and, if requested, all due timers.
+// Returns true if anything was evaluated.
+func (l *eventLoop) runOnce(withTimers bool) bool {
+	l.mu.Lock()
+	tasks := l.tasks
+	l.tasks = nil
+	var due []*timer
+	if withTimers {
+		now := time.Now()
+		for _, t := range l.timers {
+			if !t.at.After(now) {
+				due = append(due, t)
+			}
+		}
+		sort.Slice(due, func(i, j int) bool {
+			if due[i].at.Equal(due[j].at) {
+				return due[i].id < due[j].id
+			}
+			return due[i].at.Before(due[j].at)
+		})
+		for _, t := range due {
+			if t.repeat {
+				t.at = now.Add(t.interval)
+			} else {
+				delete(l.timers, t.id)
+			}
+		}
+	}
+	l.mu.Unlock()
+
+	for _, t := range tasks {
+		l.handle(t())
+	}
+	for _, t := range due {
+		if t.repeat && !l.hasTimer(t.id) {
+			continue // cleared by a previous callback
+		}
+		l.handle(t.fn())
+	}
+	return len(tasks) != 0 || len(due) != 0
+}
+
+func (l *eventLoop) hasTimer(id int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.timers[id]
+	return ok
+}
+
+func (l *eventLoop) handle(result objects.Object) {
+	err, ok := result.(*objects.Error)
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	if l.err == nil {
+		l.err = err
+	}
+	l.mu.Unlock()
+}
+
+func (l *eventLoop) takeError() *objects.Error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.err
+	l.err = nil
+	return err
+}
+
+// wait blocks until there is a work to evaluate. Returns false if the loop is drained
+func (l *eventLoop) wait() bool {
+	l.mu.Lock()
+	if len(l.tasks) != 0 {
+		l.mu.Unlock()
+		return true
+	}
+	if len(l.timers) == 0 && l.refs == 0 {
+		l.mu.Unlock()
+		return false
+	}
+	l.mu.Unlock()
+	l.waitWork()
+	return true
+}
+
+// waitWork blocks until a new work is scheduled or the nearest timer is due
+func (l *eventLoop) waitWork() {
+	l.mu.Lock()
+	if len(l.tasks) != 0 {
+		l.mu.Unlock()
+		return
+	}
+	signal := l.signal
+	var next <-chan time.Time
+	if nearest, ok := l.nearestTimer(); ok {
+		t := time.NewTimer(time.Until(nearest))
+		defer t.Stop()
+		next = t.C
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-signal:
+	case <-next:
+	}
+}
+
+// nearestTimer must be called under l.mu
+func (l *eventLoop) nearestTimer() (time.Time, bool) {
+	var nearest time.Time
+	found := false
+	for _, t := range l.timers {
+		if !found || t.at.Before(nearest) {
+			nearest = t.at
+			found = true
+		}
+	}
+	return nearest, found
+}
+
+// await blocks the current task until one of the cases is ready (see reflect.Select) and returns the chosen case.
+// Meanwhile the current goroutine evaluates scheduled work of the event loop and
+// gives the interpreter lock to other tasks when there is nothing to evaluate.
+// If nonBlocking is set and no case is ready, -1 is returned immediately.
+// The current goroutine must hold the interpreter lock.
+func (l *eventLoop) await(cases []reflect.SelectCase, nonBlocking bool) (int, reflect.Value, bool, *objects.Error) {
+	n := len(cases)
+	for {
+		chosen, value, ok, err := trySelect(append(cases[:n:n], reflect.SelectCase{Dir: reflect.SelectDefault}))
+		if err != nil || chosen < n {
+			return chosen, value, ok, err
+		}
+		if nonBlocking {
+			return -1, reflect.Value{}, false, nil
+		}
+		if l.runOnce(true) {
+			continue
+		}
+
+		l.mu.Lock()
+		if len(l.tasks) != 0 {
+			l.mu.Unlock()
+			continue
+		}
+		if len(l.timers) == 0 && l.refs == 0 {
+			l.mu.Unlock()
+			return 0, reflect.Value{}, false, newError("deadlock: all tasks are asleep")
+		}
+		waitCases := append(cases[:n:n], reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(l.signal)})
+		var next *time.Timer
+		if nearest, ok := l.nearestTimer(); ok {
+			next = time.NewTimer(time.Until(nearest))
+			waitCases = append(waitCases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(next.C)})
+		}
+		l.mu.Unlock()
+
+		l.interp.Unlock()
+		chosen, value, ok, err = trySelect(waitCases)
+		l.interp.Lock()
+		if next != nil {
+			next.Stop()
+		}
+		if err != nil || chosen < n {
+			return chosen, value, ok, err
+		}
+	}
+}
+
+// trySelect is reflect.Select which returns an error instead of panic on send to a closed channel
+func trySelect(cases []reflect.SelectCase) (chosen int, value reflect.Value, ok bool, err *objects.Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newError("send on closed channel")
+		}
+	}()
+	chosen, value, ok = reflect.Select(cases)
+	return
+}
+
+// block calls the function in a separate goroutine and meanwhile evaluates queued tasks,
+// so plugins are able to call rash callbacks synchronously without locking the interpreter.
+// The interpreter lock is given to other tasks while the function is waited for,
+// so plugin calls of different tasks run concurrently.
+// The current goroutine must hold the interpreter lock.
+func (l *eventLoop) block(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	for {
+		l.runOnce(false)
+		l.mu.Lock()
+		signal := l.signal
+		pending := len(l.tasks) != 0
+		l.mu.Unlock()
+		if pending {
+			continue
+		}
+
+		finished := false
+		l.interp.Unlock()
+		select {
+		case <-done:
+			finished = true
+		case <-signal:
+		}
+		l.interp.Lock()
+		if finished {
+			l.runOnce(false)
+			return
+		}
+	}
+}
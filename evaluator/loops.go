@@ -0,0 +1,181 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/extensions"
+	"github.com/YReshetko/rash-lang/objects"
+)
+
+// isInterruption checks if the result stops execution of statements: errors, returns, break and continue
+func isInterruption(result objects.Object) bool {
+	switch result.Type() {
+	case objects.RETURN_VALUE_OBJ, objects.ERROR_OBJ, objects.BREAK_OBJ, objects.CONTINUE_OBJ:
+		return true
+	default:
+		return false
+	}
+}
+
+// loopControl handles the result of a loop iteration and returns the value of loop and whether the loop has to stop.
+// Errors, returns and signals of outer loops stop the loop and are passed to the caller,
+// break stops the loop with the value of previous iteration and continue keeps it
+func loopControl(result, previous objects.Object, label *ast.Identifier) (objects.Object, bool) {
+	switch signal := result.(type) {
+	case *objects.Error, *objects.ReturnValue:
+		return signal, true
+	case *objects.Break:
+		if signal.Label != "" && signal.Label != labelName(label) {
+			return signal, true
+		}
+		return previous, true
+	case *objects.Continue:
+		if signal.Label != "" && signal.Label != labelName(label) {
+			return signal, true
+		}
+		return previous, false
+	default:
+		return result, false
+	}
+}
+
+func labelName(label *ast.Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Value
+}
+
+func newRange(start, end objects.Object) objects.Object {
+	s, ok := start.(*objects.Integer)
+	if !ok {
+		return newError("range expects integer bounds, but got %s", start.Type())
+	}
+	e, ok := end.(*objects.Integer)
+	if !ok {
+		return newError("range expects integer bounds, but got %s", end.Type())
+	}
+	return &objects.Range{Start: s.Value, End: e.Value}
+}
+
+// evalForInExpression binds the loop variables in a new environment on each iteration,
+// so closures created in the body keep the values of their iteration
+func evalForInExpression(node *ast.ForInExpression, environment *objects.Environment) objects.Object {
+	iterable := eval(node.Iterable, environment)
+	if isError(iterable) {
+		return iterable
+	}
+	next, err := iterate(iterable)
+	if err != nil {
+		return err
+	}
+
+	var value objects.Object = objects.NULL
+	for {
+		key, element, ok := next()
+		if !ok {
+			return value
+		}
+		if isError(element) {
+			return element
+		}
+
+		iterationEnv := objects.NewEnclosedEnvironment(environment)
+		switch {
+		case node.Key != nil:
+			iterationEnv.Set(node.Key.Value, key)
+			iterationEnv.Set(node.Value.Value, element)
+		case iterable.Type() == objects.HASH_OBJ:
+			iterationEnv.Set(node.Value.Value, key)
+		default:
+			iterationEnv.Set(node.Value.Value, element)
+		}
+
+		var stop bool
+		value, stop = loopControl(eval(node.Body, iterationEnv), value, node.Label)
+		if stop {
+			return value
+		}
+	}
+}
+
+// iterator returns the key and the value of the next element, or false if there are no more elements.
+// Keys are indexes for all iterables except hashes
+type iterator func() (key objects.Object, value objects.Object, ok bool)
+
+// iterate creates an iterator over elements of the collection, arrays and hashes are iterated over
+// the elements they have when the loop starts
+func iterate(iterable objects.Object) (iterator, *objects.Error) {
+	index := int64(-1)
+	nextIndex := func() objects.Object {
+		index++
+		return &objects.Integer{Value: index}
+	}
+
+	switch value := iterable.(type) {
+	case *objects.Array:
+		elements := value.Elements
+		return func() (objects.Object, objects.Object, bool) {
+			if index+1 >= int64(len(elements)) {
+				return nil, nil, false
+			}
+			key := nextIndex()
+			return key, elements[index], true
+		}, nil
+	case *objects.Hash:
+		pairs := value.SortedPairs()
+		return func() (objects.Object, objects.Object, bool) {
+			if index+1 >= int64(len(pairs)) {
+				return nil, nil, false
+			}
+			nextIndex()
+			return pairs[index].Key, pairs[index].Value, true
+		}, nil
+	case *objects.String:
+		chars := []rune(value.Value)
+		return func() (objects.Object, objects.Object, bool) {
+			if index+1 >= int64(len(chars)) {
+				return nil, nil, false
+			}
+			key := nextIndex()
+			return key, &objects.String{Value: string(chars[index])}, true
+		}, nil
+	case *objects.Range:
+		return func() (objects.Object, objects.Object, bool) {
+			if index+1 >= value.Len() {
+				return nil, nil, false
+			}
+			key := nextIndex()
+			return key, &objects.Integer{Value: value.Start + index}, true
+		}, nil
+	case *objects.Iterator:
+		return func() (objects.Object, objects.Object, bool) {
+			element, ok := value.Next()
+			if !ok {
+				return nil, nil, false
+			}
+			return nextIndex(), element, true
+		}, nil
+	default:
+		return nil, newError("unable to iterate over %s", iterable.Type())
+	}
+}
+
+// nativeIterator wraps the iterator returned by a plugin, the interpreter is given to other tasks
+// while the plugin waits for the next value (see eventLoop.block)
+func nativeIterator(it extensions.Iterator) *objects.Iterator {
+	return &objects.Iterator{Next: func() (objects.Object, bool) {
+		var value interface{}
+		var ok bool
+		var err error
+		loop.block(func() {
+			value, ok, err = it.Next()
+		})
+		if err != nil {
+			return newError("iterator err: %v", err), true
+		}
+		if !ok {
+			return nil, false
+		}
+		return retVal(value), true
+	}}
+}
@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+)
+
+// evalMatchExpression evaluates the body of the first arm which pattern matches the value and guard is truthy,
+// the bindings of pattern are visible in the guard and the body only
+func evalMatchExpression(node *ast.MatchExpression, environment *objects.Environment) objects.Object {
+	value := eval(node.Value, environment)
+	if isError(value) {
+		return value
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := objects.NewEnclosedEnvironment(environment)
+		matched, err := matchPattern(arm.Pattern, value, armEnv)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+		if arm.Guard != nil {
+			guard := eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return eval(arm.Body, armEnv)
+	}
+	return newError("non-exhaustive match: no pattern matches %s", value.Inspect())
+}
+
+// matchPattern checks the value against the pattern and binds the names of pattern in the environment.
+// Literals match the values of the same type, `_` matches everything without binding
+func matchPattern(pattern ast.Expression, value objects.Object, environment *objects.Environment) (bool, *objects.Error) {
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		if p.Value != "_" {
+			environment.Set(p.Value, value)
+		}
+		return true, nil
+	case *ast.AlternativePattern:
+		for _, alternative := range p.Patterns {
+			matched, err := matchPattern(alternative, value, environment)
+			if matched || err != nil {
+				return matched, err
+			}
+		}
+		return false, nil
+	case *ast.ArrayPattern:
+		return matchArray(p, value, environment)
+	case *ast.HashPattern:
+		return matchHash(p, value, environment)
+	default:
+		literal := eval(pattern, environment)
+		if err, ok := literal.(*objects.Error); ok {
+			return false, err
+		}
+		return equalLiterals(literal, value), nil
+	}
+}
+
+func matchArray(pattern *ast.ArrayPattern, value objects.Object, environment *objects.Environment) (bool, *objects.Error) {
+	arr, ok := value.(*objects.Array)
+	if !ok {
+		return false, nil
+	}
+	expected := len(pattern.Elements)
+	if len(arr.Elements) < expected || (pattern.Rest == nil && len(arr.Elements) != expected) {
+		return false, nil
+	}
+
+	for i, element := range pattern.Elements {
+		matched, err := matchPattern(element, arr.Elements[i], environment)
+		if !matched || err != nil {
+			return matched, err
+		}
+	}
+	if pattern.Rest != nil {
+		environment.Set(pattern.Rest.Value, arr.Slice(expected, len(arr.Elements)))
+	}
+	return true, nil
+}
+
+func matchHash(pattern *ast.HashPattern, value objects.Object, environment *objects.Environment) (bool, *objects.Error) {
+	get, members, ok := hashMembers(value)
+	if !ok {
+		return false, nil
+	}
+
+	for i, key := range pattern.Keys {
+		member, ok := get(key.Value)
+		if !ok {
+			return false, nil
+		}
+		matched, err := matchPattern(pattern.Targets[i], member, environment)
+		if !matched || err != nil {
+			return matched, err
+		}
+	}
+	if pattern.Rest != nil {
+		environment.Set(pattern.Rest.Value, restMembers(members(), pattern.Keys))
+	}
+	return true, nil
+}
+
+func equalLiterals(literal, value objects.Object) bool {
+	if literal.Type() != value.Type() {
+		return false
+	}
+	if literal == objects.NULL {
+		return true
+	}
+	left, ok := literal.(objects.Hashable)
+	if !ok {
+		return false
+	}
+	return left.HashKey() == value.(objects.Hashable).HashKey()
+}
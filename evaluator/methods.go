@@ -0,0 +1,79 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+)
+
+// method is a function applied to the receiver, `value.name(args)` calls method(value, args...)
+type method func(receiver objects.Object, args ...objects.Object) objects.Object
+
+// methods is the method table per object type
+var methods = map[objects.ObjectType]map[string]method{}
+
+// Methods are registered on init and delegate to builtins which take the receiver as the first argument
+func init() {
+	registerMethods(objects.STRING_OBJ, "len", "type", "str", "int", "float", "bool", "slice", "concat",
+		"split", "replace", "trim", "trimLeft", "trimRight", "trimPrefix", "trimSuffix", "upper", "lower",
+		"contains", "startsWith", "endsWith", "indexOf", "repeat", "chars", "format")
+	registerMethods(objects.ARRAY_OBJ, "len", "type", "str", "bool", "freeze", "frozen", "push", "pop", "slice", "concat", "join",
+		"map", "filter", "reduce", "each", "find", "any", "all", "sort", "sortBy", "zip", "groupBy", "unique", "flatten")
+	registerMethods(objects.HASH_OBJ, "len", "type", "str", "bool", "freeze", "frozen", "keys", "values", "has", "delete", "each")
+	registerMethods(objects.INTEGER_OBJ, "type", "str", "int", "float", "bool")
+	registerMethods(objects.DOUBLE_OBJ, "type", "str", "int", "float", "bool")
+	registerMethods(objects.BOOLEAN_OBJ, "type", "str", "int", "bool")
+	registerMethods(objects.CHANNEL_OBJ, "type", "send", "recv", "close")
+	registerMethods(objects.TASK_OBJ, "type", "join")
+	registerMethods(objects.INSTANCE_OBJ, "type", "str")
+	registerMethods(objects.RANGE_OBJ, "len", "type", "str")
+}
+
+func registerMethods(objectType objects.ObjectType, names ...string) {
+	if methods[objectType] == nil {
+		methods[objectType] = map[string]method{}
+	}
+	for _, name := range names {
+		methods[objectType][name] = builtinMethod(name)
+	}
+}
+
+// builtinMethod looks up the builtin on call, so methods don't depend on the order of builtins registration
+func builtinMethod(name string) method {
+	return func(receiver objects.Object, args ...objects.Object) objects.Object {
+		return builtins[name].Fn(append([]objects.Object{receiver}, args...)...)
+	}
+}
+
+// evalMember resolves `left.name`, hash keys take precedence over the methods of hash.
+// Missing key of hash is null, or an error in strict mode
+func evalMember(left objects.Object, name *ast.Identifier, strict bool) objects.Object {
+	switch value := left.(type) {
+	case *objects.ExternalEnvironment:
+		return moduleMember(value, name)
+	case *objects.Hash:
+		if member, ok := value.Get(&objects.String{Value: name.Value}); ok {
+			return member
+		}
+	case *objects.Instance:
+		if member, ok := instanceMember(value, name.Value); ok {
+			return member
+		}
+	}
+
+	fn, ok := methods[left.Type()][name.Value]
+	if !ok {
+		if left.Type() == objects.HASH_OBJ {
+			if strict {
+				return newError("key %s not found in HASH", name.Value)
+			}
+			return objects.NULL
+		}
+		if instance, ok := left.(*objects.Instance); ok {
+			return newError("undefined member %s of %s", name.Value, instance.Struct.Name)
+		}
+		return newError("undefined method %s for %s", name.Value, left.Type())
+	}
+	return &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		return fn(left, args...)
+	}}
+}
@@ -0,0 +1,103 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+)
+
+// A module exposes its exported top-level bindings, or all of them except the names starting with underscore
+// if there are no `export` markers in the module.
+// Exposed bindings can be reassigned by importing scripts unless they are declared with `const`
+
+// moduleMember resolves `alias.name` in the environment of included script
+func moduleMember(module *objects.ExternalEnvironment, name *ast.Identifier) objects.Object {
+	if !module.Environment.IsPublic(name.Value) {
+		return newError("%s is private to the module", name.Value)
+	}
+	return evalIdentifier(name, module.Environment)
+}
+
+// evalAssignModuleMember assigns `alias.name = value`, only existing bindings of the module can be reassigned
+func evalAssignModuleMember(module *objects.ExternalEnvironment, name *ast.Identifier, value objects.Object) objects.Object {
+	switch {
+	case !module.Environment.IsPublic(name.Value):
+		return newError("%s is private to the module", name.Value)
+	case !module.Environment.Has(name.Value):
+		return newError("undefined name %s in the module", name.Value)
+	case module.Environment.IsConst(name.Value):
+		return newError("cannot assign to constant %s", name.Value)
+	}
+	module.Environment.Set(name.Value, value)
+	return value
+}
+
+// evalImportNames binds the names of selective import `# {a, b as c} from "file.rs"` in the including script
+func evalImportNames(module *objects.ExternalEnvironment, names []*ast.ImportName, environment *objects.Environment) objects.Object {
+	for _, name := range names {
+		_, isModule := module.Environment.GetExternalEnvironment(name.Name.Value)
+		switch {
+		case !module.Environment.Has(name.Name.Value) && !isModule:
+			return newError("undefined name %s in the module", name.Name.Value)
+		case !module.Environment.IsPublic(name.Name.Value):
+			return newError("%s is private to the module", name.Name.Value)
+		}
+		bindImport(name.Bound().Value, module, name.Name, environment)
+	}
+	return module
+}
+
+// evalImportAll binds all public names of module `# * from "file.rs"` in the including script
+func evalImportAll(module *objects.ExternalEnvironment, environment *objects.Environment) objects.Object {
+	for _, name := range module.Environment.PublicNames() {
+		bindImport(name, module, &ast.Identifier{Value: name}, environment)
+	}
+	return module
+}
+
+// bindImport binds the member of module by the name, the imported module is bound as alias,
+// so the names of module are accessed by the name. The constants of module stay constants in the including script
+func bindImport(name string, module *objects.ExternalEnvironment, member *ast.Identifier, environment *objects.Environment) {
+	value := moduleMember(module, member)
+	if imported, ok := value.(*objects.ExternalEnvironment); ok {
+		environment.AddExternalEnvironment(name, imported.Environment)
+		return
+	}
+	if module.Environment.IsConst(member.Value) {
+		environment.SetConst(name, value)
+		return
+	}
+	environment.Set(name, value)
+}
+
+// evalExportStatement evaluates the declaration and marks the declared names as public names of module
+func evalExportStatement(node *ast.ExportStatement, environment *objects.Environment) objects.Object {
+	result := eval(node.Statement, environment)
+	if isError(result) {
+		return result
+	}
+
+	switch statement := node.Statement.(type) {
+	case *ast.LetStatement:
+		if statement.Pattern != nil {
+			for _, name := range ast.BoundNames(statement.Pattern) {
+				environment.Export(name)
+			}
+		} else {
+			environment.Export(statement.Name.Value)
+		}
+	case *ast.StructStatement:
+		environment.Export(statement.Name.Value)
+	case *ast.DeclarationStatement:
+		include := statement.Declaration.(*ast.IncludeDeclaration)
+		if !include.Wildcard {
+			for _, name := range include.Bound() {
+				environment.Export(name)
+			}
+			break
+		}
+		for _, name := range result.(*objects.ExternalEnvironment).Environment.PublicNames() {
+			environment.Export(name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,395 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/YReshetko/rash-lang/objects"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Output is used by `print` and `println` builtins
+var Output io.Writer = os.Stdout
+
+// Core builtins are registered on init to keep them apart from the plugin builtins `eval` and `call`
+func init() {
+	builtins["len"] = &objects.Builtin{Fn: builtinLen}
+	builtins["type"] = &objects.Builtin{Fn: builtinType}
+	builtins["str"] = &objects.Builtin{Fn: builtinStr}
+	builtins["int"] = &objects.Builtin{Fn: builtinInt}
+	builtins["float"] = &objects.Builtin{Fn: builtinFloat}
+	builtins["bool"] = &objects.Builtin{Fn: builtinBool}
+	builtins["keys"] = &objects.Builtin{Fn: builtinKeys}
+	builtins["values"] = &objects.Builtin{Fn: builtinValues}
+	builtins["has"] = &objects.Builtin{Fn: builtinHas}
+	builtins["delete"] = &objects.Builtin{Fn: builtinDelete}
+	builtins["push"] = &objects.Builtin{Fn: builtinPush}
+	builtins["pop"] = &objects.Builtin{Fn: builtinPop}
+	builtins["slice"] = &objects.Builtin{Fn: builtinSlice}
+	builtins["concat"] = &objects.Builtin{Fn: builtinConcat}
+	builtins["range"] = &objects.Builtin{Fn: builtinRange}
+	builtins["print"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		return builtinPrint("", args)
+	}}
+	builtins["println"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		return builtinPrint("\n", args)
+	}}
+	builtins["assert"] = &objects.Builtin{Fn: builtinAssert}
+	builtins["freeze"] = &objects.Builtin{Fn: builtinFreeze}
+	builtins["frozen"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		if err := expectArgs("frozen", args, 1); err != nil {
+			return err
+		}
+		return nativeBoolean(objects.IsFrozen(args[0]))
+	}}
+}
+
+func builtinLen(args ...objects.Object) objects.Object {
+	if err := expectArgs("len", args, 1); err != nil {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *objects.String:
+		return &objects.Integer{Value: int64(arg.Len())}
+	case *objects.Array:
+		return &objects.Integer{Value: int64(len(arg.Elements))}
+	case *objects.Hash:
+		return &objects.Integer{Value: int64(len(arg.Pairs))}
+	case *objects.Range:
+		return &objects.Integer{Value: arg.Len()}
+	default:
+		return newError("`len` expects string, array, hash or range, but got %s", args[0].Type())
+	}
+}
+
+func builtinType(args ...objects.Object) objects.Object {
+	if err := expectArgs("type", args, 1); err != nil {
+		return err
+	}
+	if instance, ok := args[0].(*objects.Instance); ok {
+		return &objects.String{Value: instance.Struct.Name}
+	}
+	return &objects.String{Value: string(args[0].Type())}
+}
+
+func builtinStr(args ...objects.Object) objects.Object {
+	if err := expectArgs("str", args, 1); err != nil {
+		return err
+	}
+	if s, ok := args[0].(*objects.String); ok {
+		return s
+	}
+	return &objects.String{Value: args[0].Inspect()}
+}
+
+func builtinInt(args ...objects.Object) objects.Object {
+	if err := expectArgs("int", args, 1); err != nil {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *objects.Integer:
+		return arg
+	case *objects.Double:
+		return &objects.Integer{Value: int64(arg.Value)}
+	case *objects.Boolean:
+		if arg.Value {
+			return &objects.Integer{Value: 1}
+		}
+		return &objects.Integer{Value: 0}
+	case *objects.String:
+		value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 10, 64)
+		if err != nil {
+			return newError("`int` unable to convert %q to integer", arg.Value)
+		}
+		return &objects.Integer{Value: value}
+	default:
+		return newError("`int` expects number, boolean or string, but got %s", args[0].Type())
+	}
+}
+
+func builtinFloat(args ...objects.Object) objects.Object {
+	if err := expectArgs("float", args, 1); err != nil {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *objects.Integer:
+		return &objects.Double{Value: float64(arg.Value)}
+	case *objects.Double:
+		return arg
+	case *objects.String:
+		value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+		if err != nil {
+			return newError("`float` unable to convert %q to double", arg.Value)
+		}
+		return &objects.Double{Value: value}
+	default:
+		return newError("`float` expects number or string, but got %s", args[0].Type())
+	}
+}
+
+func builtinBool(args ...objects.Object) objects.Object {
+	if err := expectArgs("bool", args, 1); err != nil {
+		return err
+	}
+	return nativeBoolean(isTruthy(args[0]))
+}
+
+func builtinKeys(args ...objects.Object) objects.Object {
+	if err := expectArgs("keys", args, 1); err != nil {
+		return err
+	}
+	hash, ok := args[0].(*objects.Hash)
+	if !ok {
+		return newError("`keys` expects hash, but got %s", args[0].Type())
+	}
+	return &objects.Array{Elements: hash.Keys()}
+}
+
+func builtinValues(args ...objects.Object) objects.Object {
+	if err := expectArgs("values", args, 1); err != nil {
+		return err
+	}
+	hash, ok := args[0].(*objects.Hash)
+	if !ok {
+		return newError("`values` expects hash, but got %s", args[0].Type())
+	}
+	return &objects.Array{Elements: hash.Values()}
+}
+
+func builtinHas(args ...objects.Object) objects.Object {
+	if err := expectArgs("has", args, 2); err != nil {
+		return err
+	}
+	hash, key, err := hashAndKey("has", args)
+	if err != nil {
+		return err
+	}
+	_, ok := hash.Get(key)
+	return nativeBoolean(ok)
+}
+
+func builtinDelete(args ...objects.Object) objects.Object {
+	if err := expectArgs("delete", args, 2); err != nil {
+		return err
+	}
+	hash, key, err := hashAndKey("delete", args)
+	if err != nil {
+		return err
+	}
+	if hash.Frozen {
+		return frozenError(hash)
+	}
+	value, ok := hash.Delete(key)
+	if !ok {
+		return objects.NULL
+	}
+	return value
+}
+
+func builtinPush(args ...objects.Object) objects.Object {
+	if len(args) < 2 {
+		return newError("wrong number of arguments to `push`; got=%d, expected>=%d", len(args), 2)
+	}
+	arr, ok := args[0].(*objects.Array)
+	if !ok {
+		return newError("`push` expects array as first argument, but got %s", args[0].Type())
+	}
+	if arr.Frozen {
+		return frozenError(arr)
+	}
+	arr.Push(args[1:]...)
+	return arr
+}
+
+func builtinPop(args ...objects.Object) objects.Object {
+	if err := expectArgs("pop", args, 1); err != nil {
+		return err
+	}
+	arr, ok := args[0].(*objects.Array)
+	if !ok {
+		return newError("`pop` expects array, but got %s", args[0].Type())
+	}
+	if arr.Frozen {
+		return frozenError(arr)
+	}
+	return arr.Pop()
+}
+
+// builtinSlice returns a part of array or string in range [start, end), negative bounds are counted from the end
+func builtinSlice(args ...objects.Object) objects.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments to `slice`; got=%d, expected=%d or %d", len(args), 2, 3)
+	}
+	var end objects.Object
+	if len(args) == 3 {
+		end = args[2]
+	}
+	return sliceObject(args[0], args[1], end)
+}
+
+// sliceObject slices array or string by characters, nil bound means the beginning or the end accordingly
+func sliceObject(obj, startObj, endObj objects.Object) objects.Object {
+	var length int
+	switch arg := obj.(type) {
+	case *objects.Array:
+		length = len(arg.Elements)
+	case *objects.String:
+		length = arg.Len()
+	default:
+		return newError("slice operator not supported for: %s", obj.Type())
+	}
+
+	bounds := []int{0, length}
+	for i, arg := range []objects.Object{startObj, endObj} {
+		if arg == nil {
+			continue
+		}
+		bound, ok := arg.(*objects.Integer)
+		if !ok {
+			return newError("slice expects integer bounds, but got %s", arg.Type())
+		}
+		bounds[i] = normalizeBound(bound.Value, length)
+	}
+	start, end := bounds[0], bounds[1]
+	if end < start {
+		end = start
+	}
+
+	if arr, ok := obj.(*objects.Array); ok {
+		return arr.Slice(start, end)
+	}
+	runes := []rune(obj.(*objects.String).Value)
+	return &objects.String{Value: string(runes[start:end])}
+}
+
+// normalizeBound converts negative index to the index from the end and clamps it to [0, length]
+func normalizeBound(bound int64, length int) int {
+	if bound < 0 {
+		bound += int64(length)
+	}
+	if bound < 0 {
+		return 0
+	}
+	if bound > int64(length) {
+		return length
+	}
+	return int(bound)
+}
+
+func builtinConcat(args ...objects.Object) objects.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments to `concat`; got=%d, expected>=%d", len(args), 1)
+	}
+	switch args[0].(type) {
+	case *objects.Array:
+		result := &objects.Array{Elements: []objects.Object{}}
+		for _, arg := range args {
+			arr, ok := arg.(*objects.Array)
+			if !ok {
+				return newError("`concat` expects all arguments to be ARRAY, but got %s", arg.Type())
+			}
+			result.Push(arr.Elements...)
+		}
+		return result
+	case *objects.String:
+		out := strings.Builder{}
+		for _, arg := range args {
+			s, ok := arg.(*objects.String)
+			if !ok {
+				return newError("`concat` expects all arguments to be STRING, but got %s", arg.Type())
+			}
+			out.WriteString(s.Value)
+		}
+		return &objects.String{Value: out.String()}
+	default:
+		return newError("`concat` expects arrays or strings, but got %s", args[0].Type())
+	}
+}
+
+// builtinRange supports range(end), range(start, end) and range(start, end, step), the end is exclusive
+func builtinRange(args ...objects.Object) objects.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newError("wrong number of arguments to `range`; got=%d, expected from %d to %d", len(args), 1, 3)
+	}
+	values := make([]int64, len(args))
+	for i, arg := range args {
+		v, ok := arg.(*objects.Integer)
+		if !ok {
+			return newError("`range` expects integer arguments, but got %s", arg.Type())
+		}
+		values[i] = v.Value
+	}
+
+	start, end, step := int64(0), values[0], int64(1)
+	if len(values) > 1 {
+		start, end = values[0], values[1]
+	}
+	if len(values) > 2 {
+		step = values[2]
+	}
+	if step == 0 {
+		return newError("`range` step must not be zero")
+	}
+
+	result := &objects.Array{Elements: []objects.Object{}}
+	for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+		result.Push(&objects.Integer{Value: i})
+	}
+	return result
+}
+
+func builtinPrint(end string, args []objects.Object) objects.Object {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = arg.Inspect()
+	}
+	if _, err := fmt.Fprint(Output, strings.Join(values, " ")+end); err != nil {
+		return newError("unable to print: %v", err)
+	}
+	return objects.NULL
+}
+
+func builtinAssert(args ...objects.Object) objects.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments to `assert`; got=%d, expected=%d or %d", len(args), 1, 2)
+	}
+	if isTruthy(args[0]) {
+		return objects.NULL
+	}
+	if len(args) == 2 {
+		return newError("assertion failed: %s", args[1].Inspect())
+	}
+	return newError("assertion failed")
+}
+
+func expectArgs(name string, args []objects.Object, expected int) *objects.Error {
+	if len(args) != expected {
+		return newError("wrong number of arguments to `%s`; got=%d, expected=%d", name, len(args), expected)
+	}
+	return nil
+}
+
+// builtinFreeze makes the array or hash deep-immutable in place and returns it, other values are returned as is
+func builtinFreeze(args ...objects.Object) objects.Object {
+	if err := expectArgs("freeze", args, 1); err != nil {
+		return err
+	}
+	return objects.Freeze(args[0])
+}
+
+func frozenError(obj objects.Object) *objects.Error {
+	return newError("cannot modify frozen %s", obj.Type())
+}
+
+func hashAndKey(name string, args []objects.Object) (*objects.Hash, objects.Hashable, *objects.Error) {
+	hash, ok := args[0].(*objects.Hash)
+	if !ok {
+		return nil, nil, newError("`%s` expects hash as first argument, but got %s", name, args[0].Type())
+	}
+	key, ok := args[1].(objects.Hashable)
+	if !ok {
+		return nil, nil, newError("unusable as a hash key: %s", args[1].Type())
+	}
+	return hash, key, nil
+}
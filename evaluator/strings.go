@@ -0,0 +1,212 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// String builtins work with characters (runes), so indexes are the same as for `s[i]` and `s[start:end]`
+func init() {
+	builtins["split"] = stringBuiltin("split", 2, func(values []string) objects.Object {
+		return stringsToArray(strings.Split(values[0], values[1]))
+	})
+	builtins["replace"] = &objects.Builtin{Fn: builtinReplace}
+	builtins["trim"] = trimBuiltin("trim", strings.TrimSpace, strings.Trim)
+	builtins["trimLeft"] = trimBuiltin("trimLeft", func(s string) string {
+		return strings.TrimLeftFunc(s, unicode.IsSpace)
+	}, strings.TrimLeft)
+	builtins["trimRight"] = trimBuiltin("trimRight", func(s string) string {
+		return strings.TrimRightFunc(s, unicode.IsSpace)
+	}, strings.TrimRight)
+	builtins["trimPrefix"] = stringBuiltin("trimPrefix", 2, func(values []string) objects.Object {
+		return &objects.String{Value: strings.TrimPrefix(values[0], values[1])}
+	})
+	builtins["trimSuffix"] = stringBuiltin("trimSuffix", 2, func(values []string) objects.Object {
+		return &objects.String{Value: strings.TrimSuffix(values[0], values[1])}
+	})
+	builtins["upper"] = stringBuiltin("upper", 1, func(values []string) objects.Object {
+		return &objects.String{Value: strings.ToUpper(values[0])}
+	})
+	builtins["lower"] = stringBuiltin("lower", 1, func(values []string) objects.Object {
+		return &objects.String{Value: strings.ToLower(values[0])}
+	})
+	builtins["contains"] = stringBuiltin("contains", 2, func(values []string) objects.Object {
+		return nativeBoolean(strings.Contains(values[0], values[1]))
+	})
+	builtins["startsWith"] = stringBuiltin("startsWith", 2, func(values []string) objects.Object {
+		return nativeBoolean(strings.HasPrefix(values[0], values[1]))
+	})
+	builtins["endsWith"] = stringBuiltin("endsWith", 2, func(values []string) objects.Object {
+		return nativeBoolean(strings.HasSuffix(values[0], values[1]))
+	})
+	builtins["indexOf"] = stringBuiltin("indexOf", 2, func(values []string) objects.Object {
+		index := strings.Index(values[0], values[1])
+		if index > 0 {
+			index = utf8.RuneCountInString(values[0][:index])
+		}
+		return &objects.Integer{Value: int64(index)}
+	})
+	builtins["chars"] = stringBuiltin("chars", 1, func(values []string) objects.Object {
+		chars := []string{}
+		for _, r := range values[0] {
+			chars = append(chars, string(r))
+		}
+		return stringsToArray(chars)
+	})
+	builtins["repeat"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		if err := expectArgs("repeat", args, 2); err != nil {
+			return err
+		}
+		s, ok := args[0].(*objects.String)
+		if !ok {
+			return newError("`repeat` expects string as first argument, but got %s", args[0].Type())
+		}
+		n, ok := args[1].(*objects.Integer)
+		if !ok {
+			return newError("`repeat` expects integer as second argument, but got %s", args[1].Type())
+		}
+		return repeatString(s, n)
+	}}
+	builtins["format"] = &objects.Builtin{Fn: builtinFormat}
+}
+
+// stringBuiltin creates a builtin which expects exactly n string arguments
+func stringBuiltin(name string, n int, fn func(values []string) objects.Object) *objects.Builtin {
+	return &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		if err := expectArgs(name, args, n); err != nil {
+			return err
+		}
+		values, err := stringValues(name, args)
+		if err != nil {
+			return err
+		}
+		return fn(values)
+	}}
+}
+
+// trimBuiltin creates a builtin which trims spaces or, if the second argument is passed, the given characters
+func trimBuiltin(name string, spaces func(string) string, cutset func(string, string) string) *objects.Builtin {
+	return &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		if len(args) != 1 && len(args) != 2 {
+			return newError("wrong number of arguments to `%s`; got=%d, expected=%d or %d", name, len(args), 1, 2)
+		}
+		values, err := stringValues(name, args)
+		if err != nil {
+			return err
+		}
+		if len(values) == 1 {
+			return &objects.String{Value: spaces(values[0])}
+		}
+		return &objects.String{Value: cutset(values[0], values[1])}
+	}}
+}
+
+func stringValues(name string, args []objects.Object) ([]string, *objects.Error) {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(*objects.String)
+		if !ok {
+			return nil, newError("`%s` expects string arguments, but got %s", name, arg.Type())
+		}
+		values[i] = s.Value
+	}
+	return values, nil
+}
+
+func stringsToArray(values []string) *objects.Array {
+	arr := &objects.Array{Elements: make([]objects.Object, len(values))}
+	for i, value := range values {
+		arr.Elements[i] = &objects.String{Value: value}
+	}
+	return arr
+}
+
+// joinStrings joins elements of array with separator, non-string elements are joined by their representation.
+// It's called by `join` builtin when the first argument is array, while `join(task)` waits for the task (see tasks.go)
+func joinStrings(arr *objects.Array, args ...objects.Object) objects.Object {
+	if err := expectArgs("join", args, 2); err != nil {
+		return err
+	}
+	sep, ok := args[1].(*objects.String)
+	if !ok {
+		return newError("`join` expects string as second argument, but got %s", args[1].Type())
+	}
+	values := make([]string, len(arr.Elements))
+	for i, element := range arr.Elements {
+		if s, ok := element.(*objects.String); ok {
+			values[i] = s.Value
+			continue
+		}
+		values[i] = element.Inspect()
+	}
+	return &objects.String{Value: strings.Join(values, sep.Value)}
+}
+
+// builtinReplace supports replace(s, old, new) to replace all occurrences and replace(s, old, new, n) to replace first n
+func builtinReplace(args ...objects.Object) objects.Object {
+	if len(args) != 3 && len(args) != 4 {
+		return newError("wrong number of arguments to `replace`; got=%d, expected=%d or %d", len(args), 3, 4)
+	}
+	values, err := stringValues("replace", args[:3])
+	if err != nil {
+		return err
+	}
+	n := int64(-1)
+	if len(args) == 4 {
+		count, ok := args[3].(*objects.Integer)
+		if !ok {
+			return newError("`replace` expects integer as fourth argument, but got %s", args[3].Type())
+		}
+		n = count.Value
+	}
+	return &objects.String{Value: strings.Replace(values[0], values[1], values[2], int(n))}
+}
+
+// builtinFormat formats a string in printf style, scalars are passed as is and other objects by their representation
+func builtinFormat(args ...objects.Object) objects.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments to `format`; got=%d, expected>=%d", len(args), 1)
+	}
+	format, ok := args[0].(*objects.String)
+	if !ok {
+		return newError("`format` expects string as first argument, but got %s", args[0].Type())
+	}
+	values := make([]interface{}, len(args)-1)
+	for i, arg := range args[1:] {
+		switch arg.(type) {
+		case *objects.String, *objects.Integer, *objects.Double, *objects.Boolean:
+			values[i] = getValue(arg)
+		default:
+			values[i] = arg.Inspect()
+		}
+	}
+	return &objects.String{Value: fmt.Sprintf(format.Value, values...)}
+}
+
+func repeatString(s *objects.String, n *objects.Integer) objects.Object {
+	if n.Value < 0 {
+		return newError("negative repeat count: %d", n.Value)
+	}
+	return &objects.String{Value: strings.Repeat(s.Value, int(n.Value))}
+}
+
+// evalTemplateLiteral joins the parts of template, values are converted the same way as by `str` builtin
+func evalTemplateLiteral(node *ast.TemplateLiteral, environment *objects.Environment) objects.Object {
+	out := strings.Builder{}
+	for _, part := range node.Parts {
+		value := eval(part, environment)
+		if isError(value) {
+			return value
+		}
+		if s, ok := value.(*objects.String); ok {
+			out.WriteString(s.Value)
+			continue
+		}
+		out.WriteString(value.Inspect())
+	}
+	return &objects.String{Value: out.String()}
+}
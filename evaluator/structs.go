@@ -0,0 +1,161 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+)
+
+func evalStructStatement(node *ast.StructStatement, environment *objects.Environment) {
+	s := &objects.Struct{
+		Name:        node.Name.Value,
+		Fields:      make([]string, len(node.Fields)),
+		Defaults:    map[string]ast.Expression{},
+		Methods:     map[string]*objects.Function{},
+		Environment: environment,
+	}
+	for i, field := range node.Fields {
+		s.Fields[i] = field.Name.Value
+		if field.Value != nil {
+			s.Defaults[field.Name.Value] = field.Value
+		}
+	}
+	for _, method := range node.Methods {
+		s.Methods[method.Name.Value] = &objects.Function{
+			Parameters:  method.Function.Parameters,
+			Patterns:    method.Function.Patterns,
+			Defaults:    method.Function.Defaults,
+			Rest:        method.Function.Rest,
+			Body:        method.Function.Body,
+			Environment: environment,
+			Async:       method.Function.Async,
+		}
+	}
+	environment.Set(s.Name, s)
+}
+
+// newInstance creates an instance of the struct. If the struct has `init` method the arguments are passed to it,
+// otherwise the arguments are assigned to the fields in order of declaration and named arguments to the fields by name
+func newInstance(s *objects.Struct, args []objects.Object, named map[string]objects.Object) objects.Object {
+	instance := objects.NewInstance(s)
+	for i, field := range s.Fields {
+		exp, ok := s.Defaults[field]
+		if !ok {
+			continue
+		}
+		value := eval(exp, s.Environment)
+		if isError(value) {
+			return value
+		}
+		instance.Values[i] = value
+	}
+
+	if init, ok := s.Methods["init"]; ok {
+		result := callFunction(init, append([]objects.Object{instance}, args...), named)
+		if isError(result) {
+			return result
+		}
+		return instance
+	}
+
+	if len(args) > len(s.Fields) {
+		return newError("too many arguments to create %s; got=%d, expected<=%d", s.Name, len(args), len(s.Fields))
+	}
+	copy(instance.Values, args)
+	for name, value := range named {
+		index, ok := s.FieldIndex(name)
+		if !ok {
+			return newError("undefined field %s of %s", name, s.Name)
+		}
+		if index < len(args) {
+			return newError("multiple values for field %s", name)
+		}
+		instance.Values[index] = value
+	}
+	return instance
+}
+
+// instanceMember resolves a field or a method bound to the instance
+func instanceMember(instance *objects.Instance, name string) (objects.Object, bool) {
+	if value, ok := instance.Get(name); ok {
+		return value, true
+	}
+	method, ok := instance.Struct.Methods[name]
+	if !ok {
+		return nil, false
+	}
+	if _, ok := method.Patterns[0]; ok || len(method.Parameters) == 0 {
+		return &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+			return applyFunction(method, append([]objects.Object{instance}, args...))
+		}}, true
+	}
+	return bindMethod(method, instance), true
+}
+
+// bindMethod returns the method without the receiver parameter, the receiver is bound in the method environment,
+// so the bound method accepts default, rest and named arguments as any other function
+func bindMethod(method *objects.Function, instance *objects.Instance) *objects.Function {
+	env := objects.NewEnclosedEnvironment(method.Environment)
+	env.Set(method.Parameters[0].Value, instance)
+
+	bound := &objects.Function{
+		Parameters:  method.Parameters[1:],
+		Patterns:    map[int]ast.Expression{},
+		Defaults:    map[int]ast.Expression{},
+		Rest:        method.Rest,
+		Body:        method.Body,
+		Environment: env,
+		Async:       method.Async,
+	}
+	for i, pattern := range method.Patterns {
+		bound.Patterns[i-1] = pattern
+	}
+	for i, value := range method.Defaults {
+		bound.Defaults[i-1] = value
+	}
+	return bound
+}
+
+// evalAssignDottedExpression assigns `left.name = value` and `left.name[index] = value`
+func evalAssignDottedExpression(node *ast.InfixExpression, value objects.Object, environment *objects.Environment) objects.Object {
+	left := eval(node.Left, environment)
+	if isError(left) {
+		return left
+	}
+	switch n := node.Right.(type) {
+	case *ast.Identifier:
+		return evalAssignMember(left, n, value)
+	case *ast.IndexExpression:
+		target := evalDottedExpression(left, n.Left, environment, false)
+		if isError(target) {
+			return target
+		}
+		index := eval(n.Index, environment)
+		if isError(index) {
+			return index
+		}
+		return evalAssignIndexExpression(target, index, value)
+	default:
+		return newError("unsupported assignment type receiver: %s", node.Right.TokenLiteral())
+	}
+}
+
+// evalAssignMember assigns `left.name = value` to a field of instance, a key of hash or a binding of module
+func evalAssignMember(left objects.Object, name *ast.Identifier, value objects.Object) objects.Object {
+	switch obj := left.(type) {
+	case *objects.ExternalEnvironment:
+		return evalAssignModuleMember(obj, name, value)
+	case *objects.Instance:
+		if !obj.Set(name.Value, value) {
+			return newError("undefined field %s of %s", name.Value, obj.Struct.Name)
+		}
+		return value
+	case *objects.Hash:
+		if obj.Frozen {
+			return frozenError(obj)
+		}
+		obj.Set(&objects.String{Value: name.Value}, value)
+		return value
+	default:
+		return newError("unsupported member assignment on: %s", left.Type())
+	}
+}
@@ -0,0 +1,185 @@
+package evaluator
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/objects"
+	"reflect"
+)
+
+// Concurrency builtins work with tasks and channels
+func init() {
+	builtins["channel"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		if len(args) > 1 {
+			return newError("wrong number of arguments to `channel`; got=%d, expected<=%d", len(args), 1)
+		}
+		if len(args) == 0 {
+			return objects.NewChannel(0)
+		}
+		capacity, ok := args[0].(*objects.Integer)
+		if !ok || capacity.Value < 0 {
+			return newError("`channel` expects non-negative integer capacity, but got %s", args[0].Inspect())
+		}
+		return objects.NewChannel(int(capacity.Value))
+	}}
+	builtins["send"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		c, err := channelOperation("send", args)
+		if err != nil {
+			return err
+		}
+		if _, _, _, err := loop.await([]reflect.SelectCase{c}, false); err != nil {
+			return err
+		}
+		return args[1]
+	}}
+	builtins["recv"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		c, err := channelOperation("recv", args)
+		if err != nil {
+			return err
+		}
+		_, value, ok, err := loop.await([]reflect.SelectCase{c}, false)
+		if err != nil {
+			return err
+		}
+		return received(value, ok)
+	}}
+	builtins["close"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments to `close`; got=%d, expected=%d", len(args), 1)
+		}
+		ch, ok := args[0].(*objects.Channel)
+		if !ok {
+			return newError("`close` expects channel, but got %s", args[0].Type())
+		}
+		if !ch.Close() {
+			return newError("close of closed channel")
+		}
+		return objects.NULL
+	}}
+	builtins["join"] = &objects.Builtin{Fn: func(args ...objects.Object) objects.Object {
+		if len(args) != 0 {
+			if arr, ok := args[0].(*objects.Array); ok {
+				return joinStrings(arr, args...)
+			}
+		}
+		if err := expectArgs("join", args, 1); err != nil {
+			return err
+		}
+		task, ok := args[0].(*objects.Task)
+		if !ok {
+			return newError("`join` expects task or array, but got %s", args[0].Type())
+		}
+		done := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(task.Done())}
+		if _, _, _, err := loop.await([]reflect.SelectCase{done}, false); err != nil {
+			return err
+		}
+		result, _ := task.Result()
+		if err, ok := result.(*objects.Error); ok {
+			return &objects.Error{Message: err.Message, Stack: append([]string{}, err.Stack...)}
+		}
+		return result
+	}}
+}
+
+// channelOperation validates arguments of `send(channel, value)` or `recv(channel)` and prepares the select case
+func channelOperation(name string, args []objects.Object) (reflect.SelectCase, *objects.Error) {
+	expected := 1
+	dir := reflect.SelectRecv
+	if name == "send" {
+		expected = 2
+		dir = reflect.SelectSend
+	}
+	if len(args) != expected {
+		return reflect.SelectCase{}, newError("wrong number of arguments to `%s`; got=%d, expected=%d", name, len(args), expected)
+	}
+	ch, ok := args[0].(*objects.Channel)
+	if !ok {
+		return reflect.SelectCase{}, newError("`%s` expects channel as first argument, but got %s", name, args[0].Type())
+	}
+
+	c := reflect.SelectCase{Dir: dir, Chan: reflect.ValueOf(ch.Chan())}
+	if dir == reflect.SelectSend {
+		c.Send = reflect.ValueOf(&args[1]).Elem()
+	}
+	return c, nil
+}
+
+// received returns NULL if the channel is closed
+func received(value reflect.Value, ok bool) objects.Object {
+	if !ok {
+		return objects.NULL
+	}
+	return value.Interface().(objects.Object)
+}
+
+func evalSpawnExpression(node *ast.SpawnExpression, environment *objects.Environment) objects.Object {
+	var function objects.Object
+	var named map[string]objects.Object
+	args := []objects.Object{}
+
+	// The call arguments are evaluated by the current task, the function is applied in the spawned one
+	if call, ok := node.Value.(*ast.CallExpression); ok {
+		function = eval(call.Function, environment)
+		if isError(function) {
+			return function
+		}
+		args = evalExpressions(call.Arguments, environment)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		var err objects.Object
+		if named, err = evalNamedArguments(call.Named, environment); err != nil {
+			return err
+		}
+	} else {
+		function = eval(node.Value, environment)
+		if isError(function) {
+			return function
+		}
+	}
+
+	if function.Type() != objects.FUNCTION_OBJ && function.Type() != objects.BUILTIN_OBJ {
+		return newError("spawn expects a function, but got %s", function.Type())
+	}
+
+	task := objects.NewTask()
+	loop.ref()
+	go func() {
+		loop.interp.Lock()
+		result := callFunction(function, args, named)
+		loop.interp.Unlock()
+		task.Complete(result)
+		loop.unref()
+	}()
+	return task
+}
+
+func evalSelectExpression(node *ast.SelectExpression, environment *objects.Environment) objects.Object {
+	cases := make([]reflect.SelectCase, len(node.Cases))
+	for i, selectCase := range node.Cases {
+		args := evalExpressions(selectCase.Operation.Arguments, environment)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		c, err := channelOperation(selectCase.Operation.Function.String(), args)
+		if err != nil {
+			return err
+		}
+		cases[i] = c
+	}
+
+	chosen, value, ok, err := loop.await(cases, node.Default != nil)
+	if err != nil {
+		return err
+	}
+
+	newEnv := objects.NewEnclosedEnvironment(environment)
+	if chosen < 0 {
+		return eval(node.Default, newEnv)
+	}
+
+	selectCase := node.Cases[chosen]
+	if selectCase.Name != nil {
+		newEnv.Set(selectCase.Name.Value, received(value, ok))
+	}
+	return eval(selectCase.Body, newEnv)
+}
@@ -1,5 +1,17 @@
 package extensions
 
+// Iterator can be returned by a plugin function to loop over the values with `for (v in value)`,
+// the values are converted to rash objects the same way as the results of plugin functions
+type Iterator interface {
+	// Next returns the next value, or false if there are no more values
+	Next() (interface{}, bool, error)
+}
+
+// Keep must be called by a plugin which keeps the callback of `call` to call it after the plugin function returns,
+// such as a server handler or a ticker. The script keeps running until the returned release function is called.
+// The callback is valid only during the plugin call otherwise. It's set by the interpreter, the default one does nothing
+var Keep = func() (release func()) { return func() {} }
+
 type Plugin interface {
 	Eval(fnName string, args ...interface{}) ([]interface{}, error)
 	Call(fnName string, callback func(args ...interface{}) ([]interface{}, error), args ...interface{}) ([]interface{}, error)
@@ -7,3 +19,10 @@ type Plugin interface {
 	Version() string
 	Description() string
 }
+
+// Manifest can be implemented by a plugin to describe its functions for the type checker.
+// The signature is a function type of the arguments passed after the package and function names,
+// the callback of `call` is the first parameter: {"len": "fn(string | array | hash): int", "tick": "fn(fn(string), int)"}
+type Manifest interface {
+	Signatures() map[string]string
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"github.com/YReshetko/rash-lang/extensions"
 	"net/http"
 )
 
@@ -60,6 +61,14 @@ func (s httpPlugin) Description() string {
 	return desc
 }
 
+func (s httpPlugin) Signatures() map[string]string {
+	return map[string]string{
+		"new":      "fn(string): string",
+		"start":    "fn(string): null",
+		"register": "fn(fn(): string | null, string, string, string): null",
+	}
+}
+
 func (s httpPlugin) newServer(args ...interface{}) ([]interface{}, error) {
 	if len(args) < 1 {
 		return nil, errors.New("expected at least port")
@@ -131,7 +140,10 @@ func (s httpPlugin) start(args ...interface{}) ([]interface{}, error) {
 		})
 	}
 
+	// the handlers are called after start returns, so the script is kept running while the server is serving
+	release := extensions.Keep()
 	go func() {
+		defer release()
 		err := http.ListenAndServe(server.port, server.mux)
 		fmt.Println(err)
 	}()
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"github.com/YReshetko/rash-lang/extensions"
 	"time"
 )
 
@@ -50,6 +51,15 @@ func (s sysPlugin) Description() string {
 	return desc
 }
 
+func (s sysPlugin) Signatures() map[string]string {
+	return map[string]string{
+		"len":   "fn(string | array | hash): int",
+		"time":  "fn(): string",
+		"print": "fn(...any): null",
+		"tick":  "fn(fn(string), int): null",
+	}
+}
+
 func (s sysPlugin) length(args ...interface{}) ([]interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("wrong number of arguments to `len`; got=%d, expected=1", len(args))
@@ -87,6 +97,7 @@ func (s sysPlugin) tick(callback Callback, args ...interface{}) ([]interface{},
 	}
 
 	ticker := time.NewTicker(time.Second * time.Duration(duration))
+	release := extensions.Keep()
 	go func() {
 		for {
 			select {
@@ -94,6 +105,7 @@ func (s sysPlugin) tick(callback Callback, args ...interface{}) ([]interface{},
 				_, err := callback(x.Format(time.RFC3339))
 				if err != nil {
 					ticker.Stop()
+					release()
 					return
 				}
 			}
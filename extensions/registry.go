@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"plugin"
+	"sort"
 )
 
 type Registry struct {
@@ -31,10 +32,52 @@ func (r *Registry) Add(file, symbol string) error {
 	}
 
 	// TODO version validation
-	r.plugins[plug.Package()] = plug
+	r.Register(plug)
 	return nil
 }
 
+// Register adds the plugin which is already loaded, for example the one compiled into the interpreter
+func (r *Registry) Register(plug Plugin) {
+	r.plugins[plug.Package()] = plug
+}
+
+// Signature returns the signature of plugin function if the plugin implements Manifest
+func (r *Registry) Signature(pkgName, fnName string) (string, bool) {
+	manifest, ok := r.plugins[pkgName].(Manifest)
+	if !ok {
+		return "", false
+	}
+	signature, ok := manifest.Signatures()[fnName]
+	return signature, ok
+}
+
+// Packages returns the sorted package names of registered plugins
+func (r *Registry) Packages() []string {
+	names := make([]string, 0, len(r.plugins))
+	for name := range r.plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Signatures returns the signatures of plugin functions by name if the plugin implements Manifest
+func (r *Registry) Signatures(pkgName string) map[string]string {
+	manifest, ok := r.plugins[pkgName].(Manifest)
+	if !ok {
+		return map[string]string{}
+	}
+	return manifest.Signatures()
+}
+
+// Description returns the description of registered plugin
+func (r *Registry) Description(pkgName string) string {
+	if plug, ok := r.plugins[pkgName]; ok {
+		return plug.Description()
+	}
+	return ""
+}
+
 func (r *Registry) Eval(pkgName, fnName string, args ...interface{}) ([]interface{}, error) {
 	plug, ok := r.plugins[pkgName]
 	if !ok {
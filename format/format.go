@@ -0,0 +1,175 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/lexer"
+	"github.com/YReshetko/rash-lang/parser"
+	"github.com/YReshetko/rash-lang/tokens"
+	"math"
+	"reflect"
+	"strings"
+)
+
+const indent = "    "
+
+// Source formats the script in the canonical style: one statement per line terminated by semicolon,
+// blocks indented by four spaces, minimal parentheses and trailing commas in multiline arrays and hashes.
+// The comments and single blank lines between statements are kept. The script with syntax errors is not formatted
+func Source(src []byte, fileName string) ([]byte, error) {
+	l := lexer.New(string(src), fileName)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("unable to format %s due to:\n %s", fileName, strings.Join(p.Errors(), ";\n"))
+	}
+
+	pr := &printer{
+		comments: l.Comments(),
+		lines:    strings.Split(string(src), "\n"),
+	}
+	pr.program(program)
+	return pr.out.Bytes(), nil
+}
+
+// Expression returns the expression in the canonical style without comments, it's used to quote the code in messages
+func Expression(e ast.Expression) string {
+	p := &printer{}
+	p.expression(e, parser.LOWEST)
+	return p.out.String()
+}
+
+// printer writes the program indenting the lines lazily, so the comments found between nodes can be placed
+// on the lines before a node or at the end of the last written line
+type printer struct {
+	out      bytes.Buffer
+	depth    int
+	comments []lexer.Comment // comments which are not written yet
+	lines    []string        // source lines to keep blank lines
+}
+
+func (p *printer) write(s string) {
+	if s == "" {
+		return
+	}
+	if p.atLineStart() {
+		p.out.WriteString(strings.Repeat(indent, p.depth))
+	}
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.out.WriteByte('\n')
+}
+
+func (p *printer) atLineStart() bool {
+	return p.out.Len() == 0 || p.out.Bytes()[p.out.Len()-1] == '\n'
+}
+
+// flush writes the comments placed before the source line, it's called at the start of a line.
+// A trailing comment is appended to the last written line
+func (p *printer) flush(line int) {
+	for len(p.comments) != 0 && p.comments[0].Line < line {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		if comment.Trailing && p.out.Len() != 0 {
+			p.out.Truncate(p.out.Len() - 1)
+			p.out.WriteString(" " + comment.Text)
+			p.newline()
+			continue
+		}
+		p.separate(comment.Line)
+		p.write(comment.Text)
+		p.newline()
+	}
+}
+
+// separate keeps a blank line before the source line, unless it's the first line of block
+func (p *printer) separate(line int) {
+	if line < 2 || line-2 >= len(p.lines) || strings.TrimSpace(p.lines[line-2]) != "" {
+		return
+	}
+	out := p.out.Bytes()
+	if len(out) < 2 || out[len(out)-2] == '\n' || out[len(out)-2] == '{' || out[len(out)-2] == '[' {
+		return
+	}
+	p.newline()
+}
+
+// hasComments checks there are comments to write between the source lines. A comment lasts until the end of line,
+// so the comments on the line of closing token follow the token and are not counted by the callers
+func (p *printer) hasComments(from, to int) bool {
+	for _, comment := range p.comments {
+		if comment.Line > to {
+			return false
+		}
+		if comment.Line >= from {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *printer) program(program *ast.Program) {
+	for i, statement := range program.Statements {
+		if i != 0 {
+			p.newline()
+		}
+		p.flush(line(statement))
+		p.separate(line(statement))
+		p.statement(statement)
+		p.write(terminator(statement, next(program.Statements, i)))
+	}
+	if len(program.Statements) != 0 {
+		p.newline()
+	}
+	p.flush(math.MaxInt32)
+}
+
+// list writes the items of multiline array, hash, struct, match or select on separate lines
+func (p *printer) list(open, close string, end tokens.Token, count int, start func(int) int, item func(int)) {
+	if count == 0 && !p.hasComments(0, end.LineNumber-1) {
+		p.write(open + close)
+		return
+	}
+	p.write(open)
+	p.depth++
+	for i := 0; i < count; i++ {
+		p.newline()
+		p.flush(start(i))
+		p.separate(start(i))
+		item(i)
+	}
+	p.newline()
+	p.flush(end.LineNumber)
+	p.depth--
+	p.write(close)
+}
+
+func next(statements []ast.Statement, i int) ast.Statement {
+	if i+1 < len(statements) {
+		return statements[i+1]
+	}
+	return nil
+}
+
+// line returns the source line of the first token of node
+func line(node ast.Node) int {
+	switch n := node.(type) {
+	case *ast.InfixExpression:
+		return line(n.Left)
+	case *ast.CallExpression:
+		return line(n.Function)
+	case *ast.IndexExpression:
+		return line(n.Left)
+	case *ast.SliceExpression:
+		return line(n.Left)
+	case *ast.MultipleAssignment:
+		return line(n.Targets[0])
+	case *ast.AlternativePattern:
+		return line(n.Patterns[0])
+	}
+	token := reflect.Indirect(reflect.ValueOf(node)).FieldByName("Token").Interface().(tokens.Token)
+	return token.LineNumber
+}
@@ -0,0 +1,137 @@
+package format_test
+
+import (
+	"github.com/YReshetko/rash-lang/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "spacing and semicolons",
+			input:    "let a=1+2*3\nlet b = (1+2)*3;a=b",
+			expected: "let a = 1 + 2 * 3;\nlet b = (1 + 2) * 3;\na = b;\n",
+		},
+		{
+			name:     "minimal parentheses",
+			input:    "((a - b) - c); a - (b - c); -(a + b); !(!a); (await f)(1); a.b.c(1)[2]?.d; (a.b)[0]; 0..(n + 1);",
+			expected: "a - b - c;\na - (b - c);\n-(a + b);\n!!a;\n(await f)(1);\na.b.c(1)[2]?.d;\n(a.b)[0];\n0..n + 1;\n",
+		},
+		{
+			name:  "indentation",
+			input: "let f = fn(x,y=2,...rest){\nif (x>1) {\nreturn x\n} else {\nreturn -x;}\n};",
+			expected: `let f = fn(x, y = 2, ...rest) {
+    if (x > 1) {
+        return x;
+    } else {
+        return -x;
+    }
+};
+`,
+		},
+		{
+			name:     "one line blocks",
+			input:    "let double = fn(x) {x * 2};\nmap(arr, fn(x){ return x; });\nif (a) {}",
+			expected: "let double = fn(x) { x * 2 };\nmap(arr, fn(x) { return x; });\nif (a) {}\n",
+		},
+		{
+			name:  "multiline arrays and hashes",
+			input: "let a = [1, 2];\nlet h = {\n\"a\": [1,\n2], \"b\": {}};",
+			expected: `let a = [1, 2];
+let h = {
+    "a": [
+        1,
+        2,
+    ],
+    "b": {},
+};
+`,
+		},
+		{
+			name:  "comments and blank lines",
+			input: "// header\n\n\n\nlet a = 1; // one\nlet f = fn() {\n  // inside\n  a\n\n  // last\n};\n// end",
+			expected: `// header
+
+let a = 1; // one
+let f = fn() {
+    // inside
+    a;
+
+    // last
+};
+// end
+`,
+		},
+		{
+			name:  "trailing comments after closing tokens",
+			input: "let b = [1, 2, 3]; // b\nlet c = {\"a\": 1}; // c\nlet d = fn() { 1 }; // d\nlet e = [ // e\n1];",
+			expected: `let b = [1, 2, 3]; // b
+let c = {"a": 1}; // c
+let d = fn() { 1 }; // d
+let e = [ // e
+    1,
+];
+`,
+		},
+		{
+			name:  "declarations",
+			input: "# {print, b as c} from \"x.rs\"\nexport # * from \"y.rs\";\nexport struct Point { x, y = 0; fn len(self): int { self.x } }\nconst [a, {name, \"k\": v, ...rest}] = p;",
+			expected: `# {print, b as c} from "x.rs";
+export # * from "y.rs";
+export struct Point {
+    x;
+    y = 0;
+    fn len(self): int { self.x }
+}
+const [a, {name, "k": v, ...rest}] = p;
+`,
+		},
+		{
+			name:  "loops, match and select",
+			input: "outer: for (k, v in h) { for (let i = 0; i < 3; i = i + 1) { continue outer } }\nmatch (a) { 1 | 2 => \"small\", [x, ...r] if x > 1 => { x }, _ => -1 }\nselect { case v = recv(ch) { v } default {} }",
+			expected: `outer: for (k, v in h) { for (let i = 0; i < 3; i = i + 1) { continue outer; } }
+match (a) {
+    1 | 2 => "small",
+    [x, ...r] if x > 1 => { x },
+    _ => -1,
+}
+select {
+    case v = recv(ch) { v }
+    default {}
+}
+`,
+		},
+		{
+			name:     "strings as written",
+			input:    "let s = `raw\n  text`;\nlet t = \"a ${b + 1} \\n\";",
+			expected: "let s = `raw\n  text`;\nlet t = \"a ${b + 1} \\n\";\n",
+		},
+		{
+			name:     "block expression followed by bracket or minus",
+			input:    "if (a) { b };\n(c);\nfor (x) { b };\n-c;\n(a + b) * c",
+			expected: "if (a) { b }\nc;\nfor (x) { b };\n-c;\n(a + b) * c;\n",
+		},
+	}
+
+	for _, test := range tests {
+		out, err := format.Source([]byte(test.input), "non-file")
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, string(out), test.name)
+
+		again, err := format.Source(out, "non-file")
+		require.NoError(t, err, test.name)
+		assert.Equal(t, string(out), string(again), test.name)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := format.Source([]byte("let a = ;"), "broken.rs")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to format broken.rs due to:")
+}
@@ -0,0 +1,540 @@
+package format
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/parser"
+	"github.com/YReshetko/rash-lang/tokens"
+	"sort"
+	"strings"
+)
+
+// atom is the precedence of literals, identifiers and other expressions which are never parenthesized
+const atom = parser.INDEX + 1
+
+func (p *printer) statement(statement ast.Statement) {
+	switch s := statement.(type) {
+	case *ast.LetStatement:
+		p.let(s)
+	case *ast.ReturnStatement:
+		p.write("return")
+		if s.Value != nil {
+			p.write(" ")
+			p.expression(s.Value, parser.LOWEST)
+		}
+	case *ast.BreakStatement:
+		p.branch(s.TokenLiteral(), s.Label)
+	case *ast.ContinueStatement:
+		p.branch(s.TokenLiteral(), s.Label)
+	case *ast.ExpressionStatement:
+		p.expression(s.Expression, parser.LOWEST)
+	case *ast.DeclarationStatement:
+		p.include(s.Declaration.(*ast.IncludeDeclaration))
+	case *ast.ExportStatement:
+		p.write("export ")
+		p.statement(s.Statement)
+	case *ast.StructStatement:
+		p.structure(s)
+	case *ast.BlockStatement:
+		p.block(s)
+	}
+}
+
+// terminator returns the semicolon ending the statement. Struct declarations and loops, conditions, match and select
+// ending with a block are not terminated unless the next statement is written starting from ( [ - which would
+// continue the expression
+func terminator(statement, next ast.Statement) string {
+	switch s := statement.(type) {
+	case *ast.StructStatement, *ast.BlockStatement:
+		return ""
+	case *ast.ExportStatement:
+		return terminator(s.Statement, next)
+	case *ast.ExpressionStatement:
+		switch s.Expression.(type) {
+		case *ast.IfExpression, *ast.ForExpression, *ast.ForInExpression, *ast.MatchExpression, *ast.SelectExpression:
+			if n, ok := next.(*ast.ExpressionStatement); ok {
+				written := &printer{}
+				written.statement(n)
+				if strings.IndexAny(written.out.String(), "([-") == 0 {
+					return ";"
+				}
+			}
+			return ""
+		}
+	}
+	return ";"
+}
+
+func (p *printer) let(s *ast.LetStatement) {
+	p.write(s.TokenLiteral() + " ")
+	if s.Pattern != nil {
+		p.pattern(s.Pattern)
+	} else {
+		p.write(s.Name.Value)
+	}
+	if s.Type != nil {
+		p.write(": " + s.Type.String())
+	}
+	p.write(" = ")
+	p.expression(s.Value, parser.LOWEST)
+}
+
+func (p *printer) include(d *ast.IncludeDeclaration) {
+	p.write("# ")
+	switch {
+	case d.Wildcard:
+		p.write("* from ")
+	case d.Names != nil:
+		names := make([]string, len(d.Names))
+		for i, name := range d.Names {
+			names[i] = name.String()
+		}
+		p.write("{" + strings.Join(names, ", ") + "} from ")
+	default:
+		p.write(d.Alias.Value + " ")
+	}
+	p.write(d.Include.Token.Raw)
+}
+
+func (p *printer) branch(keyword string, label *ast.Identifier) {
+	p.write(keyword)
+	if label != nil {
+		p.write(" " + label.Value)
+	}
+}
+
+// block writes the statements on separate lines. The block which is written on one line in the source and
+// contains a single short statement is kept on one line: fn(x) { x * 2 }
+func (p *printer) block(b *ast.BlockStatement) {
+	if len(b.Statements) == 1 && b.Token.LineNumber == b.End.LineNumber {
+		inline := &printer{}
+		inline.statement(b.Statements[0])
+		if _, ok := b.Statements[0].(*ast.ExpressionStatement); !ok {
+			inline.write(terminator(b.Statements[0], nil))
+		}
+		if !strings.Contains(inline.out.String(), "\n") {
+			p.write("{ " + inline.out.String() + " }")
+			return
+		}
+	}
+	p.list("{", "}", b.End, len(b.Statements), func(i int) int {
+		return line(b.Statements[i])
+	}, func(i int) {
+		p.statement(b.Statements[i])
+		p.write(terminator(b.Statements[i], next(b.Statements, i)))
+	})
+}
+
+// structure writes the fields and methods of struct in the source order
+func (p *printer) structure(s *ast.StructStatement) {
+	type member struct {
+		line  int
+		write func()
+	}
+	members := []member{}
+	for _, field := range s.Fields {
+		field := field
+		members = append(members, member{line: field.Name.Token.LineNumber, write: func() {
+			p.write(field.Name.Value)
+			if field.Value != nil {
+				p.write(" = ")
+				p.expression(field.Value, parser.LOWEST)
+			}
+			p.write(";")
+		}})
+	}
+	for _, method := range s.Methods {
+		method := method
+		members = append(members, member{line: method.Function.Token.LineNumber, write: func() {
+			p.function(method.Function, method.Name.Value)
+		}})
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].line < members[j].line
+	})
+
+	p.write("struct " + s.Name.Value + " ")
+	p.list("{", "}", s.End, len(members), func(i int) int {
+		return members[i].line
+	}, func(i int) {
+		members[i].write()
+	})
+}
+
+func (p *printer) expression(e ast.Expression, precedence int) {
+	if precedenceOf(e) < precedence {
+		p.write("(")
+		p.expression(e, parser.LOWEST)
+		p.write(")")
+		return
+	}
+
+	switch n := e.(type) {
+	case *ast.StringLiteral:
+		p.write(n.Token.Raw)
+	case *ast.TemplateLiteral:
+		p.write(n.Token.Raw)
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.DoubleLiteral, *ast.BooleanLiteral, *ast.NullLiteral:
+		p.write(n.TokenLiteral())
+	case *ast.LetStatement:
+		p.let(n)
+	case *ast.PrefixExpression:
+		p.write(n.Operator)
+		p.operand(n.Right)
+	case *ast.AwaitExpression:
+		p.write("await ")
+		p.operand(n.Value)
+	case *ast.SpawnExpression:
+		p.write("spawn ")
+		p.operand(n.Value)
+	case *ast.SpreadExpression:
+		p.write("...")
+		p.expression(n.Value, parser.LOWEST)
+	case *ast.InfixExpression:
+		p.infix(n)
+	case *ast.MultipleAssignment:
+		p.expressions(n.Targets)
+		p.write(" = ")
+		p.expressions(n.Values)
+	case *ast.CallExpression:
+		p.call(n)
+	case *ast.IndexExpression:
+		p.expression(n.Left, parser.INDEX)
+		p.write(bracket(n.Optional))
+		p.expression(n.Index, parser.LOWEST)
+		p.write("]")
+	case *ast.SliceExpression:
+		p.expression(n.Left, parser.INDEX)
+		p.write(bracket(n.Optional))
+		if n.Start != nil {
+			p.expression(n.Start, parser.LOWEST)
+		}
+		p.write(":")
+		if n.End != nil {
+			p.expression(n.End, parser.LOWEST)
+		}
+		p.write("]")
+	case *ast.ArrayLiteral:
+		p.array(n)
+	case *ast.HashLiteral:
+		p.hash(n)
+	case *ast.FunctionLiteral:
+		p.function(n, "")
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(n.Condition, parser.LOWEST)
+		p.write(") ")
+		p.block(n.Consequence)
+		if n.Alternative != nil {
+			p.write(" else ")
+			p.block(n.Alternative)
+		}
+	case *ast.ForExpression:
+		p.loop(n)
+	case *ast.ForInExpression:
+		p.label(n.Label)
+		p.write("for (")
+		if n.Key != nil {
+			p.write(n.Key.Value + ", ")
+		}
+		p.write(n.Value.Value + " in ")
+		p.expression(n.Iterable, parser.LOWEST)
+		p.write(") ")
+		p.block(n.Body)
+	case *ast.MatchExpression:
+		p.match(n)
+	case *ast.SelectExpression:
+		p.selection(n)
+	}
+}
+
+// precedenceOf returns the precedence of operator of the expression, the operands of lower precedence are parenthesized.
+// Calls, indexes and slices end with a bracket, so they never need parentheses
+func precedenceOf(e ast.Expression) int {
+	switch n := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(n.Token.Type)
+	case *ast.PrefixExpression, *ast.AwaitExpression, *ast.SpawnExpression:
+		return parser.PREFIX
+	case *ast.LetStatement, *ast.MultipleAssignment, *ast.SpreadExpression:
+		return parser.LOWEST
+	default:
+		return atom
+	}
+}
+
+// operand writes the operand of prefix operator, the parser binds the operators tighter than prefix to the operand
+func (p *printer) operand(e ast.Expression) {
+	if precedenceOf(e) == parser.PREFIX {
+		p.expression(e, parser.PREFIX)
+		return
+	}
+	p.expression(e, parser.PREFIX+1)
+}
+
+// infix writes the binary operation, the operators of the same precedence are left associative
+func (p *printer) infix(n *ast.InfixExpression) {
+	precedence := parser.Precedence(n.Token.Type)
+	p.expression(n.Left, precedence)
+	switch n.Operator {
+	case ".", "?.", "..":
+		p.write(n.Operator)
+	default:
+		p.write(" " + n.Operator + " ")
+	}
+	p.expression(n.Right, precedence+1)
+}
+
+func (p *printer) expressions(expressions []ast.Expression) {
+	for i, e := range expressions {
+		if i != 0 {
+			p.write(", ")
+		}
+		p.expression(e, parser.LOWEST)
+	}
+}
+
+func (p *printer) call(n *ast.CallExpression) {
+	p.expression(n.Function, parser.CALL)
+	if n.Optional {
+		p.write("?.")
+	}
+	p.write("(")
+	p.expressions(n.Arguments)
+	for i, argument := range n.Named {
+		if i != 0 || len(n.Arguments) != 0 {
+			p.write(", ")
+		}
+		p.write(argument.Name.Value + ": ")
+		p.expression(argument.Value, parser.LOWEST)
+	}
+	p.write(")")
+}
+
+func bracket(optional bool) string {
+	if optional {
+		return "?["
+	}
+	return "["
+}
+
+// multiline checks the elements of array or hash are written on separate lines in the source
+func (p *printer) multiline(open, end tokens.Token, elements []ast.Expression) bool {
+	for _, element := range elements {
+		if line(element) != open.LineNumber {
+			return true
+		}
+	}
+	return p.hasComments(open.LineNumber, end.LineNumber-1)
+}
+
+func (p *printer) array(n *ast.ArrayLiteral) {
+	if !p.multiline(n.Token, n.End, n.Elements) {
+		p.write("[")
+		p.expressions(n.Elements)
+		p.write("]")
+		return
+	}
+	p.list("[", "]", n.End, len(n.Elements), func(i int) int {
+		return line(n.Elements[i])
+	}, func(i int) {
+		p.expression(n.Elements[i], parser.LOWEST)
+		p.write(",")
+	})
+}
+
+func (p *printer) hash(n *ast.HashLiteral) {
+	pair := func(i int) {
+		p.expression(n.Keys[i], parser.LOWEST)
+		p.write(": ")
+		p.expression(n.Pairs[n.Keys[i]], parser.LOWEST)
+	}
+	if !p.multiline(n.Token, n.End, n.Keys) {
+		p.write("{")
+		for i := range n.Keys {
+			if i != 0 {
+				p.write(", ")
+			}
+			pair(i)
+		}
+		p.write("}")
+		return
+	}
+	p.list("{", "}", n.End, len(n.Keys), func(i int) int {
+		return line(n.Keys[i])
+	}, func(i int) {
+		pair(i)
+		p.write(",")
+	})
+}
+
+// function writes the function literal or the method of struct if the name is defined
+func (p *printer) function(f *ast.FunctionLiteral, name string) {
+	if f.Async {
+		p.write("async ")
+	}
+	p.write("fn")
+	if name != "" {
+		p.write(" " + name)
+	}
+	p.write("(")
+	for i, parameter := range f.Parameters {
+		if i != 0 {
+			p.write(", ")
+		}
+		if pattern, ok := f.Patterns[i]; ok {
+			p.pattern(pattern)
+		} else {
+			p.write(parameter.Value)
+		}
+		if t, ok := f.Types[i]; ok {
+			p.write(": " + t.String())
+		}
+		if value, ok := f.Defaults[i]; ok {
+			p.write(" = ")
+			p.expression(value, parser.LOWEST)
+		}
+	}
+	if f.Rest != nil {
+		if len(f.Parameters) != 0 {
+			p.write(", ")
+		}
+		p.write("..." + f.Rest.Value)
+	}
+	p.write(")")
+	if f.Result != nil {
+		p.write(": " + f.Result.String())
+	}
+	p.write(" ")
+	p.block(f.Body)
+}
+
+func (p *printer) label(label *ast.Identifier) {
+	if label != nil {
+		p.write(label.Value + ": ")
+	}
+}
+
+func (p *printer) loop(n *ast.ForExpression) {
+	p.label(n.Label)
+	p.write("for (")
+	arguments := []ast.Expression{}
+	for _, argument := range []ast.Expression{n.Initial, n.Condition, n.Complete} {
+		if argument != nil {
+			arguments = append(arguments, argument)
+		}
+	}
+	for i, argument := range arguments {
+		if i != 0 {
+			p.write("; ")
+		}
+		p.expression(argument, parser.LOWEST)
+	}
+	p.write(") ")
+	p.block(n.Body)
+}
+
+func (p *printer) match(n *ast.MatchExpression) {
+	p.write("match (")
+	p.expression(n.Value, parser.LOWEST)
+	p.write(") ")
+	p.list("{", "}", n.End, len(n.Arms), func(i int) int {
+		return n.Arms[i].Token.LineNumber
+	}, func(i int) {
+		arm := n.Arms[i]
+		p.pattern(arm.Pattern)
+		if arm.Guard != nil {
+			p.write(" if ")
+			p.expression(arm.Guard, parser.LOWEST)
+		}
+		p.write(" => ")
+		if arm.Body.Token.Type == tokens.LBRACE {
+			p.block(arm.Body)
+		} else {
+			p.expression(arm.Body.Statements[0].(*ast.ExpressionStatement).Expression, parser.LOWEST)
+		}
+		p.write(",")
+	})
+}
+
+func (p *printer) selection(n *ast.SelectExpression) {
+	count := len(n.Cases)
+	if n.Default != nil {
+		count++
+	}
+	p.write("select ")
+	p.list("{", "}", n.End, count, func(i int) int {
+		if i == len(n.Cases) {
+			return n.Default.Token.LineNumber
+		}
+		return n.Cases[i].Token.LineNumber
+	}, func(i int) {
+		if i == len(n.Cases) {
+			p.write("default ")
+			p.block(n.Default)
+			return
+		}
+		c := n.Cases[i]
+		p.write("case ")
+		if c.Name != nil {
+			p.write(c.Name.Value + " = ")
+		}
+		p.call(c.Operation)
+		p.write(" ")
+		p.block(c.Body)
+	})
+}
+
+// pattern writes the destructuring or match pattern
+func (p *printer) pattern(pattern ast.Expression) {
+	switch n := pattern.(type) {
+	case *ast.ArrayPattern:
+		p.write("[")
+		for i, element := range n.Elements {
+			if i != 0 {
+				p.write(", ")
+			}
+			p.pattern(element)
+		}
+		p.rest(len(n.Elements), n.Rest)
+		p.write("]")
+	case *ast.HashPattern:
+		p.write("{")
+		for i, key := range n.Keys {
+			if i != 0 {
+				p.write(", ")
+			}
+			if target, ok := n.Targets[i].(*ast.Identifier); ok && target.Value == key.Value && key.Token.Type == tokens.IDENT {
+				p.write(key.Value)
+				continue
+			}
+			if key.Token.Type == tokens.STRING {
+				p.write(key.Token.Raw)
+			} else {
+				p.write(key.Value)
+			}
+			p.write(": ")
+			p.pattern(n.Targets[i])
+		}
+		p.rest(len(n.Keys), n.Rest)
+		p.write("}")
+	case *ast.AlternativePattern:
+		for i, alternative := range n.Patterns {
+			if i != 0 {
+				p.write(" | ")
+			}
+			p.pattern(alternative)
+		}
+	default:
+		p.expression(pattern, parser.LOWEST)
+	}
+}
+
+func (p *printer) rest(count int, rest *ast.Identifier) {
+	if rest == nil {
+		return
+	}
+	if count != 0 {
+		p.write(", ")
+	}
+	p.write("..." + rest.Value)
+}
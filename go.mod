@@ -1,5 +1,5 @@
 module github.com/YReshetko/rash-lang
 
-go 1.15
+go 1.16
 
 require github.com/stretchr/testify v1.7.0
@@ -1,4 +1,4 @@
-let some_constant = 144
+const some_constant = 144
 
 let doubled = fn(){
 	return some_constant * 2;
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"fmt"
 	"github.com/YReshetko/rash-lang/tokens"
 	"strings"
 )
@@ -14,12 +15,27 @@ type Lexer struct {
 	// for debug
 	fileName string // Input file name
 	line     int    // current line
+
+	errors   []string
+	comments []Comment
+}
+
+// Comment is a line comment `// ...`, the lexer skips comments and keeps them for the formatter
+type Comment struct {
+	Text     string // comment text starting from //
+	Line     int
+	Trailing bool // the comment follows the code on the same line
 }
 
 func New(input, fileName string) *Lexer {
+	return NewFromLine(input, fileName, 1)
+}
+
+// NewFromLine creates the lexer of a code fragment which starts on the given line of the file
+func NewFromLine(input, fileName string, line int) *Lexer {
 	l := &Lexer{
 		input:    input,
-		line:     1,
+		line:     line,
 		fileName: fileName,
 	}
 	// The sign of reading completion is ch=0, in ASCII it's 'NUL'.
@@ -36,6 +52,9 @@ func (l *Lexer) NextToken() tokens.Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = l.newToken(tokens.EQ, "==")
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = l.newToken(tokens.ARROW, "=>")
 		} else {
 			tok = l.newToken(tokens.ASSIGN, "=")
 		}
@@ -59,9 +78,34 @@ func (l *Lexer) NextToken() tokens.Token {
 	case '>':
 		tok = l.newToken(tokens.GT, ">")
 	case '.':
-		tok = l.newToken(tokens.DOT, ".")
+		if l.peekChar() == '.' && l.readPosition+1 < len(l.input) && l.input[l.readPosition+1] == '.' {
+			l.readChar()
+			l.readChar()
+			tok = l.newToken(tokens.ELLIPSIS, "...")
+		} else if l.peekChar() == '.' {
+			l.readChar()
+			tok = l.newToken(tokens.RANGE, "..")
+		} else {
+			tok = l.newToken(tokens.DOT, ".")
+		}
+	case '?':
+		switch l.peekChar() {
+		case '.':
+			l.readChar()
+			tok = l.newToken(tokens.OPTIONAL_DOT, "?.")
+		case '[':
+			l.readChar()
+			tok = l.newToken(tokens.OPTIONAL_LBRACKET, "?[")
+		case '?':
+			l.readChar()
+			tok = l.newToken(tokens.NULLISH, "??")
+		default:
+			tok = l.newToken(tokens.ILLEGAL, "?")
+		}
 	case ',':
 		tok = l.newToken(tokens.COMMA, ",")
+	case '|':
+		tok = l.newToken(tokens.PIPE, "|")
 	case ';':
 		tok = l.newToken(tokens.SEMICOLON, ";")
 	case ':':
@@ -81,7 +125,9 @@ func (l *Lexer) NextToken() tokens.Token {
 	case '#':
 		tok = l.newToken(tokens.HASH, "#")
 	case '"':
-		tok = l.newToken(tokens.STRING, l.readString())
+		tok = l.readString()
+	case '`':
+		tok = l.readRawString()
 	case 0:
 		return l.newToken(tokens.EOF, "")
 	default:
@@ -141,12 +187,33 @@ func (l *Lexer) peekChar() byte {
 }
 
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		if l.ch == '\n' {
-			l.line++
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			if l.ch == '\n' {
+				l.line++
+			}
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			l.readComment()
+		default:
+			return
 		}
+	}
+}
+
+// readComment reads the comment until the end of line
+func (l *Lexer) readComment() {
+	lineStart := strings.LastIndexByte(l.input[:l.position], '\n') + 1
+	start := l.position
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
+	l.comments = append(l.comments, Comment{
+		Text:     strings.TrimRight(l.input[start:l.position], " \t\r"),
+		Line:     l.line,
+		Trailing: strings.TrimSpace(l.input[lineStart:start]) != "",
+	})
 }
 
 func (l *Lexer) isDigit(ch byte) bool {
@@ -155,36 +222,82 @@ func (l *Lexer) isDigit(ch byte) bool {
 
 func (l *Lexer) readNumber() string {
 	position := l.position
-	for l.isDigit(l.ch) || l.ch == '.' {
+	for l.isDigit(l.ch) || (l.ch == '.' && l.peekChar() != '.') {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString() string {
-	out := ""
-	isContinue := true
-	for isContinue {
-		l.readChar()
-		switch l.ch {
-		case '\\':
-			switch l.peekChar() {
-			case '"':
-				out = out + string('"')
-			case 'n':
-				out = out + string('\n')
-			case 't':
-				out = out + string('\t')
-			}
-			l.readChar()
-		case '"':
-			isContinue = false
-		case 0:
-			// TODO error lexer
-			isContinue = false
-		default:
-			out = out + string(l.ch)
-		}
+// readString reads the string in double quotes, the string containing `${...}` is read as a template
+// which is split and unescaped by the parser (see SplitTemplate)
+func (l *Lexer) readString() tokens.Token {
+	tok := l.newToken(tokens.STRING, "")
+	start := l.position + 1
+	end := scanString(l.input, start)
+	if end < 0 {
+		l.error("unterminated string")
+		end = len(l.input)
+	}
+	raw := l.input[start:end]
+	tok.Raw = l.quoted(start, end)
+	l.line += strings.Count(raw, "\n")
+	l.moveTo(end)
+
+	if _, interpolations := SplitTemplate(raw); len(interpolations) != 0 {
+		tok.Type = tokens.TEMPLATE
+		tok.Literal = raw
+		return tok
+	}
+	value, err := Unescape(raw)
+	if err != nil {
+		l.errors = append(l.errors, fmt.Sprintf("%v on line %d", err, tok.LineNumber))
+	}
+	tok.Literal = value
+	return tok
+}
+
+// readRawString reads the string in backticks as is, it can be multiline and has no escape sequences
+func (l *Lexer) readRawString() tokens.Token {
+	tok := l.newToken(tokens.STRING, "")
+	start := l.position + 1
+	end := strings.IndexByte(l.input[start:], '`')
+	if end < 0 {
+		l.error("unterminated raw string")
+		end = len(l.input)
+	} else {
+		end += start
+	}
+	tok.Literal = l.input[start:end]
+	tok.Raw = l.quoted(start, end)
+	l.line += strings.Count(tok.Literal, "\n")
+	l.moveTo(end)
+	return tok
+}
+
+// quoted returns the source of literal from the opening quote before start to the closing quote at end
+func (l *Lexer) quoted(start, end int) string {
+	if end < len(l.input) {
+		end++
 	}
-	return out
+	return l.input[start-1 : end]
+}
+
+// moveTo sets the current char to the given position
+func (l *Lexer) moveTo(position int) {
+	l.readPosition = position
+	l.readChar()
+}
+
+func (l *Lexer) error(message string) {
+	l.errors = append(l.errors, fmt.Sprintf("%s on line %d", message, l.line))
+}
+
+// Comments returns the comments read so far in the order of lines
+func (l *Lexer) Comments() []Comment {
+	return l.comments
+}
+
+// Errors returns the errors of malformed literals, the lexer reads such literals as far as possible
+func (l *Lexer) Errors() []string {
+	return l.errors
 }
@@ -291,3 +291,191 @@ func TestDottedIdentifiers(t *testing.T) {
 	}
 
 }
+
+func TestNextToken_UnicodeString(t *testing.T) {
+	input := `"привет, 世界\n"`
+
+	l := lexer.New(input, "non-file")
+
+	next := l.NextToken()
+	assert.EqualValues(t, tokens.STRING, next.Type)
+	assert.Equal(t, "привет, 世界\n", next.Literal)
+	assert.EqualValues(t, tokens.EOF, l.NextToken().Type)
+}
+
+func TestNextToken_StringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\"b"`, `a"b`},
+		{`"a\\b"`, `a\b`},
+		{`"\n\t\r"`, "\n\t\r"},
+		{`"\$\x41\u{44F}\u{1F600}"`, "$Aя😀"},
+		{"`raw \\n ${x}\n\"line\"`", "raw \\n ${x}\n\"line\""},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		next := l.NextToken()
+		assert.EqualValues(t, tokens.STRING, next.Type, test.input)
+		assert.Equal(t, test.expected, next.Literal, test.input)
+		assert.Empty(t, l.Errors(), test.input)
+	}
+}
+
+func TestNextToken_Template(t *testing.T) {
+	input := `"a ${ b["}"] } c \${d}" + "${ "x${y}" }"
+	e`
+	tests := []struct {
+		expectedType    tokens.TokenType
+		expectedLiteral string
+	}{
+		{tokens.TEMPLATE, `a ${ b["}"] } c \${d}`},
+		{tokens.PLUS, "+"},
+		{tokens.TEMPLATE, `${ "x${y}" }`},
+		{tokens.IDENT, "e"},
+		{tokens.EOF, ""},
+	}
+
+	l := lexer.New(input, "non-file")
+
+	for _, v := range tests {
+		next := l.NextToken()
+		assert.Equal(t, v.expectedLiteral, next.Literal)
+		assert.Equal(t, v.expectedType, next.Type)
+	}
+	assert.Empty(t, l.Errors())
+}
+
+func TestNextToken_StringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"abc`, "unterminated string on line 1"},
+		{"\n`abc", "unterminated raw string on line 2"},
+		{`"a ${b"`, "unterminated string on line 1"},
+		{`"\q"`, `invalid escape sequence \q on line 1`},
+		{`"\x4"`, `invalid escape sequence \x4 on line 1`},
+		{`"\xZZ"`, `invalid escape sequence \xZZ on line 1`},
+		{`"\u41"`, `invalid unicode escape sequence, expected \u{...} on line 1`},
+		{`"\u{110000}"`, `invalid unicode escape sequence \u{110000} on line 1`},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		for l.NextToken().Type != tokens.EOF {
+		}
+		assert.Equal(t, []string{test.expected}, l.Errors(), test.input)
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	texts, interpolations := lexer.SplitTemplate(`a\${b}${ c + "}" }d${e}`)
+	assert.Equal(t, []string{`a\${b}`, "d", ""}, texts)
+	assert.Equal(t, []string{` c + "}" `, "e"}, interpolations)
+}
+
+func TestNextToken_Ellipsis(t *testing.T) {
+	input := `[a, ...rest] a.b .. 0..10 1.5`
+	tests := []struct {
+		expectedType    tokens.TokenType
+		expectedLiteral string
+	}{
+		{tokens.LBRACKET, "["},
+		{tokens.IDENT, "a"},
+		{tokens.COMMA, ","},
+		{tokens.ELLIPSIS, "..."},
+		{tokens.IDENT, "rest"},
+		{tokens.RBRACKET, "]"},
+		{tokens.IDENT, "a"},
+		{tokens.DOT, "."},
+		{tokens.IDENT, "b"},
+		{tokens.RANGE, ".."},
+		{tokens.INT, "0"},
+		{tokens.RANGE, ".."},
+		{tokens.INT, "10"},
+		{tokens.DOUBLE, "1.5"},
+		{tokens.EOF, ""},
+	}
+
+	l := lexer.New(input, "non-file")
+
+	for _, v := range tests {
+		next := l.NextToken()
+		assert.Equal(t, v.expectedLiteral, next.Literal)
+		assert.Equal(t, v.expectedType, next.Type)
+	}
+}
+
+func TestNextToken_NullSafety(t *testing.T) {
+	input := `a?.b?["k"] ?? null f?.() ?`
+	tests := []struct {
+		expectedType    tokens.TokenType
+		expectedLiteral string
+	}{
+		{tokens.IDENT, "a"},
+		{tokens.OPTIONAL_DOT, "?."},
+		{tokens.IDENT, "b"},
+		{tokens.OPTIONAL_LBRACKET, "?["},
+		{tokens.STRING, "k"},
+		{tokens.RBRACKET, "]"},
+		{tokens.NULLISH, "??"},
+		{tokens.NULL, "null"},
+		{tokens.IDENT, "f"},
+		{tokens.OPTIONAL_DOT, "?."},
+		{tokens.LPAREN, "("},
+		{tokens.RPAREN, ")"},
+		{tokens.ILLEGAL, "?"},
+		{tokens.EOF, ""},
+	}
+
+	l := lexer.New(input, "non-file")
+
+	for _, v := range tests {
+		next := l.NextToken()
+		assert.Equal(t, v.expectedLiteral, next.Literal)
+		assert.Equal(t, v.expectedType, next.Type)
+	}
+}
+
+func TestNextToken_Comments(t *testing.T) {
+	input := `// header
+let a = 10 / 2; // half
+// "not a string"
+let s = "a\n${a}" + ` + "`raw`" + `;`
+	tests := []struct {
+		expectedType    tokens.TokenType
+		expectedLiteral string
+		expectedRaw     string
+	}{
+		{tokens.LET, "let", ""},
+		{tokens.IDENT, "a", ""},
+		{tokens.ASSIGN, "=", ""},
+		{tokens.INT, "10", ""},
+		{tokens.SLASH, "/", ""},
+		{tokens.INT, "2", ""},
+		{tokens.SEMICOLON, ";", ""},
+		{tokens.LET, "let", ""},
+		{tokens.IDENT, "s", ""},
+		{tokens.ASSIGN, "=", ""},
+		{tokens.TEMPLATE, `a\n${a}`, `"a\n${a}"`},
+		{tokens.PLUS, "+", ""},
+		{tokens.STRING, "raw", "`raw`"},
+		{tokens.SEMICOLON, ";", ""},
+		{tokens.EOF, "", ""},
+	}
+
+	l := lexer.New(input, "non-file")
+
+	for _, v := range tests {
+		next := l.NextToken()
+		assert.Equal(t, v.expectedLiteral, next.Literal)
+		assert.Equal(t, v.expectedType, next.Type)
+		assert.Equal(t, v.expectedRaw, next.Raw)
+	}
+	assert.Equal(t, []lexer.Comment{
+		{Text: "// header", Line: 1},
+		{Text: "// half", Line: 2, Trailing: true},
+		{Text: `// "not a string"`, Line: 3},
+	}, l.Comments())
+}
@@ -0,0 +1,132 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Unescape replaces escape sequences of a string literal: \" \\ \n \t \r \$ \xHH and \u{H...}
+func Unescape(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	out := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("unterminated escape sequence")
+		}
+		switch s[i] {
+		case '"', '\\', '$':
+			out.WriteByte(s[i])
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case 'x':
+			if i+3 > len(s) {
+				return "", fmt.Errorf("invalid escape sequence \\x%s", s[i+1:])
+			}
+			value, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape sequence \\x%s", s[i+1:i+3])
+			}
+			out.WriteByte(byte(value))
+			i += 2
+		case 'u':
+			end := strings.IndexByte(s[i:], '}')
+			if i+1 == len(s) || s[i+1] != '{' || end < 0 {
+				return "", fmt.Errorf("invalid unicode escape sequence, expected \\u{...}")
+			}
+			digits := s[i+2 : i+end]
+			value, err := strconv.ParseUint(digits, 16, 32)
+			if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(value)) {
+				return "", fmt.Errorf("invalid unicode escape sequence \\u{%s}", digits)
+			}
+			out.WriteRune(rune(value))
+			i += end
+		default:
+			return "", fmt.Errorf("invalid escape sequence \\%c", s[i])
+		}
+	}
+	return out.String(), nil
+}
+
+// SplitTemplate splits the raw content of a string literal into text parts and `${...}` interpolations,
+// the texts are still escaped and there is always one more text than interpolations
+func SplitTemplate(raw string) (texts []string, interpolations []string) {
+	last := 0
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\':
+			i++
+		case raw[i] == '$' && i+1 < len(raw) && raw[i+1] == '{':
+			end := scanInterpolation(raw, i+2)
+			if end < 0 {
+				return append(texts, raw[last:]), interpolations
+			}
+			texts = append(texts, raw[last:i])
+			interpolations = append(interpolations, raw[i+2:end])
+			last = end + 1
+			i = end
+		}
+	}
+	return append(texts, raw[last:]), interpolations
+}
+
+// scanString returns the position of the closing double quote of a string started at pos, or -1
+func scanString(input string, pos int) int {
+	for i := pos; i < len(input); i++ {
+		switch {
+		case input[i] == '\\':
+			i++
+		case input[i] == '"':
+			return i
+		case input[i] == '$' && i+1 < len(input) && input[i+1] == '{':
+			end := scanInterpolation(input, i+2)
+			if end < 0 {
+				return -1
+			}
+			i = end
+		}
+	}
+	return -1
+}
+
+// scanInterpolation returns the position of the brace closing the interpolation started at pos, or -1.
+// Braces of nested blocks and strings inside the expression are skipped
+func scanInterpolation(input string, pos int) int {
+	depth := 0
+	for i := pos; i < len(input); i++ {
+		switch input[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case '"':
+			end := scanString(input, i+1)
+			if end < 0 {
+				return -1
+			}
+			i = end
+		case '`':
+			end := strings.IndexByte(input[i+1:], '`')
+			if end < 0 {
+				return -1
+			}
+			i += end + 1
+		}
+	}
+	return -1
+}
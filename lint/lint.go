@@ -0,0 +1,228 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/lexer"
+	"github.com/YReshetko/rash-lang/loaders"
+	"github.com/YReshetko/rash-lang/parser"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The rules reported by the linter
+const (
+	Undefined      = "undefined"       // identifiers which are not declared, builtin or imported
+	UnusedVariable = "unused-variable" // local variables which are never read
+	UnusedImport   = "unused-import"   // module aliases and imported names which are never used
+	Shadow         = "shadow"          // declarations which hide the names of outer scopes
+	Unreachable    = "unreachable"     // statements after return, break or continue
+	Arity          = "arity"           // calls of script functions with wrong number of arguments
+	Comparison     = "comparison"      // comparisons which are always true or false
+	Syntax         = "syntax"          // parser errors, the rule can't be disabled
+)
+
+// Rules is the list of rules which can be enabled or disabled
+var Rules = []string{Undefined, UnusedVariable, UnusedImport, Shadow, Unreachable, Arity, Comparison}
+
+// Diagnostic is a problem found by the linter
+type Diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", d.File, d.Line, d.Message, d.Rule)
+}
+
+// Config enables or disables the rules by name, the rules which are not listed are enabled
+type Config struct {
+	Rules map[string]bool `json:"rules"`
+}
+
+// LoadConfig reads the JSON config: {"rules": {"shadow": false}}
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("unable to read lint config %s: %v", path, err)
+	}
+	for rule := range config.Rules {
+		if err := config.Set(rule, config.Rules[rule]); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// Set enables or disables the rule
+func (c *Config) Set(rule string, enabled bool) error {
+	if !isRule(rule) {
+		return fmt.Errorf("unknown lint rule %s, expected one of %s", rule, strings.Join(Rules, ", "))
+	}
+	if c.Rules == nil {
+		c.Rules = map[string]bool{}
+	}
+	c.Rules[rule] = enabled
+	return nil
+}
+
+// Enabled checks the rule is reported
+func (c *Config) Enabled(rule string) bool {
+	if c == nil {
+		return true
+	}
+	enabled, ok := c.Rules[rule]
+	return !ok || enabled
+}
+
+func isRule(rule string) bool {
+	for _, r := range Rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// Linter reports the mistakes in scripts which are found by the AST without evaluation
+type Linter struct {
+	// Loader resolves and reads the included modules to find the names imported by wildcard
+	Loader *loaders.Loader
+
+	config      *Config
+	diagnostics []Diagnostic
+
+	scope     *scope
+	calls     []call // calls of script functions, checked when all the assignments are known
+	functions int    // the depth of function literals being walked
+	unknown   bool   // a wildcard import can't be resolved, so the undefined names may be imported
+}
+
+// New creates the linter, all the rules are reported if config is nil
+func New(config *Config) *Linter {
+	return &Linter{Loader: loaders.Default, config: config}
+}
+
+// LintFile reports the problems of script, the included modules are not linted
+func (l *Linter) LintFile(path string) []Diagnostic {
+	src, err := l.Loader.ReadFile(path)
+	if err != nil {
+		return []Diagnostic{{File: path, Rule: Syntax, Message: fmt.Sprintf("unable to load script %s: %v", path, err)}}
+	}
+	return l.LintSource(src, path)
+}
+
+// LintSource reports the problems of script source, the syntax errors are reported if it can't be parsed
+func (l *Linter) LintSource(src []byte, path string) []Diagnostic {
+	p := parser.New(lexer.New(string(src), path))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		diagnostics := []Diagnostic{}
+		for _, msg := range p.Errors() {
+			diagnostics = append(diagnostics, parserDiagnostic(path, msg))
+		}
+		return diagnostics
+	}
+	return l.Lint(program)
+}
+
+// Lint reports the problems of parsed program sorted by line
+func (l *Linter) Lint(program *ast.Program) []Diagnostic {
+	l.diagnostics = []Diagnostic{}
+	l.calls = nil
+	l.unknown = false
+	l.program(program)
+	l.checkCalls()
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		return l.diagnostics[i].Line < l.diagnostics[j].Line
+	})
+	return l.diagnostics
+}
+
+// parserDiagnostic moves the line from the end of parser error to the diagnostic
+func parserDiagnostic(path, msg string) Diagnostic {
+	d := Diagnostic{File: path, Rule: Syntax, Message: msg}
+	if i := strings.LastIndex(msg, " on line "); i >= 0 {
+		if line, err := strconv.Atoi(msg[i+len(" on line "):]); err == nil {
+			d.Line = line
+			d.Message = msg[:i]
+		}
+	}
+	return d
+}
+
+// publicNames returns the names imported from the module by wildcard the same way as objects.Environment.PublicNames,
+// false is returned if the module can't be loaded
+func (l *Linter) publicNames(include *ast.IncludeDeclaration, visited map[string]bool) ([]string, bool) {
+	path, err := l.Loader.Resolve(include.Include.Value, include.Token.FileName)
+	if err != nil || visited[path] {
+		return nil, false
+	}
+	visited[path] = true
+	src, err := l.Loader.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	p := parser.New(lexer.New(string(src), path))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, false
+	}
+
+	names, exported, hasExports := []string{}, []string{}, false
+	for _, statement := range program.Statements {
+		export, isExport := statement.(*ast.ExportStatement)
+		if isExport {
+			statement = export.Statement
+			hasExports = true
+		}
+		var bound []string
+		switch s := statement.(type) {
+		case *ast.LetStatement:
+			bound = letNames(s)
+		case *ast.StructStatement:
+			bound = []string{s.Name.Value}
+		case *ast.DeclarationStatement:
+			inner, ok := s.Declaration.(*ast.IncludeDeclaration)
+			switch {
+			case !ok:
+			case inner.Wildcard:
+				if bound, ok = l.publicNames(inner, visited); !ok {
+					return nil, false
+				}
+			default:
+				bound = inner.Bound()
+			}
+		}
+		names = append(names, bound...)
+		if isExport {
+			exported = append(exported, bound...)
+		}
+	}
+	if hasExports {
+		return exported, true
+	}
+	public := []string{}
+	for _, name := range names {
+		if !strings.HasPrefix(name, "_") {
+			public = append(public, name)
+		}
+	}
+	return public, true
+}
+
+func letNames(s *ast.LetStatement) []string {
+	if s.Pattern != nil {
+		return ast.BoundNames(s.Pattern)
+	}
+	return []string{s.Name.Value}
+}
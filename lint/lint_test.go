@@ -0,0 +1,167 @@
+package lint_test
+
+import (
+	"encoding/json"
+	"github.com/YReshetko/rash-lang/lexer"
+	"github.com/YReshetko/rash-lang/lint"
+	"github.com/YReshetko/rash-lang/loaders"
+	"github.com/YReshetko/rash-lang/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func lintSource(t *testing.T, input string, config *lint.Config) []string {
+	p := parser.New(lexer.New(input, "test.rs"))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors(), input)
+
+	messages := []string{}
+	for _, d := range lint.New(config).Lint(program) {
+		messages = append(messages, d.String())
+	}
+	return messages
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "clean script",
+			input:    "let f = fn(a, b = 1) { let c = a + b; return c; }; println(f(1)); let g = fn() { h() }; let h = fn() { 1 };",
+			expected: []string{},
+		},
+		{
+			name:     "undefined identifier",
+			input:    "# http \"http.rs\";\nlet server1 = http.new_server(\"4000\");\nserver[\"start\"]();\nmissing = 1;",
+			expected: []string{"test.rs:3: undefined identifier server (undefined)", "test.rs:4: undefined identifier missing (undefined)"},
+		},
+		{
+			name:     "members are not identifiers",
+			input:    "let h = {\"a\": 1}; h.a; h.b(1).c[0]; h?.d;",
+			expected: []string{},
+		},
+		{
+			name:     "unused variables",
+			input:    "let top = 1;\nlet f = fn(param) {\n let a = 1;\n let _b = 2;\n let c = 3;\n c = 4;\n};\nf(1);",
+			expected: []string{"test.rs:3: a is declared but not used (unused-variable)", "test.rs:5: c is declared but not used (unused-variable)"},
+		},
+		{
+			name:     "unused imports",
+			input:    "# a \"a.rs\";\n# {x, y as z} from \"b.rs\";\nexport # c \"c.rs\";\n# {T} from \"t.rs\";\nlet p: T = z;",
+			expected: []string{"test.rs:1: a is imported but not used (unused-import)", "test.rs:2: x is imported but not used (unused-import)"},
+		},
+		{
+			name:     "shadowed names",
+			input:    "let x = 1;\nlet f = fn(x) {\n for (i, v in [x]) { let x = v + i; println(x); }\n};\nf(x);",
+			expected: []string{"test.rs:2: x shadows the declaration on line 1 (shadow)", "test.rs:3: x shadows the declaration on line 2 (shadow)"},
+		},
+		{
+			name:     "use before declaration",
+			input:    "print(q);\nlet q = 1;\nlet f = fn() {\n r = 2;\n let r = q;\n print(r);\n};\nf();",
+			expected: []string{"test.rs:1: undefined identifier q (undefined)", "test.rs:4: undefined identifier r (undefined)"},
+		},
+		{
+			name:     "destructured names are defined",
+			input:    "let [a, b] = [1, 2];\nlet {c, d: e} = {\"c\": 3, \"d\": 4};\nlet [p, ...q] = [5, 6];\nprint(a + b + c + e + p, q);\nlet f = fn() { let [x, ...y] = [1]; let {z} = {}; [x, y, z] };\nf();",
+			expected: []string{},
+		},
+		{
+			name:     "destructured names are used after declaration",
+			input:    "print(a);\nlet [a, ...b] = [1];\nlet {c} = {\"c\": b};\nprint(c);",
+			expected: []string{"test.rs:1: undefined identifier a (undefined)"},
+		},
+		{
+			name:     "outer declaration is used until the block declares the name",
+			input:    "let x = 1;\nif (true) {\n print(x);\n let x = 2;\n print(x);\n}",
+			expected: []string{"test.rs:4: x shadows the declaration on line 1 (shadow)"},
+		},
+		{
+			name:     "later declarations are not shadowed",
+			input:    "let f = fn(x) { x };\nlet g = fn() { let y = 1; y };\nf(g());\nlet x = 1;\nlet y = x;\nprint(y);",
+			expected: []string{},
+		},
+		{
+			name:     "unreachable code",
+			input:    "let f = fn() {\n return 1;\n println(2);\n println(3);\n};\nfor (let i = 0; i < 3; i = i + 1) { break;\n println(i); }\nf();",
+			expected: []string{"test.rs:3: unreachable code after return (unreachable)", "test.rs:7: unreachable code after break (unreachable)"},
+		},
+		{
+			name:  "wrong arity",
+			input: "let add = fn(a, b = 1) { a + b };\nadd();\nadd(1, 2, 3);\nadd(1, b: 2);\nadd(...[1, 2, 3]);\nlet f = fn(a, ...rest) { a };\nf();\nlet g = fn() { 1 };\ng = fn(a) { a };\ng(1);",
+			expected: []string{
+				"test.rs:2: wrong number of arguments to `add`; got=0, expected=1..2 (arity)",
+				"test.rs:3: wrong number of arguments to `add`; got=3, expected=1..2 (arity)",
+				"test.rs:7: wrong number of arguments to `f`; got=0, expected>=1 (arity)",
+			},
+		},
+		{
+			name:  "suspicious comparisons",
+			input: "let a = 1; let b = 2; let c = 3; let f = fn() { a };\na < b < c;\na == a;\na == [];\n1 == \"1\";\nnull != false;\na == b;\n1 == 1.0;\nf() == f();",
+			expected: []string{
+				"test.rs:2: a < b < c compares the boolean result of comparison, use && to chain comparisons (comparison)",
+				"test.rs:3: a == a compares a with itself (comparison)",
+				"test.rs:4: a == [] is always false, arrays, hashes and functions are compared by reference (comparison)",
+				"test.rs:5: 1 == \"1\" is always false, number is compared with string (comparison)",
+				"test.rs:6: null != false is always true, null is compared with boolean (comparison)",
+			},
+		},
+		{
+			name:     "match, select and struct bindings",
+			input:    "struct P { x; fn len(self) { self.x } }\nmatch (P(1)) { {x} if x > 0 => x, [h, ..._] => h, _ => 0 };\nlet ch = channel();\nselect { case v = recv(ch) { v } default {} }",
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, lintSource(t, test.input, nil), test.name)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	input := "let f = fn(x) { let y = 1; x; };\nf();"
+	config := &lint.Config{}
+	require.NoError(t, config.Set(lint.UnusedVariable, false))
+	assert.Equal(t, []string{"test.rs:2: wrong number of arguments to `f`; got=0, expected=1 (arity)"}, lintSource(t, input, config))
+
+	assert.EqualError(t, config.Set("typo", true), "unknown lint rule typo, expected one of undefined, unused-variable, unused-import, shadow, unreachable, arity, comparison")
+
+	path := filepath.Join(t.TempDir(), "rashlint.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"rules": {"arity": false}}`), 0644))
+	config, err := lint.LoadConfig(path)
+	require.NoError(t, err)
+	assert.False(t, config.Enabled(lint.Arity))
+	assert.True(t, config.Enabled(lint.Shadow))
+	assert.Equal(t, []string{"test.rs:1: y is declared but not used (unused-variable)"}, lintSource(t, input, config))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"rules": {"shadows": false}}`), 0644))
+	_, err = lint.LoadConfig(path)
+	assert.Error(t, err)
+}
+
+func TestLintFile(t *testing.T) {
+	l := lint.New(nil)
+	l.Loader = loaders.New(fstest.MapFS{
+		"main.rs":   {Data: []byte("# * from \"lib.rs\";\nprintln(greet(name));\nprintln(_secret);\n")},
+		"lib.rs":    {Data: []byte("export let greet = fn(n) { n };\nexport # * from \"names.rs\";\nlet _secret = 1;\n")},
+		"names.rs":  {Data: []byte("let name = \"rash\";\n")},
+		"broken.rs": {Data: []byte("let a = ;\n")},
+		"other.rs":  {Data: []byte("# * from \"missing.rs\";\nprintln(anything);\n")},
+	})
+
+	diagnostics := l.LintFile("main.rs")
+	assert.Equal(t, []lint.Diagnostic{{File: "main.rs", Line: 3, Rule: lint.Undefined, Message: "undefined identifier _secret"}}, diagnostics)
+
+	out, err := json.Marshal(diagnostics)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"file": "main.rs", "line": 3, "rule": "undefined", "message": "undefined identifier _secret"}]`, string(out))
+
+	assert.Equal(t, []lint.Diagnostic{{File: "broken.rs", Line: 1, Rule: lint.Syntax, Message: "no prefix parse functions found for ;"}}, l.LintFile("broken.rs"))
+	assert.Empty(t, l.LintFile("other.rs"))
+}
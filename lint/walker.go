@@ -0,0 +1,598 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/evaluator"
+	"github.com/YReshetko/rash-lang/format"
+	"github.com/YReshetko/rash-lang/tokens"
+	"strings"
+)
+
+type kind int
+
+const (
+	variable  kind = iota // let, const and struct, it's reported if unused in a block
+	parameter             // parameter, loop variable, match or select binding
+	imported              // module alias or selectively imported name, it's reported if unused
+	wildcard              // name imported by wildcard
+)
+
+type declaration struct {
+	token    tokens.Token
+	kind     kind
+	reads    int
+	exported bool
+	assigned bool                 // the variable is reassigned or redeclared, so the function it holds is unknown
+	function *ast.FunctionLiteral // the function literal the variable is declared with
+	defined  bool                 // the declaration statement is walked, parameters and bindings are defined at once
+}
+
+// scope holds the declarations of block, the names declared by let statements are known in the whole block,
+// so the functions may refer to the variables declared after them. The same function may use a name only
+// after its declaration
+type scope struct {
+	names    map[string]*declaration
+	outer    *scope
+	function int // the depth of function literals the block belongs to
+}
+
+type call struct {
+	name        string
+	declaration *declaration
+	expression  *ast.CallExpression
+}
+
+func (l *Linter) report(rule string, token tokens.Token, format string, args ...interface{}) {
+	if !l.config.Enabled(rule) {
+		return
+	}
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		File:    token.FileName,
+		Line:    token.LineNumber,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *Linter) openScope() {
+	l.scope = &scope{names: map[string]*declaration{}, outer: l.scope, function: l.functions}
+}
+
+// closeScope reports the unused imports and the unused variables of blocks, top level variables may be used
+// by the scripts including the module
+func (l *Linter) closeScope() {
+	for name, d := range l.scope.names {
+		switch {
+		case d.reads != 0 || d.exported || strings.HasPrefix(name, "_"):
+		case d.kind == imported:
+			l.report(UnusedImport, d.token, "%s is imported but not used", name)
+		case d.kind == variable && l.scope.outer != nil:
+			l.report(UnusedVariable, d.token, "%s is declared but not used", name)
+		}
+	}
+	l.scope = l.scope.outer
+}
+
+// declare adds the name to the current scope and reports the declaration which hides the name of outer scope,
+// only the outer declarations which are already walked are hidden
+func (l *Linter) declare(name string, token tokens.Token, k kind) *declaration {
+	if d, ok := l.scope.names[name]; ok {
+		d.assigned = true
+		return d
+	}
+	if !strings.HasPrefix(name, "_") {
+		for s := l.scope.outer; s != nil; s = s.outer {
+			if outer, ok := s.names[name]; ok && outer.defined {
+				l.report(Shadow, token, "%s shadows the declaration on line %d", name, outer.token.LineNumber)
+				break
+			}
+		}
+	}
+	d := &declaration{token: token, kind: k, defined: k == parameter}
+	l.scope.names[name] = d
+	return d
+}
+
+// lookup finds the declaration the name refers to, the declarations of the same function which are not walked yet
+// are skipped as the name is bound to them later, nested functions see all the names of the block
+func (l *Linter) lookup(name string) *declaration {
+	for s := l.scope; s != nil; s = s.outer {
+		if d, ok := s.names[name]; ok && (d.defined || s.function != l.functions) {
+			return d
+		}
+	}
+	return nil
+}
+
+// use marks the identifier as read, the identifier which is not declared must be a builtin
+func (l *Linter) use(ident *ast.Identifier) *declaration {
+	d := l.lookup(ident.Value)
+	switch {
+	case d != nil:
+		d.reads++
+	case !evaluator.IsBuiltin(ident.Value) && !l.unknown:
+		l.report(Undefined, ident.Token, "undefined identifier %s", ident.Value)
+	}
+	return d
+}
+
+func (l *Linter) program(program *ast.Program) {
+	l.scope = nil
+	l.openScope()
+	l.statements(program.Statements)
+	l.closeScope()
+}
+
+func (l *Linter) block(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+	l.openScope()
+	l.statements(block.Statements)
+	l.closeScope()
+}
+
+// statements declares the names of the block and walks the statements, the first statement after
+// return, break or continue is reported as unreachable
+func (l *Linter) statements(statements []ast.Statement) {
+	for _, statement := range statements {
+		l.hoist(statement)
+	}
+	reported := false
+	for i, statement := range statements {
+		l.statement(statement)
+		if i+1 < len(statements) && !reported && leaves(statement) {
+			l.report(Unreachable, statementToken(statements[i+1]), "unreachable code after %s", statement.TokenLiteral())
+			reported = true
+		}
+	}
+}
+
+// hoist declares the names bound by the statement in the current scope
+func (l *Linter) hoist(statement ast.Statement) {
+	switch s := statement.(type) {
+	case *ast.LetStatement:
+		for _, name := range letNames(s) {
+			l.declare(name, s.Token, variable)
+		}
+	case *ast.StructStatement:
+		l.declare(s.Name.Value, s.Name.Token, variable)
+	case *ast.DeclarationStatement:
+		include, ok := s.Declaration.(*ast.IncludeDeclaration)
+		if !ok {
+			return
+		}
+		if !include.Wildcard {
+			for _, name := range include.Bound() {
+				l.declare(name, include.Token, imported)
+			}
+			return
+		}
+		names, ok := l.publicNames(include, map[string]bool{})
+		if !ok {
+			l.unknown = true
+		}
+		for _, name := range names {
+			l.declare(name, include.Token, wildcard)
+		}
+	case *ast.ExportStatement:
+		l.hoist(s.Statement)
+		for _, name := range exportedNames(s.Statement) {
+			l.scope.names[name].exported = true
+		}
+	}
+}
+
+func exportedNames(statement ast.Statement) []string {
+	switch s := statement.(type) {
+	case *ast.LetStatement:
+		return letNames(s)
+	case *ast.StructStatement:
+		return []string{s.Name.Value}
+	case *ast.DeclarationStatement:
+		if include, ok := s.Declaration.(*ast.IncludeDeclaration); ok && !include.Wildcard {
+			return include.Bound()
+		}
+	}
+	return nil
+}
+
+// leaves checks the statement leaves the block
+func leaves(statement ast.Statement) bool {
+	switch statement.(type) {
+	case *ast.ReturnStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	}
+	return false
+}
+
+func statementToken(statement ast.Statement) tokens.Token {
+	switch s := statement.(type) {
+	case *ast.LetStatement:
+		return s.Token
+	case *ast.ReturnStatement:
+		return s.Token
+	case *ast.BreakStatement:
+		return s.Token
+	case *ast.ContinueStatement:
+		return s.Token
+	case *ast.ExpressionStatement:
+		return s.Token
+	case *ast.DeclarationStatement:
+		return s.Token
+	case *ast.BlockStatement:
+		return s.Token
+	case *ast.StructStatement:
+		return s.Token
+	case *ast.ExportStatement:
+		return s.Token
+	}
+	return tokens.Token{}
+}
+
+func (l *Linter) statement(statement ast.Statement) {
+	switch s := statement.(type) {
+	case *ast.LetStatement:
+		l.let(s)
+	case *ast.ReturnStatement:
+		l.expression(s.Value)
+	case *ast.ExpressionStatement:
+		l.expression(s.Expression)
+	case *ast.BlockStatement:
+		l.block(s)
+	case *ast.StructStatement:
+		l.scope.names[s.Name.Value].defined = true
+		for _, field := range s.Fields {
+			l.expression(field.Value)
+		}
+		for _, method := range s.Methods {
+			l.function(method.Function)
+		}
+	case *ast.DeclarationStatement:
+		l.define(s)
+	case *ast.ExportStatement:
+		l.statement(s.Statement)
+	}
+}
+
+// define marks the names bound by include declaration as walked
+func (l *Linter) define(s *ast.DeclarationStatement) {
+	include, ok := s.Declaration.(*ast.IncludeDeclaration)
+	if !ok {
+		return
+	}
+	for _, d := range l.scope.names {
+		if d.token == include.Token {
+			d.defined = true
+		}
+	}
+}
+
+// let walks the value and remembers the function literal the variable is declared with to check the calls
+func (l *Linter) let(s *ast.LetStatement) {
+	l.annotation(s.Type)
+	l.expression(s.Value)
+	if s.Name == nil {
+		for _, name := range letNames(s) {
+			d := l.scope.names[name]
+			d.assigned = d.assigned || d.defined
+			d.defined = true
+		}
+		return
+	}
+	d := l.scope.names[s.Name.Value]
+	if d.defined {
+		d.assigned = true
+		return
+	}
+	d.defined = true
+	if fn, ok := s.Value.(*ast.FunctionLiteral); ok {
+		d.function = fn
+	}
+}
+
+// annotation marks the struct names used as types, the other type names are not identifiers
+func (l *Linter) annotation(t *ast.TypeAnnotation) {
+	if t == nil {
+		return
+	}
+	if d := l.lookup(t.Name); d != nil {
+		d.reads++
+	}
+	for _, parameter := range t.Parameters {
+		l.annotation(parameter)
+	}
+	for _, alternative := range t.Union {
+		l.annotation(alternative)
+	}
+	l.annotation(t.Result)
+}
+
+func (l *Linter) expression(expression ast.Expression) {
+	switch e := expression.(type) {
+	case *ast.Identifier:
+		l.use(e)
+	case *ast.LetStatement:
+		for _, name := range letNames(e) {
+			l.declare(name, e.Token, variable)
+		}
+		l.let(e)
+	case *ast.InfixExpression:
+		l.infix(e)
+	case *ast.MultipleAssignment:
+		for _, value := range e.Values {
+			l.expression(value)
+		}
+		for _, target := range e.Targets {
+			l.assign(target)
+		}
+	case *ast.PrefixExpression:
+		l.expression(e.Right)
+	case *ast.TemplateLiteral:
+		for _, part := range e.Parts {
+			l.expression(part)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range e.Elements {
+			l.expression(element)
+		}
+	case *ast.HashLiteral:
+		for _, key := range e.Keys {
+			l.expression(key)
+			l.expression(e.Pairs[key])
+		}
+	case *ast.IfExpression:
+		l.expression(e.Condition)
+		l.block(e.Consequence)
+		l.block(e.Alternative)
+	case *ast.ForExpression:
+		l.openScope()
+		l.expression(e.Initial)
+		l.expression(e.Condition)
+		l.expression(e.Complete)
+		l.block(e.Body)
+		l.closeScope()
+	case *ast.ForInExpression:
+		l.expression(e.Iterable)
+		l.openScope()
+		if e.Key != nil {
+			l.declare(e.Key.Value, e.Key.Token, parameter)
+		}
+		l.declare(e.Value.Value, e.Value.Token, parameter)
+		l.block(e.Body)
+		l.closeScope()
+	case *ast.MatchExpression:
+		l.expression(e.Value)
+		for _, arm := range e.Arms {
+			l.openScope()
+			for _, name := range ast.BoundNames(arm.Pattern) {
+				if name != "_" {
+					l.declare(name, arm.Token, parameter)
+				}
+			}
+			l.expression(arm.Guard)
+			l.block(arm.Body)
+			l.closeScope()
+		}
+	case *ast.SelectExpression:
+		for _, c := range e.Cases {
+			l.expression(c.Operation)
+			l.openScope()
+			if c.Name != nil {
+				l.declare(c.Name.Value, c.Name.Token, parameter)
+			}
+			l.block(c.Body)
+			l.closeScope()
+		}
+		l.block(e.Default)
+	case *ast.FunctionLiteral:
+		l.function(e)
+	case *ast.CallExpression:
+		l.call(e)
+	case *ast.IndexExpression:
+		l.expression(e.Left)
+		l.expression(e.Index)
+	case *ast.SliceExpression:
+		l.expression(e.Left)
+		l.expression(e.Start)
+		l.expression(e.End)
+	case *ast.AwaitExpression:
+		l.expression(e.Value)
+	case *ast.SpawnExpression:
+		l.expression(e.Value)
+	case *ast.SpreadExpression:
+		l.expression(e.Value)
+	}
+}
+
+func (l *Linter) infix(e *ast.InfixExpression) {
+	switch e.Operator {
+	case ".", "?.":
+		l.expression(e.Left)
+		l.member(e.Right)
+	case "=":
+		l.expression(e.Right)
+		l.assign(e.Left)
+	default:
+		l.expression(e.Left)
+		l.expression(e.Right)
+		l.compare(e)
+	}
+}
+
+// member walks the right side of dotted expression, the member names are not identifiers of the scope
+func (l *Linter) member(e ast.Expression) {
+	switch n := e.(type) {
+	case *ast.Identifier:
+	case *ast.CallExpression:
+		l.member(n.Function)
+		l.arguments(n)
+	case *ast.IndexExpression:
+		l.member(n.Left)
+		l.expression(n.Index)
+	case *ast.SliceExpression:
+		l.member(n.Left)
+		l.expression(n.Start)
+		l.expression(n.End)
+	default:
+		l.expression(e)
+	}
+}
+
+// assign marks the assigned variable, the variable isn't read by assignment
+func (l *Linter) assign(target ast.Expression) {
+	ident, ok := target.(*ast.Identifier)
+	if !ok {
+		l.expression(target)
+		return
+	}
+	d := l.lookup(ident.Value)
+	if d == nil {
+		if !l.unknown {
+			l.report(Undefined, ident.Token, "undefined identifier %s", ident.Value)
+		}
+		return
+	}
+	d.assigned = true
+}
+
+func (l *Linter) function(fn *ast.FunctionLiteral) {
+	l.functions++
+	defer func() { l.functions-- }()
+	l.openScope()
+	for i, param := range fn.Parameters {
+		if d, ok := fn.Defaults[i]; ok {
+			l.expression(d)
+		}
+		l.annotation(fn.Types[i])
+		names := []string{param.Value}
+		if pattern, ok := fn.Patterns[i]; ok {
+			names = ast.BoundNames(pattern)
+		}
+		for _, name := range names {
+			l.declare(name, param.Token, parameter)
+		}
+	}
+	if fn.Rest != nil {
+		l.declare(fn.Rest.Value, fn.Rest.Token, parameter)
+	}
+	l.annotation(fn.Result)
+	l.block(fn.Body)
+	l.closeScope()
+}
+
+// call walks the call and remembers the call of script function to check the number of arguments
+func (l *Linter) call(e *ast.CallExpression) {
+	if ident, ok := e.Function.(*ast.Identifier); ok {
+		if d := l.use(ident); d != nil {
+			l.calls = append(l.calls, call{name: ident.Value, declaration: d, expression: e})
+		}
+	} else {
+		l.expression(e.Function)
+	}
+	l.arguments(e)
+}
+
+func (l *Linter) arguments(e *ast.CallExpression) {
+	for _, argument := range e.Arguments {
+		l.expression(argument)
+	}
+	for _, named := range e.Named {
+		l.expression(named.Value)
+	}
+}
+
+// checkCalls reports the calls with wrong number of arguments to the functions which are never reassigned
+func (l *Linter) checkCalls() {
+	for _, c := range l.calls {
+		fn := c.declaration.function
+		if fn == nil || c.declaration.assigned || hasSpread(c.expression) {
+			continue
+		}
+		required := len(fn.Parameters) - len(fn.Defaults)
+		got := len(c.expression.Arguments) + len(c.expression.Named)
+		switch {
+		case fn.Rest != nil && got < required:
+			l.report(Arity, c.expression.Token, "wrong number of arguments to `%s`; got=%d, expected>=%d", c.name, got, required)
+		case fn.Rest != nil:
+		case required == len(fn.Parameters) && got != required:
+			l.report(Arity, c.expression.Token, "wrong number of arguments to `%s`; got=%d, expected=%d", c.name, got, required)
+		case got < required || got > len(fn.Parameters):
+			l.report(Arity, c.expression.Token, "wrong number of arguments to `%s`; got=%d, expected=%d..%d", c.name, got, required, len(fn.Parameters))
+		}
+	}
+}
+
+func hasSpread(e *ast.CallExpression) bool {
+	for _, argument := range e.Arguments {
+		if _, ok := argument.(*ast.SpreadExpression); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// compare reports the comparisons which don't depend on the values or compare the boolean result of comparison
+func (l *Linter) compare(e *ast.InfixExpression) {
+	equality := e.Operator == "==" || e.Operator == "!="
+	if !equality && e.Operator != "<" && e.Operator != ">" {
+		return
+	}
+	switch {
+	case !equality && (isComparison(e.Left) || isComparison(e.Right)):
+		l.report(Comparison, e.Token, "%s compares the boolean result of comparison, use && to chain comparisons", format.Expression(e))
+	case pure(e.Left) && format.Expression(e.Left) == format.Expression(e.Right):
+		l.report(Comparison, e.Token, "%s compares %s with itself", format.Expression(e), format.Expression(e.Left))
+	case !equality:
+	case isReference(e.Left) || isReference(e.Right):
+		l.report(Comparison, e.Token, "%s is always %t, arrays, hashes and functions are compared by reference", format.Expression(e), e.Operator == "!=")
+	case literalType(e.Left) != "" && literalType(e.Right) != "" && literalType(e.Left) != literalType(e.Right):
+		l.report(Comparison, e.Token, "%s is always %t, %s is compared with %s", format.Expression(e), e.Operator == "!=", literalType(e.Left), literalType(e.Right))
+	}
+}
+
+func isComparison(e ast.Expression) bool {
+	infix, ok := e.(*ast.InfixExpression)
+	return ok && (infix.Operator == "<" || infix.Operator == ">" || infix.Operator == "==" || infix.Operator == "!=")
+}
+
+// pure checks the expression gives the same value each time it's evaluated
+func pure(e ast.Expression) bool {
+	switch n := e.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.DoubleLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NullLiteral:
+		return true
+	case *ast.InfixExpression:
+		return (n.Operator == "." || n.Operator == "?.") && pure(n.Left) && pure(n.Right)
+	case *ast.IndexExpression:
+		return pure(n.Left) && pure(n.Index)
+	}
+	return false
+}
+
+func isReference(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.ArrayLiteral, *ast.HashLiteral, *ast.FunctionLiteral:
+		return true
+	}
+	return false
+}
+
+// literalType returns the type of literal, integers and doubles are numbers as they are compared by value
+func literalType(e ast.Expression) string {
+	switch n := e.(type) {
+	case *ast.IntegerLiteral, *ast.DoubleLiteral:
+		return "number"
+	case *ast.StringLiteral, *ast.TemplateLiteral:
+		return "string"
+	case *ast.BooleanLiteral:
+		return "boolean"
+	case *ast.NullLiteral:
+		return "null"
+	case *ast.PrefixExpression:
+		if n.Operator == "-" && literalType(n.Right) == "number" {
+			return "number"
+		}
+	}
+	return ""
+}
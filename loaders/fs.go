@@ -0,0 +1,20 @@
+package loaders
+
+import (
+	"io/fs"
+	"os"
+)
+
+// OS is the filesystem of the operating system, unlike os.DirFS it accepts absolute paths and paths
+// relative to the working directory which go outside of it
+var OS fs.FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
@@ -5,33 +5,196 @@ import (
 	"github.com/YReshetko/rash-lang/evaluator"
 	"github.com/YReshetko/rash-lang/lexer"
 	"github.com/YReshetko/rash-lang/objects"
+	"github.com/YReshetko/rash-lang/packages"
 	"github.com/YReshetko/rash-lang/parser"
-	"io/ioutil"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
-func ScriptLoader(path string) (*objects.Environment, error) {
-	src, err := ioutil.ReadFile(path)
+// Loader resolves, reads and evaluates the scripts from the filesystem, it can be an embed.FS,
+// a zip archive or an in-memory fstest.MapFS. Each included module is evaluated once
+type Loader struct {
+	// Root is the project root, the included scripts which are not found next to the including script are searched
+	// in the root, in the vendored packages and then in the directories listed in RASH_PATH
+	Root string
+
+	fsys    fs.FS
+	modules map[string]*objects.Environment // environments of evaluated scripts by path
+	loading []string                        // chain of scripts being evaluated, it's used to report import cycles
+}
+
+// New creates the loader of scripts from the filesystem
+func New(fsys fs.FS) *Loader {
+	return &Loader{
+		fsys:    fsys,
+		modules: map[string]*objects.Environment{},
+	}
+}
+
+// Default loads the scripts from the OS filesystem relatively to the working directory
+var Default = New(OS)
+
+// ScriptLoader loads the included script by the default loader
+func ScriptLoader(path, from string) (*objects.Environment, error) {
+	return Default.Load(path, from)
+}
+
+// Resolve finds the included script by the default loader
+func Resolve(path, from string) (string, error) {
+	return Default.Resolve(path, from)
+}
+
+// SetMain starts the chain of loading scripts of the default loader
+func SetMain(path string) {
+	Default.SetMain(path)
+}
+
+// Reset forgets the modules evaluated by the default loader
+func Reset() {
+	Default.Reset()
+}
+
+// Run evaluates the main script, the scripts it includes are loaded by the loader as well.
+// The loader of the interpreter is restored when the script is evaluated.
+// The result may be an error object of the script evaluation
+func (l *Loader) Run(path string, environment *objects.Environment) (objects.Object, error) {
+	src, err := l.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load script %s due to %v", path, err)
+	}
+
+	p := parser.New(lexer.New(string(src), path))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("unable to evaluate script %s due to:\n %s", path, strings.Join(p.Errors(), ";\n"))
+	}
+
+	defer l.use()()
+	l.SetMain(path)
+	return evaluator.Execute(program, environment), nil
+}
+
+// use makes the loader load the scripts included by the interpreter and returns the function restoring the previous one
+func (l *Loader) use() (restore func()) {
+	previous := evaluator.ScriptLoader
+	evaluator.ScriptLoader = l.Load
+	return func() {
+		evaluator.ScriptLoader = previous
+	}
+}
+
+// Load resolves the script included by the script from, evaluates it once and returns its environment
+func (l *Loader) Load(path, from string) (*objects.Environment, error) {
+	resolved, err := l.Resolve(path, from)
+	if err != nil {
+		return nil, err
+	}
+	key := l.key(resolved)
+	if env, ok := l.modules[key]; ok {
+		return env, nil
+	}
+	if chain, ok := l.cycle(key); ok {
+		return nil, fmt.Errorf("import cycle: %s", strings.Join(append(chain, resolved), " -> "))
+	}
+
+	src, err := l.ReadFile(resolved)
 	if err != nil {
-		return nil, fmt.Errorf("unable to load included script %s due to %v", path, err)
+		return nil, fmt.Errorf("unable to load included script %s due to %v", resolved, err)
 	}
 
-	l := lexer.New(string(src), path)
-	p := parser.New(l)
+	lex := lexer.New(string(src), resolved)
+	p := parser.New(lex)
 
 	program := p.ParseProgram()
 
 	if len(p.Errors()) != 0 {
-		return nil, fmt.Errorf("unable to evaluate included script %s due to:\n %s", path, strings.Join(p.Errors(), ";\n"))
+		return nil, fmt.Errorf("unable to evaluate included script %s due to:\n %s", resolved, strings.Join(p.Errors(), ";\n"))
 	}
 
 	externalEnv := objects.NewEnvironment()
 
-	obj := evaluator.Eval(program, externalEnv)
+	l.loading = append(l.loading, resolved)
+	restore := l.use()
+	obj := evaluator.Evaluate(program, externalEnv)
+	restore()
+	l.loading = l.loading[:len(l.loading)-1]
 	if obj.Type() == objects.ERROR_OBJ {
 		errObj := obj.(*objects.Error)
 		return nil, fmt.Errorf("%s\nStackTrace:\n%s", errObj.Inspect(), strings.Join(errObj.Stack, ";\n"))
 	}
 
+	l.modules[key] = externalEnv
 	return externalEnv, nil
 }
+
+// ReadFile reads the script from the filesystem of loader
+func (l *Loader) ReadFile(path string) ([]byte, error) {
+	return fs.ReadFile(l.fsys, filepath.ToSlash(path))
+}
+
+// SetMain starts the chain of loading scripts from the script run by interpreter,
+// so the modules including the main script are reported as import cycle
+func (l *Loader) SetMain(path string) {
+	l.loading = []string{path}
+}
+
+// Reset forgets the evaluated modules, the next include evaluates the module again
+func (l *Loader) Reset() {
+	l.modules = map[string]*objects.Environment{}
+	l.loading = nil
+}
+
+// key identifies the module, the paths of OS filesystem are made absolute
+func (l *Loader) key(path string) string {
+	if l.fsys == OS {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+	}
+	return filepath.Clean(path)
+}
+
+// cycle returns the chain of loading scripts starting from the script being loaded again
+func (l *Loader) cycle(key string) ([]string, bool) {
+	for i, path := range l.loading {
+		if l.key(path) == key {
+			return append([]string{}, l.loading[i:]...), true
+		}
+	}
+	return nil, false
+}
+
+// Resolve finds the included script: absolute paths are used as is, relative paths are searched
+// next to the including script, in the project root, in the vendored packages and in the directories of RASH_PATH.
+// Absolute paths and RASH_PATH refer to the OS filesystem, so they are skipped by the loaders of other filesystems.
+// The package directory included by name resolves to its main.rs: # utils "utils"
+func (l *Loader) Resolve(path, from string) (string, error) {
+	if filepath.IsAbs(path) {
+		if l.fsys != OS {
+			return "", fmt.Errorf("unable to load included script %s: absolute paths are included only from the OS filesystem", path)
+		}
+		return path, nil
+	}
+
+	dirs := []string{filepath.Dir(from), l.Root, filepath.Join(l.Root, packages.Dir)}
+	if l.fsys == OS {
+		dirs = append(dirs, filepath.SplitList(os.Getenv("RASH_PATH"))...)
+	}
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, path)
+		info, err := fs.Stat(l.fsys, filepath.ToSlash(candidate))
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			candidate = filepath.Join(candidate, "main.rs")
+			if _, err := fs.Stat(l.fsys, filepath.ToSlash(candidate)); err != nil {
+				continue
+			}
+		}
+		return candidate, nil
+	}
+	return "", fmt.Errorf("unable to load included script %s: not found next to %s, in the project root, %s or RASH_PATH", path, from, packages.Dir)
+}
@@ -0,0 +1,147 @@
+package loaders_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"embed"
+	"errors"
+	"github.com/YReshetko/rash-lang/evaluator"
+	"github.com/YReshetko/rash-lang/loaders"
+	"github.com/YReshetko/rash-lang/objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+func TestMapFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.rs":                      {Data: []byte(`# {add} from "lib/math.rs"; # g "greet"; [add(1, 2), g.name]`)},
+		"lib/math.rs":                  {Data: []byte(`# h "helpers.rs"; export let add = fn(a, b) { h.twice(a + b) / 2 };`)},
+		"lib/helpers.rs":               {Data: []byte(`let twice = fn(x) { x * 2 };`)},
+		"rash_modules/greet/main.rs":   {Data: []byte(`let name = "greet";`)},
+		"cycle/a.rs":                   {Data: []byte(`# b "b.rs";`)},
+		"cycle/b.rs":                   {Data: []byte(`# a "a.rs";`)},
+		"errors/private.rs":            {Data: []byte(`# {twice} from "../lib/math.rs";`)},
+		"errors/escape.rs":             {Data: []byte(`# x "../../outside.rs";`)},
+		"rash_modules/greet/unused.rs": {Data: []byte(`let x = `)},
+	}
+
+	obj, err := loaders.New(fsys).Run("main.rs", objects.NewEnvironment())
+	require.NoError(t, err)
+	assert.Equal(t, "[3, greet]", obj.Inspect())
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"cycle/a.rs", "import cycle: cycle/a.rs -> cycle/b.rs -> cycle/a.rs"},
+		{"errors/private.rs", "undefined name twice in the module"},
+		{"errors/escape.rs", "unable to load included script ../../outside.rs: not found next to errors/escape.rs"},
+	}
+	for _, test := range tests {
+		obj, err := loaders.New(fsys).Run(test.path, objects.NewEnvironment())
+		require.NoError(t, err, test.path)
+		errObj, ok := obj.(*objects.Error)
+		require.True(t, ok, test.path)
+		assert.Contains(t, errObj.Message, test.expected, test.path)
+	}
+
+	_, err = loaders.New(fsys).Run("missing.rs", objects.NewEnvironment())
+	assert.EqualError(t, err, "unable to load script missing.rs due to open missing.rs: file does not exist")
+	_, err = loaders.New(fsys).Run("rash_modules/greet/unused.rs", objects.NewEnvironment())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to evaluate script rash_modules/greet/unused.rs")
+}
+
+func TestLoadOnce(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.rs":    {Data: []byte(`# a "counter.rs"; # b "./counter.rs"; a.count = a.count + 1; b.count`)},
+		"counter.rs": {Data: []byte(`let count = 0;`)},
+	}
+	obj, err := loaders.New(fsys).Run("main.rs", objects.NewEnvironment())
+	require.NoError(t, err)
+	assert.Equal(t, "1", obj.Inspect())
+}
+
+func TestLoaderIsRestored(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.rs":  {Data: []byte(`# u "util.rs"; u.value`)},
+		"util.rs":  {Data: []byte(`# c "const.rs"; let value = c.value;`)},
+		"const.rs": {Data: []byte(`let value = 42;`)},
+	}
+	previous := evaluator.ScriptLoader
+	defer func() { evaluator.ScriptLoader = previous }()
+	called := false
+	evaluator.ScriptLoader = func(path, from string) (*objects.Environment, error) {
+		called = true
+		return nil, errors.New("unexpected loader")
+	}
+
+	obj, err := loaders.New(fsys).Run("main.rs", objects.NewEnvironment())
+	require.NoError(t, err)
+	assert.Equal(t, "42", obj.Inspect())
+
+	env, err := loaders.New(fsys).Load("util.rs", "main.rs")
+	require.NoError(t, err)
+	value, ok := env.Get("value")
+	require.True(t, ok)
+	assert.Equal(t, "42", value.Inspect())
+
+	assert.False(t, called)
+	_, err = evaluator.ScriptLoader("util.rs", "main.rs")
+	assert.EqualError(t, err, "unexpected loader")
+}
+
+func TestResolveOutsideOfOS(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "lib.rs"), []byte(`let value = 1;`), 0644))
+	os.Setenv("RASH_PATH", dir)
+	defer os.Unsetenv("RASH_PATH")
+
+	loader := loaders.New(fstest.MapFS{"main.rs": {Data: []byte(`1`)}})
+	_, err := loader.Resolve("lib.rs", "main.rs")
+	assert.EqualError(t, err, "unable to load included script lib.rs: not found next to main.rs, in the project root, rash_modules or RASH_PATH")
+	_, err = loader.Resolve(filepath.Join(dir, "lib.rs"), "main.rs")
+	assert.EqualError(t, err, "unable to load included script "+filepath.Join(dir, "lib.rs")+": absolute paths are included only from the OS filesystem")
+
+	resolved, err := loaders.New(loaders.OS).Resolve("lib.rs", "main.rs")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "lib.rs"), resolved)
+}
+
+func TestZipFS(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"app/main.rs": `# u "util.rs"; u.value * 2`,
+		"app/util.rs": `let value = 21;`,
+	} {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	obj, err := loaders.New(archive).Run("app/main.rs", objects.NewEnvironment())
+	require.NoError(t, err)
+	assert.Equal(t, "42", obj.Inspect())
+}
+
+func TestEmbedFS(t *testing.T) {
+	fsys, err := fs.Sub(testdata, "testdata")
+	require.NoError(t, err)
+	obj, err := loaders.New(fsys).Run("main.rs", objects.NewEnvironment())
+	require.NoError(t, err)
+	assert.Equal(t, "hello embed", obj.Inspect())
+}
@@ -0,0 +1 @@
+export let greet = fn(name) { "hello " + name };
@@ -0,0 +1,2 @@
+# {greet} from "lib/greet.rs";
+greet("embed")
@@ -0,0 +1,394 @@
+package lsp
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/checker"
+	"github.com/YReshetko/rash-lang/evaluator"
+	"github.com/YReshetko/rash-lang/tokens"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxDepth limits the chain of modules re-exporting a name, so an import cycle doesn't loop
+const maxDepth = 16
+
+var (
+	pluginPackage  = regexp.MustCompile(`\b(?:eval|call)\(\s*"\w*$`)
+	pluginFunction = regexp.MustCompile(`\b(?:eval|call)\(\s*"(\w+)"\s*,\s*"\w*$`)
+	pluginCall     = regexp.MustCompile(`\b(?:eval|call)\(\s*"(\w+)"\s*,\s*"(\w+)"`)
+	memberAccess   = regexp.MustCompile(`(\w+)\??\.\w*$`)
+)
+
+// definition returns the location of declaration of the identifier, the module member or the included module
+func (s *Server) definition(doc *document, pos Position) interface{} {
+	if include := doc.includeAt(pos); include != nil {
+		if module, ok := s.module(include); ok {
+			return Location{URI: module.uri}
+		}
+		return nil
+	}
+	word, qualifier, _ := doc.wordAt(pos)
+	target, sym := s.resolve(doc, word, qualifier, pos.Line+1)
+	if sym == nil {
+		return nil
+	}
+	return Location{URI: target.uri, Range: target.nameRange(sym)}
+}
+
+// resolve finds the declaration of the identifier or module member, the names imported by selective
+// or wildcard includes are resolved to the declarations in the modules
+func (s *Server) resolve(doc *document, word, qualifier string, line int) (*document, *symbol) {
+	if word == "" {
+		return nil, nil
+	}
+	if qualifier != "" {
+		q := doc.index.lookup(qualifier, line)
+		if q == nil || q.include == nil || q.imported != "" {
+			return nil, nil
+		}
+		module, ok := s.module(q.include)
+		if !ok {
+			return nil, nil
+		}
+		return s.exported(module, word, 0)
+	}
+	if sym := doc.index.lookup(word, line); sym != nil {
+		if sym.include != nil && sym.imported != "" {
+			if module, ok := s.module(sym.include); ok {
+				if target, found := s.exported(module, sym.imported, 0); found != nil {
+					return target, found
+				}
+			}
+		}
+		return doc, sym
+	}
+	for _, include := range doc.index.wildcards {
+		if module, ok := s.module(include); ok {
+			if target, found := s.exported(module, word, 0); found != nil {
+				return target, found
+			}
+		}
+	}
+	return nil, nil
+}
+
+// exported finds the top level declaration of module following the names the module imports
+func (s *Server) exported(module *document, name string, depth int) (*document, *symbol) {
+	if depth > maxDepth {
+		return nil, nil
+	}
+	sym := module.index.member(name)
+	if sym != nil && sym.include != nil && sym.imported != "" {
+		if next, ok := s.module(sym.include); ok {
+			if target, found := s.exported(next, sym.imported, depth+1); found != nil {
+				return target, found
+			}
+		}
+	}
+	if sym != nil {
+		return module, sym
+	}
+	for _, include := range module.index.wildcards {
+		if next, ok := s.module(include); ok {
+			if target, found := s.exported(next, name, depth+1); found != nil {
+				return target, found
+			}
+		}
+	}
+	return nil, nil
+}
+
+// members returns the public top level declarations of module including the names imported by wildcard
+func (s *Server) members(module *document, depth int) []*symbol {
+	members := []*symbol{}
+	if depth > maxDepth {
+		return members
+	}
+	for _, sym := range module.index.top {
+		if module.index.public(sym) {
+			members = append(members, sym)
+		}
+	}
+	if module.index.exports {
+		return members
+	}
+	for _, include := range module.index.wildcards {
+		if next, ok := s.module(include); ok {
+			members = append(members, s.members(next, depth+1)...)
+		}
+	}
+	return members
+}
+
+// hover shows the declaration and the doc comment of the identifier, builtin or plugin function
+func (s *Server) hover(doc *document, pos Position) interface{} {
+	if h := s.pluginHover(doc, pos); h != nil {
+		return h
+	}
+	word, qualifier, r := doc.wordAt(pos)
+	if word == "" {
+		return nil
+	}
+	if _, sym := s.resolve(doc, word, qualifier, pos.Line+1); sym != nil {
+		return &Hover{Contents: markdown(sym.detail, sym.doc), Range: &r}
+	}
+	if qualifier == "" && evaluator.IsBuiltin(word) {
+		return &Hover{Contents: markdown(builtinSignature(word), "builtin function"), Range: &r}
+	}
+	return nil
+}
+
+// pluginHover shows the signature of plugin function named in `eval` or `call`: eval("sys", "len", s)
+func (s *Server) pluginHover(doc *document, pos Position) *Hover {
+	if s.Registry == nil || pos.Line >= len(doc.lines) {
+		return nil
+	}
+	line := doc.lines[pos.Line]
+	offset := byteOffset(line, pos.Character)
+	for _, m := range pluginCall.FindAllStringSubmatchIndex(line, -1) {
+		if offset < m[4] || offset > m[5] {
+			continue
+		}
+		pkgName, fnName := line[m[2]:m[3]], line[m[4]:m[5]]
+		detail := "fn " + fnName + "(...)"
+		if signature, ok := s.Registry.Signature(pkgName, fnName); ok {
+			detail = named(fnName, signature)
+		}
+		r := Range{Start: Position{Line: pos.Line, Character: character(line, m[4])}, End: Position{Line: pos.Line, Character: character(line, m[5])}}
+		return &Hover{Contents: markdown(detail, s.Registry.Description(pkgName)), Range: &r}
+	}
+	return nil
+}
+
+func builtinSignature(name string) string {
+	if signature, ok := checker.BuiltinSignature(name); ok {
+		return named(name, signature)
+	}
+	return "fn " + name + "(...)"
+}
+
+// named inserts the name to function type: fn(string): int -> fn len(string): int
+func named(name, signature string) string {
+	return strings.Replace(signature, "fn(", "fn "+name+"(", 1)
+}
+
+func markdown(detail, doc string) MarkupContent {
+	value := "```rash\n" + detail + "\n```"
+	if doc != "" {
+		value += "\n\n" + doc
+	}
+	return MarkupContent{Kind: "markdown", Value: value}
+}
+
+// completion offers the plugin packages and functions inside `eval` and `call`, the public names of module
+// after its alias and dot, otherwise the visible names, the builtins and the keywords
+func (s *Server) completion(doc *document, pos Position) CompletionList {
+	items := []CompletionItem{}
+	line := ""
+	if pos.Line < len(doc.lines) {
+		line = doc.lines[pos.Line]
+		line = line[:byteOffset(line, pos.Character)]
+	}
+
+	switch m := pluginFunction.FindStringSubmatch(line); {
+	case m != nil:
+		if s.Registry != nil {
+			for name, signature := range s.Registry.Signatures(m[1]) {
+				items = append(items, CompletionItem{Label: name, Kind: CompletionFunction, Detail: named(name, signature)})
+			}
+		}
+	case pluginPackage.MatchString(line):
+		if s.Registry != nil {
+			for _, name := range s.Registry.Packages() {
+				items = append(items, CompletionItem{Label: name, Kind: CompletionModule, Documentation: s.Registry.Description(name)})
+			}
+		}
+	case memberAccess.MatchString(line):
+		qualifier := memberAccess.FindStringSubmatch(line)[1]
+		if q := doc.index.lookup(qualifier, pos.Line+1); q != nil && q.include != nil && q.imported == "" {
+			if module, ok := s.module(q.include); ok {
+				for _, sym := range s.members(module, 0) {
+					items = append(items, symbolItem(sym))
+				}
+			}
+		}
+	default:
+		for _, sym := range doc.index.visibleAt(pos.Line + 1) {
+			items = append(items, symbolItem(sym))
+		}
+		for _, include := range doc.index.wildcards {
+			if module, ok := s.module(include); ok {
+				for _, sym := range s.members(module, 0) {
+					items = append(items, symbolItem(sym))
+				}
+			}
+		}
+		for _, name := range evaluator.Builtins() {
+			items = append(items, CompletionItem{Label: name, Kind: CompletionFunction, Detail: builtinSignature(name)})
+		}
+		for _, word := range tokens.Keywords() {
+			items = append(items, CompletionItem{Label: word, Kind: CompletionKeyword})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return CompletionList{Items: items}
+}
+
+func symbolItem(sym *symbol) CompletionItem {
+	kind := CompletionVariable
+	switch sym.kind {
+	case SymbolFunction:
+		kind = CompletionFunction
+	case SymbolStruct:
+		kind = CompletionStruct
+	case SymbolModule:
+		kind = CompletionModule
+	case SymbolConstant:
+		kind = CompletionConstant
+	}
+	return CompletionItem{Label: sym.name, Kind: kind, Detail: sym.detail, Documentation: sym.doc}
+}
+
+// documentSymbols returns the tree of top level declarations, the structs have fields and methods
+func documentSymbols(doc *document, symbols []*symbol) []DocumentSymbol {
+	out := []DocumentSymbol{}
+	for _, sym := range symbols {
+		r := doc.lineRange(sym.line - 1)
+		r.End = doc.lineRange(sym.end - 1).End
+		out = append(out, DocumentSymbol{
+			Name:           sym.name,
+			Detail:         sym.detail,
+			Kind:           sym.kind,
+			Range:          r,
+			SelectionRange: doc.nameRange(sym),
+			Children:       documentSymbols(doc, sym.children),
+		})
+	}
+	return out
+}
+
+// lineRange returns the range of the whole line
+func (d *document) lineRange(line int) Range {
+	if line < 0 || line >= len(d.lines) {
+		line = 0
+	}
+	return Range{Start: Position{Line: line}, End: Position{Line: line, Character: utf16Len(d.lines[line])}}
+}
+
+// nameRange returns the range of the name on the line of declaration, or the whole line if it's not found
+func (d *document) nameRange(sym *symbol) Range {
+	r := d.lineRange(sym.line - 1)
+	text := d.lines[r.Start.Line]
+	i := indexWord(text, sym.name)
+	if i < 0 {
+		return r
+	}
+	return Range{
+		Start: Position{Line: r.Start.Line, Character: utf16Len(text[:i])},
+		End:   Position{Line: r.Start.Line, Character: utf16Len(text[:i+len(sym.name)])},
+	}
+}
+
+// wordAt returns the identifier at the position with its range, and the identifier before the dot
+// if the word is a member: alias.name or alias?.name
+func (d *document) wordAt(pos Position) (string, string, Range) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return "", "", Range{}
+	}
+	line := d.lines[pos.Line]
+	start := byteOffset(line, pos.Character)
+	end := start
+	for start > 0 && isWordChar(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWordChar(line[end]) {
+		end++
+	}
+	r := Range{Start: Position{Line: pos.Line, Character: character(line, start)}, End: Position{Line: pos.Line, Character: character(line, end)}}
+
+	qualifier := ""
+	if before := line[:start]; strings.HasSuffix(before, ".") && !strings.HasSuffix(before, "..") {
+		before = strings.TrimSuffix(strings.TrimSuffix(before, "."), "?")
+		i := len(before)
+		for i > 0 && isWordChar(before[i-1]) {
+			i--
+		}
+		qualifier = before[i:]
+	}
+	return line[start:end], qualifier, r
+}
+
+// includeAt returns the include declaration if the position is on its path
+func (d *document) includeAt(pos Position) *ast.IncludeDeclaration {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return nil
+	}
+	line := d.lines[pos.Line]
+	offset := byteOffset(line, pos.Character)
+	for _, include := range d.index.includes {
+		if include.Token.LineNumber != pos.Line+1 {
+			continue
+		}
+		raw := include.Include.Token.Raw
+		if i := strings.Index(line, raw); i >= 0 && offset >= i && offset <= i+len(raw) {
+			return include
+		}
+	}
+	return nil
+}
+
+func isWordChar(ch byte) bool {
+	return ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9')
+}
+
+// indexWord returns the byte offset of the first occurrence of the whole word in the text
+func indexWord(text, word string) int {
+	for from := 0; from <= len(text); {
+		i := strings.Index(text[from:], word)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		end := i + len(word)
+		if (i == 0 || !isWordChar(text[i-1])) && (end == len(text) || !isWordChar(text[end])) {
+			return i
+		}
+		from = i + 1
+	}
+	return -1
+}
+
+// byteOffset converts the character offset counted in UTF-16 code units to the byte offset in the line
+func byteOffset(line string, char int) int {
+	units := 0
+	for i, r := range line {
+		if units >= char {
+			return i
+		}
+		units += runeUnits(r)
+	}
+	return len(line)
+}
+
+// character converts the byte offset in the line to the offset in UTF-16 code units
+func character(line string, offset int) int {
+	return utf16Len(line[:offset])
+}
+
+func utf16Len(s string) int {
+	units := 0
+	for _, r := range s {
+		units += runeUnits(r)
+	}
+	return units
+}
+
+func runeUnits(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,394 @@
+package lsp
+
+import (
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/format"
+	"github.com/YReshetko/rash-lang/lexer"
+	"math"
+	"strings"
+)
+
+// span is the range of source lines including both ends
+type span struct {
+	from, to int
+}
+
+func (s span) contains(line int) bool {
+	return s.from <= line && line <= s.to
+}
+
+// symbol is a name declared in the document
+type symbol struct {
+	name     string
+	kind     int                     // kind of document symbol
+	line     int                     // line of the declaration
+	end      int                     // last line of the declaration
+	visible  span                    // lines where the name can be used
+	detail   string                  // declaration shown by hover: fn add(a, b = 1)
+	doc      string                  // comment lines right above the declaration
+	exported bool                    // the top level declaration is marked by export
+	include  *ast.IncludeDeclaration // the module of alias or imported name
+	imported string                  // the name in the module if the symbol is imported by name
+	children []*symbol               // fields and methods of struct
+}
+
+// index collects the names declared in the document with the lines where they are visible,
+// a block makes the names visible from its first to its last line
+type index struct {
+	symbols   []*symbol
+	top       []*symbol
+	includes  []*ast.IncludeDeclaration
+	wildcards []*ast.IncludeDeclaration
+	exports   bool           // the module has export markers, so only the exported names are public
+	comments  map[int]string // whole line comments by line
+}
+
+func newIndex(program *ast.Program, comments []lexer.Comment) *index {
+	x := &index{comments: map[int]string{}}
+	for _, comment := range comments {
+		if !comment.Trailing {
+			x.comments[comment.Line] = comment.Text
+		}
+	}
+	x.statements(program.Statements, span{1, math.MaxInt32}, true)
+	return x
+}
+
+// lookup returns the declaration of name visible on the line: the one of the innermost block,
+// and the last one declared before the line if the name is declared several times in the block
+func (x *index) lookup(name string, line int) *symbol {
+	var found *symbol
+	for _, s := range x.symbols {
+		if s.name == name && s.visible.contains(line) && (found == nil || closer(s, found, line)) {
+			found = s
+		}
+	}
+	return found
+}
+
+func closer(s, other *symbol, line int) bool {
+	width, otherWidth := s.visible.to-s.visible.from, other.visible.to-other.visible.from
+	switch {
+	case width != otherWidth:
+		return width < otherWidth
+	case (s.line <= line) != (other.line <= line):
+		return s.line <= line
+	case s.line <= line:
+		return s.line > other.line
+	default:
+		return s.line < other.line
+	}
+}
+
+// visibleAt returns the declarations which can be used on the line, a name hidden by an inner declaration is skipped
+func (x *index) visibleAt(line int) []*symbol {
+	symbols := []*symbol{}
+	seen := map[string]bool{}
+	for _, s := range x.symbols {
+		if seen[s.name] || !s.visible.contains(line) {
+			continue
+		}
+		seen[s.name] = true
+		symbols = append(symbols, x.lookup(s.name, line))
+	}
+	return symbols
+}
+
+// member returns the top level declaration of module
+func (x *index) member(name string) *symbol {
+	for _, s := range x.top {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// public checks the top level declaration can be used by the including scripts
+func (x *index) public(s *symbol) bool {
+	if x.exports {
+		return s.exported
+	}
+	return !strings.HasPrefix(s.name, "_")
+}
+
+// doc returns the comment lines right above the line without the slashes
+func (x *index) doc(line int) string {
+	lines := []string{}
+	for l := line - 1; ; l-- {
+		text, ok := x.comments[l]
+		if !ok {
+			break
+		}
+		lines = append([]string{strings.TrimSpace(strings.TrimPrefix(text, "//"))}, lines...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (x *index) declare(s *symbol, top bool) {
+	s.doc = x.doc(s.line)
+	if s.end < s.line {
+		s.end = s.line
+	}
+	x.symbols = append(x.symbols, s)
+	if top {
+		x.top = append(x.top, s)
+	}
+}
+
+func (x *index) statements(statements []ast.Statement, visible span, top bool) {
+	for _, statement := range statements {
+		x.statement(statement, visible, top)
+	}
+}
+
+func (x *index) statement(statement ast.Statement, visible span, top bool) {
+	switch s := statement.(type) {
+	case *ast.ExportStatement:
+		declared := len(x.top)
+		x.statement(s.Statement, visible, top)
+		for _, exported := range x.top[declared:] {
+			exported.exported = true
+		}
+		x.exports = true
+	case *ast.LetStatement:
+		x.let(s, visible, top)
+	case *ast.StructStatement:
+		x.structure(s, visible, top)
+	case *ast.DeclarationStatement:
+		if include, ok := s.Declaration.(*ast.IncludeDeclaration); ok {
+			x.include(include, visible, top)
+		}
+	case *ast.ReturnStatement:
+		x.expression(s.Value)
+	case *ast.ExpressionStatement:
+		x.expression(s.Expression)
+	case *ast.BlockStatement:
+		x.block(s)
+	}
+}
+
+func (x *index) let(s *ast.LetStatement, visible span, top bool) {
+	kind := SymbolVariable
+	if s.IsConst() {
+		kind = SymbolConstant
+	}
+	switch {
+	case s.Name == nil:
+		for _, name := range ast.BoundNames(s.Pattern) {
+			x.declare(&symbol{name: name, kind: kind, line: s.Token.LineNumber, visible: visible, detail: s.Token.Literal + " " + name}, top)
+		}
+	case isFunction(s.Value):
+		fn := s.Value.(*ast.FunctionLiteral)
+		x.declare(&symbol{name: s.Name.Value, kind: SymbolFunction, line: s.Token.LineNumber, end: blockSpan(fn.Body).to,
+			visible: visible, detail: signature(s.Name.Value, fn)}, top)
+	default:
+		detail := s.Token.Literal + " " + s.Name.Value
+		if s.Type != nil {
+			detail += ": " + s.Type.String()
+		}
+		x.declare(&symbol{name: s.Name.Value, kind: kind, line: s.Token.LineNumber, visible: visible, detail: detail}, top)
+	}
+	x.expression(s.Value)
+}
+
+func isFunction(e ast.Expression) bool {
+	_, ok := e.(*ast.FunctionLiteral)
+	return ok
+}
+
+// signature returns the declaration of function without body: fn add(a: int, b = 1, ...rest): int
+func signature(name string, fn *ast.FunctionLiteral) string {
+	params := []string{}
+	for i, param := range fn.Parameters {
+		text := param.Value
+		if t, ok := fn.Types[i]; ok {
+			text += ": " + t.String()
+		}
+		if value, ok := fn.Defaults[i]; ok {
+			text += " = " + format.Expression(value)
+		}
+		params = append(params, text)
+	}
+	if fn.Rest != nil {
+		params = append(params, "..."+fn.Rest.Value)
+	}
+	out := "fn " + name + "(" + strings.Join(params, ", ") + ")"
+	if fn.Result != nil {
+		out += ": " + fn.Result.String()
+	}
+	return out
+}
+
+func (x *index) structure(s *ast.StructStatement, visible span, top bool) {
+	fields := make([]string, len(s.Fields))
+	children := []*symbol{}
+	for i, field := range s.Fields {
+		fields[i] = field.Name.Value
+		if field.Value != nil {
+			fields[i] += " = " + format.Expression(field.Value)
+		}
+		children = append(children, &symbol{name: field.Name.Value, kind: SymbolField, line: field.Name.Token.LineNumber,
+			end: field.Name.Token.LineNumber, detail: fields[i], doc: x.doc(field.Name.Token.LineNumber)})
+		x.expression(field.Value)
+	}
+	for _, method := range s.Methods {
+		line := method.Name.Token.LineNumber
+		children = append(children, &symbol{name: method.Name.Value, kind: SymbolMethod, line: line,
+			end: blockSpan(method.Function.Body).to, detail: signature(method.Name.Value, method.Function), doc: x.doc(line)})
+		x.function(method.Function)
+	}
+	x.declare(&symbol{name: s.Name.Value, kind: SymbolStruct, line: s.Token.LineNumber, end: s.End.LineNumber,
+		visible: visible, detail: "struct " + s.Name.Value + " { " + strings.Join(fields, "; ") + " }", children: children}, top)
+}
+
+func (x *index) include(d *ast.IncludeDeclaration, visible span, top bool) {
+	x.includes = append(x.includes, d)
+	detail := strings.TrimSuffix(d.String(), ";")
+	switch {
+	case d.Wildcard:
+		x.wildcards = append(x.wildcards, d)
+	case d.Names != nil:
+		for _, name := range d.Names {
+			x.declare(&symbol{name: name.Bound().Value, kind: SymbolVariable, line: d.Token.LineNumber, visible: visible,
+				detail: detail, include: d, imported: name.Name.Value}, top)
+		}
+	default:
+		x.declare(&symbol{name: d.Alias.Value, kind: SymbolModule, line: d.Token.LineNumber, visible: visible,
+			detail: detail, include: d}, top)
+	}
+}
+
+func (x *index) block(b *ast.BlockStatement) {
+	if b == nil {
+		return
+	}
+	x.statements(b.Statements, blockSpan(b), false)
+}
+
+// blockSpan returns the lines of block, the expression body of match arm has no braces and ends on its first line
+func blockSpan(b *ast.BlockStatement) span {
+	if b == nil {
+		return span{}
+	}
+	if b.End.LineNumber < b.Token.LineNumber {
+		return span{b.Token.LineNumber, b.Token.LineNumber}
+	}
+	return span{b.Token.LineNumber, b.End.LineNumber}
+}
+
+func (x *index) function(fn *ast.FunctionLiteral) {
+	visible := span{fn.Token.LineNumber, blockSpan(fn.Body).to}
+	for i, param := range fn.Parameters {
+		names := []string{param.Value}
+		if pattern, ok := fn.Patterns[i]; ok {
+			names = ast.BoundNames(pattern)
+		}
+		for _, name := range names {
+			x.declare(&symbol{name: name, kind: SymbolVariable, line: param.Token.LineNumber, visible: visible, detail: "parameter " + name}, false)
+		}
+		x.expression(fn.Defaults[i])
+	}
+	if fn.Rest != nil {
+		x.declare(&symbol{name: fn.Rest.Value, kind: SymbolVariable, line: fn.Rest.Token.LineNumber, visible: visible, detail: "parameter ..." + fn.Rest.Value}, false)
+	}
+	x.block(fn.Body)
+}
+
+// expression indexes the names declared by functions, loops, match arms and select cases of the expression
+func (x *index) expression(expression ast.Expression) {
+	switch e := expression.(type) {
+	case *ast.FunctionLiteral:
+		x.function(e)
+	case *ast.InfixExpression:
+		x.expression(e.Left)
+		x.expression(e.Right)
+	case *ast.MultipleAssignment:
+		for _, value := range e.Values {
+			x.expression(value)
+		}
+	case *ast.PrefixExpression:
+		x.expression(e.Right)
+	case *ast.AwaitExpression:
+		x.expression(e.Value)
+	case *ast.SpawnExpression:
+		x.expression(e.Value)
+	case *ast.SpreadExpression:
+		x.expression(e.Value)
+	case *ast.TemplateLiteral:
+		for _, part := range e.Parts {
+			x.expression(part)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range e.Elements {
+			x.expression(element)
+		}
+	case *ast.HashLiteral:
+		for _, key := range e.Keys {
+			x.expression(key)
+			x.expression(e.Pairs[key])
+		}
+	case *ast.CallExpression:
+		x.expression(e.Function)
+		for _, argument := range e.Arguments {
+			x.expression(argument)
+		}
+		for _, named := range e.Named {
+			x.expression(named.Value)
+		}
+	case *ast.IndexExpression:
+		x.expression(e.Left)
+		x.expression(e.Index)
+	case *ast.SliceExpression:
+		x.expression(e.Left)
+		x.expression(e.Start)
+		x.expression(e.End)
+	case *ast.IfExpression:
+		x.expression(e.Condition)
+		x.block(e.Consequence)
+		x.block(e.Alternative)
+	case *ast.ForExpression:
+		visible := span{e.Token.LineNumber, blockSpan(e.Body).to}
+		if let, ok := e.Initial.(*ast.LetStatement); ok {
+			x.let(let, visible, false)
+		} else {
+			x.expression(e.Initial)
+		}
+		x.expression(e.Condition)
+		x.expression(e.Complete)
+		x.block(e.Body)
+	case *ast.ForInExpression:
+		x.expression(e.Iterable)
+		visible := span{e.Token.LineNumber, blockSpan(e.Body).to}
+		for _, ident := range []*ast.Identifier{e.Key, e.Value} {
+			if ident != nil {
+				x.declare(&symbol{name: ident.Value, kind: SymbolVariable, line: ident.Token.LineNumber, visible: visible, detail: "for " + ident.Value}, false)
+			}
+		}
+		x.block(e.Body)
+	case *ast.MatchExpression:
+		x.expression(e.Value)
+		for _, arm := range e.Arms {
+			visible := span{arm.Token.LineNumber, blockSpan(arm.Body).to}
+			for _, name := range ast.BoundNames(arm.Pattern) {
+				if name != "_" {
+					x.declare(&symbol{name: name, kind: SymbolVariable, line: arm.Token.LineNumber, visible: visible, detail: "match " + name}, false)
+				}
+			}
+			x.expression(arm.Guard)
+			x.block(arm.Body)
+		}
+	case *ast.SelectExpression:
+		for _, c := range e.Cases {
+			if c.Operation != nil {
+				x.expression(c.Operation)
+			}
+			if c.Name != nil {
+				visible := span{c.Token.LineNumber, blockSpan(c.Body).to}
+				x.declare(&symbol{name: c.Name.Value, kind: SymbolVariable, line: c.Name.Token.LineNumber, visible: visible, detail: "case " + c.Name.Value}, false)
+			}
+			x.block(c.Body)
+		}
+		x.block(e.Default)
+	}
+}
@@ -0,0 +1,312 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/extensions"
+	"github.com/YReshetko/rash-lang/format"
+	"github.com/YReshetko/rash-lang/lexer"
+	"github.com/YReshetko/rash-lang/lint"
+	"github.com/YReshetko/rash-lang/loaders"
+	"github.com/YReshetko/rash-lang/parser"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// InternalError is the code of error returned if a handler fails
+const InternalError = -32603
+
+// Server is the language server of rash scripts talking JSON-RPC over the reader and writer, usually stdin and stdout.
+// The server keeps the open documents, publishes the parser and linter diagnostics on every change and answers
+// the requests of definitions, hovers, completions, document symbols and formatting
+type Server struct {
+	// Loader resolves and reads the included modules which are not open in the editor
+	Loader *loaders.Loader
+	// Registry describes the plugin functions for completion and hover, it may be nil
+	Registry *extensions.Registry
+	// Lint configures the rules of linter diagnostics, all the rules are reported if it's nil
+	Lint *lint.Config
+
+	in        *bufio.Reader
+	out       io.Writer
+	documents map[string]*document // open documents by URI
+	shutdown  bool
+}
+
+// document is the open script or the included module read by the loader
+type document struct {
+	uri    string
+	path   string
+	text   string
+	lines  []string
+	errors []string
+	index  *index
+}
+
+func newDocument(uri, path, text string) *document {
+	l := lexer.New(text, path)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	return &document{
+		uri:    uri,
+		path:   path,
+		text:   text,
+		lines:  strings.Split(text, "\n"),
+		errors: p.Errors(),
+		index:  newIndex(program, l.Comments()),
+	}
+}
+
+// New creates the server reading the requests from in and writing the responses and notifications to out
+func New(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		Loader:    loaders.Default,
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: map[string]*document{},
+	}
+}
+
+// Serve handles the messages until `exit` notification or the end of input
+func (s *Server) Serve() error {
+	for {
+		content, err := ReadMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		req := request{}
+		if err := json.Unmarshal(content, &req); err != nil {
+			if err := s.reply(nil, nil, &ResponseError{Code: ParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit notification is received before shutdown request")
+			}
+			return nil
+		}
+		result, err := s.call(req.Method, req.Params)
+		if req.ID == nil {
+			continue
+		}
+		if err := s.reply(req.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+// call handles the message and converts the panic of a handler to the error, so a broken document doesn't stop the server
+func (s *Server) call(method string, params json.RawMessage) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &ResponseError{Code: InternalError, Message: fmt.Sprintf("%s failed: %v", method, r)}
+		}
+	}()
+	return s.handle(method, params)
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, err error) error {
+	if err == nil {
+		return WriteMessage(s.out, response{JSONRPC: "2.0", ID: id, Result: result})
+	}
+	responseErr, ok := err.(*ResponseError)
+	if !ok {
+		responseErr = &ResponseError{Code: InternalError, Message: err.Error()}
+	}
+	return WriteMessage(s.out, errorResponse{JSONRPC: "2.0", ID: id, Error: responseErr})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return WriteMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":           1, // the full text is sent on change
+				"definitionProvider":         true,
+				"hoverProvider":              true,
+				"completionProvider":         map[string]interface{}{"triggerCharacters": []string{".", "\""}},
+				"documentSymbolProvider":     true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "rash"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		p := DidOpenTextDocumentParams{}
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, s.open(p.TextDocument.URI, p.TextDocument.Text)
+	case "textDocument/didChange":
+		p := DidChangeTextDocumentParams{}
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		if len(p.ContentChanges) == 0 {
+			return nil, nil
+		}
+		return nil, s.open(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		p := DidCloseTextDocumentParams{}
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.documents, p.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	case "textDocument/didSave":
+		return nil, nil
+	case "textDocument/definition":
+		doc, pos, err := s.position(params)
+		if err != nil {
+			return nil, err
+		}
+		return s.definition(doc, pos), nil
+	case "textDocument/hover":
+		doc, pos, err := s.position(params)
+		if err != nil {
+			return nil, err
+		}
+		return s.hover(doc, pos), nil
+	case "textDocument/completion":
+		doc, pos, err := s.position(params)
+		if err != nil {
+			return nil, err
+		}
+		return s.completion(doc, pos), nil
+	case "textDocument/documentSymbol":
+		doc, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		return documentSymbols(doc, doc.index.top), nil
+	case "textDocument/formatting":
+		doc, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		return formatting(doc), nil
+	default:
+		return nil, &ResponseError{Code: MethodNotFound, Message: "method not found: " + method}
+	}
+}
+
+func decode(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &ResponseError{Code: InvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) document(params json.RawMessage) (*document, error) {
+	p := DocumentParams{}
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, &ResponseError{Code: InvalidParams, Message: "document is not open: " + p.TextDocument.URI}
+	}
+	return doc, nil
+}
+
+func (s *Server) position(params json.RawMessage) (*document, Position, error) {
+	p := TextDocumentPositionParams{}
+	if err := decode(params, &p); err != nil {
+		return nil, Position{}, err
+	}
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, Position{}, &ResponseError{Code: InvalidParams, Message: "document is not open: " + p.TextDocument.URI}
+	}
+	return doc, p.Position, nil
+}
+
+// open parses the document and publishes the syntax errors, or the linter problems if the document is parsed
+func (s *Server) open(uri, text string) error {
+	doc := newDocument(uri, uriToPath(uri), text)
+	s.documents[uri] = doc
+
+	linter := lint.New(s.Lint)
+	linter.Loader = s.Loader
+	diagnostics := []Diagnostic{}
+	for _, d := range linter.LintSource([]byte(text), doc.path) {
+		if d.File != doc.path {
+			continue
+		}
+		severity := SeverityWarning
+		if d.Rule == lint.Syntax {
+			severity = SeverityError
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    doc.lineRange(d.Line - 1),
+			Severity: severity,
+			Code:     d.Rule,
+			Source:   "rash",
+			Message:  d.Message,
+		})
+	}
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+// module returns the included module, the document open in the editor is used instead of the file
+func (s *Server) module(include *ast.IncludeDeclaration) (*document, bool) {
+	path, err := s.Loader.Resolve(include.Include.Value, include.Token.FileName)
+	if err != nil {
+		return nil, false
+	}
+	uri := pathToURI(path)
+	if doc, ok := s.documents[uri]; ok {
+		return doc, true
+	}
+	src, err := s.Loader.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return newDocument(uri, path, string(src)), true
+}
+
+// formatting replaces the whole document with the formatted text, nothing is changed if it can't be parsed
+func formatting(doc *document) []TextEdit {
+	out, err := format.Source([]byte(doc.text), doc.path)
+	if err != nil || string(out) == doc.text {
+		return []TextEdit{}
+	}
+	last := len(doc.lines) - 1
+	return []TextEdit{{
+		Range:   Range{End: Position{Line: last, Character: utf16Len(doc.lines[last])}},
+		NewText: string(out),
+	}}
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
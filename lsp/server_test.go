@@ -0,0 +1,274 @@
+package lsp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/YReshetko/rash-lang/extensions"
+	"github.com/YReshetko/rash-lang/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+type sysPlugin struct{}
+
+func (sysPlugin) Eval(fnName string, args ...interface{}) ([]interface{}, error) {
+	return nil, nil
+}
+func (sysPlugin) Call(fnName string, callback func(args ...interface{}) ([]interface{}, error), args ...interface{}) ([]interface{}, error) {
+	return nil, nil
+}
+func (sysPlugin) Package() string     { return "sys" }
+func (sysPlugin) Version() string     { return "v1" }
+func (sysPlugin) Description() string { return "system functions" }
+func (sysPlugin) Signatures() map[string]string {
+	return map[string]string{"len": "fn(string): int"}
+}
+
+// client is a scripted LSP client talking to the server over pipes
+type client struct {
+	t      *testing.T
+	in     io.WriteCloser
+	out    *bufio.Reader
+	id     int
+	done   chan error
+	events []map[string]json.RawMessage // notifications received while waiting for responses
+}
+
+func start(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	registry := extensions.New()
+	registry.Register(sysPlugin{})
+	server := lsp.New(inR, outW)
+	server.Registry = registry
+
+	c := &client{t: t, in: inW, out: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		c.done <- server.Serve()
+		_ = outW.Close()
+	}()
+	return c
+}
+
+func (c *client) send(v interface{}) {
+	require.NoError(c.t, lsp.WriteMessage(c.in, v))
+}
+
+func (c *client) read() map[string]json.RawMessage {
+	content, err := lsp.ReadMessage(c.out)
+	require.NoError(c.t, err)
+	msg := map[string]json.RawMessage{}
+	require.NoError(c.t, json.Unmarshal(content, &msg))
+	return msg
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// request sends the request and decodes the result of response, the response is returned to check errors
+func (c *client) request(method string, params interface{}, result interface{}) map[string]json.RawMessage {
+	c.id++
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "id": c.id, "method": method, "params": params})
+	for {
+		msg := c.read()
+		if _, ok := msg["id"]; !ok {
+			c.events = append(c.events, msg)
+			continue
+		}
+		assert.JSONEq(c.t, string(mustJSON(c.t, c.id)), string(msg["id"]))
+		if result != nil {
+			require.NoError(c.t, json.Unmarshal(msg["result"], result), string(msg["error"]))
+		}
+		return msg
+	}
+}
+
+// diagnostics reads the diagnostics published after the document is opened or changed
+func (c *client) diagnostics() lsp.PublishDiagnosticsParams {
+	msg := c.read()
+	require.Equal(c.t, `"textDocument/publishDiagnostics"`, string(msg["method"]))
+	params := lsp.PublishDiagnosticsParams{}
+	require.NoError(c.t, json.Unmarshal(msg["params"], &params))
+	return params
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+func uri(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func position(uri string, line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     map[string]int{"line": line, "character": character},
+	}
+}
+
+const mainScript = `# lib "lib.rs";
+# {greet as hello} from "lib.rs";
+// counts the items
+let count = fn(items, step = 1) {
+    let total = len(items);
+    total * step
+};
+let server1 = lib.new_server("4000");
+server["start"]();
+println(count([1]), hello("rash"), eval("sys", "len", "abc"));
+`
+
+const libScript = `// Greets by name
+export let greet = fn(name) { "hello " + name };
+// Creates the server
+export let new_server = fn(port) { {"port": port} };
+let _hidden = 1;
+`
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "lib.rs"), []byte(libScript), 0644))
+	mainURI, libURI := uri(filepath.Join(dir, "main.rs")), uri(filepath.Join(dir, "lib.rs"))
+	doc := map[string]string{"uri": mainURI}
+
+	c := start(t)
+	initialized := struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}{}
+	c.request("initialize", map[string]interface{}{"processId": nil, "rootUri": uri(dir)}, &initialized)
+	assert.Equal(t, true, initialized.Capabilities["hoverProvider"])
+	assert.Equal(t, true, initialized.Capabilities["definitionProvider"])
+	c.notify("initialized", map[string]interface{}{})
+
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": mainURI, "languageId": "rash", "version": 1, "text": mainScript},
+	})
+	assert.Equal(t, lsp.PublishDiagnosticsParams{URI: mainURI, Diagnostics: []lsp.Diagnostic{{
+		Range:    lsp.Range{Start: lsp.Position{Line: 8}, End: lsp.Position{Line: 8, Character: 18}},
+		Severity: lsp.SeverityWarning,
+		Code:     "undefined",
+		Source:   "rash",
+		Message:  "undefined identifier server",
+	}}}, c.diagnostics())
+
+	t.Run("definition", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			line, ch  int
+			uri       string
+			startLine int
+			startCh   int
+			endCh     int
+		}{
+			{name: "local function", line: 9, ch: 10, uri: mainURI, startLine: 3, startCh: 4, endCh: 9},
+			{name: "local variable", line: 5, ch: 5, uri: mainURI, startLine: 4, startCh: 8, endCh: 13},
+			{name: "module member", line: 7, ch: 20, uri: libURI, startLine: 3, startCh: 11, endCh: 21},
+			{name: "imported name", line: 9, ch: 21, uri: libURI, startLine: 1, startCh: 11, endCh: 16},
+			{name: "included module", line: 0, ch: 9, uri: libURI},
+		}
+		for _, test := range tests {
+			location := lsp.Location{}
+			c.request("textDocument/definition", position(mainURI, test.line, test.ch), &location)
+			assert.Equal(t, lsp.Location{URI: test.uri, Range: lsp.Range{
+				Start: lsp.Position{Line: test.startLine, Character: test.startCh},
+				End:   lsp.Position{Line: test.startLine, Character: test.endCh},
+			}}, location, test.name)
+		}
+
+		msg := c.request("textDocument/definition", position(mainURI, 8, 2), nil)
+		assert.Equal(t, "null", string(msg["result"]))
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		tests := []struct {
+			line, ch int
+			expected string
+		}{
+			{9, 10, "```rash\nfn count(items, step = 1)\n```\n\ncounts the items"},
+			{7, 22, "```rash\nfn new_server(port)\n```\n\nCreates the server"},
+			{4, 17, "```rash\nfn len(string | array | hash | range): int\n```\n\nbuiltin function"},
+			{9, 49, "```rash\nfn len(string): int\n```\n\nsystem functions"},
+		}
+		for _, test := range tests {
+			hover := lsp.Hover{}
+			c.request("textDocument/hover", position(mainURI, test.line, test.ch), &hover)
+			assert.Equal(t, "markdown", hover.Contents.Kind)
+			assert.Equal(t, test.expected, hover.Contents.Value)
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		labels := func(line, ch int) []string {
+			list := lsp.CompletionList{}
+			c.request("textDocument/completion", position(mainURI, line, ch), &list)
+			labels := []string{}
+			for _, item := range list.Items {
+				labels = append(labels, item.Label)
+			}
+			return labels
+		}
+		assert.Equal(t, []string{"greet", "new_server"}, labels(7, 18))
+		assert.Equal(t, []string{"sys"}, labels(9, 41))
+		assert.Equal(t, []string{"len"}, labels(9, 48))
+
+		names := labels(9, 0)
+		for _, name := range []string{"count", "hello", "lib", "server1", "println", "let"} {
+			assert.Contains(t, names, name)
+		}
+		assert.NotContains(t, names, "total")
+		assert.Contains(t, labels(5, 4), "total")
+	})
+
+	t.Run("document symbols", func(t *testing.T) {
+		symbols := []lsp.DocumentSymbol{}
+		c.request("textDocument/documentSymbol", map[string]interface{}{"textDocument": doc}, &symbols)
+		names := []string{}
+		for _, s := range symbols {
+			names = append(names, s.Name)
+		}
+		assert.Equal(t, []string{"lib", "hello", "count", "server1"}, names)
+		assert.Equal(t, lsp.SymbolFunction, symbols[2].Kind)
+		assert.Equal(t, lsp.Range{Start: lsp.Position{Line: 3}, End: lsp.Position{Line: 6, Character: 2}}, symbols[2].Range)
+	})
+
+	t.Run("formatting and syntax errors", func(t *testing.T) {
+		c.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": mainURI, "version": 2},
+			"contentChanges": []map[string]string{{"text": "let a=1"}},
+		})
+		assert.Empty(t, c.diagnostics().Diagnostics)
+
+		edits := []lsp.TextEdit{}
+		c.request("textDocument/formatting", map[string]interface{}{"textDocument": doc, "options": map[string]interface{}{"tabSize": 4}}, &edits)
+		assert.Equal(t, []lsp.TextEdit{{
+			Range:   lsp.Range{End: lsp.Position{Line: 0, Character: 7}},
+			NewText: "let a = 1;\n",
+		}}, edits)
+
+		c.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": mainURI, "version": 3},
+			"contentChanges": []map[string]string{{"text": "let a = ;"}},
+		})
+		diagnostics := c.diagnostics().Diagnostics
+		require.Len(t, diagnostics, 1)
+		assert.Equal(t, lsp.SeverityError, diagnostics[0].Severity)
+		assert.Equal(t, "syntax", diagnostics[0].Code)
+	})
+
+	msg := c.request("textDocument/unknown", map[string]interface{}{}, nil)
+	assert.JSONEq(t, `{"code": -32601, "message": "method not found: textDocument/unknown"}`, string(msg["error"]))
+
+	msg = c.request("shutdown", nil, nil)
+	assert.Equal(t, "null", string(msg["result"]))
+	c.notify("exit", nil)
+	assert.NoError(t, <-c.done)
+}
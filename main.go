@@ -1,14 +1,29 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"github.com/YReshetko/rash-lang/checker"
+	"github.com/YReshetko/rash-lang/dap"
+	"github.com/YReshetko/rash-lang/debugger"
 	"github.com/YReshetko/rash-lang/evaluator"
 	"github.com/YReshetko/rash-lang/extensions"
+	"github.com/YReshetko/rash-lang/format"
+	"github.com/YReshetko/rash-lang/lint"
 	"github.com/YReshetko/rash-lang/loaders"
+	"github.com/YReshetko/rash-lang/lsp"
+	"github.com/YReshetko/rash-lang/objects"
+	"github.com/YReshetko/rash-lang/packages"
 	"github.com/YReshetko/rash-lang/repl"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 const banner = `
@@ -25,20 +40,24 @@ const banner = `
 `
 
 func main() {
-	u, err := user.Current()
-	if err != nil {
+	evaluator.ScriptLoader = loaders.ScriptLoader
+
+	if len(os.Args) > 1 {
+		if err := command(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if _, err := plugins(); err != nil {
 		log.Fatal(err)
 	}
-	reg, err := extensionsRegistry()
+	u, err := user.Current()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	evaluator.ScriptLoader = loaders.ScriptLoader
-	evaluator.Evaluate = evaluator.Eval
-	evaluator.InitRegistry(reg)
-
-	fmt.Println(banner)
+	fmt.Print(banner)
 	fmt.Printf("Hello %s! Welcome in `rasheska` script language!\n", u.Username)
 	fmt.Printf("Let's start fun!\n")
 
@@ -48,6 +67,352 @@ func main() {
 	fmt.Println("Good bye!... rasheska will miss you")
 }
 
+func command(name string, args []string) error {
+	switch name {
+	case "run":
+		return run(args)
+	case "check":
+		return check(args)
+	case "mod":
+		return mod(args)
+	case "fmt":
+		return formatScripts(args)
+	case "lint":
+		return lintScripts(args)
+	case "lsp":
+		return languageServer(args)
+	case "dap":
+		return debugAdapter(args)
+	case "debug":
+		return debug(args)
+	default:
+		return fmt.Errorf("unknown command %s", name)
+	}
+}
+
+// run parses the flags and evaluates the script
+func run(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "report missing hash keys and array indexes as errors")
+	flags.StringVar(&loaders.Default.Root, "root", "", "project root to search the included scripts in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: rash run [-strict] [-root <dir>] <script>")
+	}
+	if _, err := plugins(); err != nil {
+		return err
+	}
+	evaluator.Strict = *strict
+	return runScript(flags.Arg(0))
+}
+
+// runScript evaluates the script and waits until all scheduled timers and callbacks are done
+func runScript(path string) error {
+	obj, err := loaders.Default.Run(path, objects.NewEnvironment())
+	if err != nil {
+		return err
+	}
+	if errObj, ok := obj.(*objects.Error); ok {
+		return scriptError(errObj)
+	}
+	if errObj := evaluator.RunEventLoop(); errObj != nil {
+		return scriptError(errObj)
+	}
+	return nil
+}
+
+// check reports the type errors of the script and included modules without evaluation
+func check(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.StringVar(&loaders.Default.Root, "root", "", "project root to search the included scripts in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: rash check [-root <dir>] <script>")
+	}
+	reg, err := plugins()
+	if err != nil {
+		return err
+	}
+	diagnostics := checker.New(reg.Signature).CheckFile(flags.Arg(0))
+	for _, d := range diagnostics {
+		fmt.Println(d)
+	}
+	if len(diagnostics) != 0 {
+		return fmt.Errorf("found %d type errors", len(diagnostics))
+	}
+	return nil
+}
+
+// mod vendors the dependencies of rash.mod into rash_modules and writes rash.lock, or verifies the vendored files
+func mod(args []string) error {
+	flags := flag.NewFlagSet("mod", flag.ContinueOnError)
+	root := flags.String("root", ".", "project root with rash.mod")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case flags.NArg() == 0:
+		locked, err := packages.Sync(*root)
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(locked))
+		for name := range locked {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s %s %s\n", name, locked[name].Source, locked[name].Sum)
+		}
+		return nil
+	case flags.NArg() == 1 && flags.Arg(0) == "verify":
+		return packages.Verify(*root)
+	default:
+		return errors.New("usage: rash mod [-root <dir>] [verify]")
+	}
+}
+
+// formatScripts prints the formatted scripts, or lists the scripts which are not formatted with -check,
+// or overwrites them with -write. The directories are walked for *.rs files except vendored packages
+func formatScripts(args []string) error {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	checkOnly := flags.Bool("check", false, "list the scripts which are not formatted and fail if there are any")
+	write := flags.Bool("write", false, "overwrite the scripts which are not formatted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *checkOnly && *write {
+		return errors.New("usage: rash fmt [-check | -write] [<path>...]")
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := scripts(paths)
+	if err != nil {
+		return err
+	}
+
+	unformatted := 0
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		out, err := format.Source(src, file)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !*checkOnly && !*write:
+			fmt.Print(string(out))
+		case string(out) == string(src):
+		case *checkOnly:
+			unformatted++
+			fmt.Println(file)
+		default:
+			if err := ioutil.WriteFile(file, out, 0644); err != nil {
+				return err
+			}
+			fmt.Println(file)
+		}
+	}
+	if unformatted != 0 {
+		return fmt.Errorf("%d scripts are not formatted, run `rash fmt -write`", unformatted)
+	}
+	return nil
+}
+
+// lintScripts reports the problems found by the linter in the scripts, the rules are configured by
+// rashlint.json in the project root or the file passed by -config, and by -enable and -disable lists
+func lintScripts(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.StringVar(&loaders.Default.Root, "root", "", "project root to search the included scripts in")
+	configFile := flags.String("config", "", "JSON config of rules, rashlint.json in the project root is used by default")
+	enable := flags.String("enable", "", "comma separated rules to enable")
+	disable := flags.String("disable", "", "comma separated rules to disable")
+	asJSON := flags.Bool("json", false, "print the problems as JSON array")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	config, err := lintConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if err := setRules(config, *enable, true); err != nil {
+		return err
+	}
+	if err := setRules(config, *disable, false); err != nil {
+		return err
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := scripts(paths)
+	if err != nil {
+		return err
+	}
+
+	diagnostics := []lint.Diagnostic{}
+	for _, file := range files {
+		diagnostics = append(diagnostics, lint.New(config).LintFile(file)...)
+	}
+	if *asJSON {
+		out, err := json.MarshalIndent(diagnostics, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, d := range diagnostics {
+			fmt.Println(d)
+		}
+	}
+	if len(diagnostics) != 0 {
+		return fmt.Errorf("found %d problems", len(diagnostics))
+	}
+	return nil
+}
+
+// lintConfig reads the config file, the default rashlint.json is optional
+func lintConfig(file string) (*lint.Config, error) {
+	if file != "" {
+		return lint.LoadConfig(file)
+	}
+	file = filepath.Join(loaders.Default.Root, "rashlint.json")
+	if _, err := os.Stat(file); err != nil {
+		return &lint.Config{}, nil
+	}
+	return lint.LoadConfig(file)
+}
+
+// languageServer serves the Language Server Protocol over stdin and stdout, the linter is configured
+// the same way as by `rash lint`
+func languageServer(args []string) error {
+	flags := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	flags.StringVar(&loaders.Default.Root, "root", "", "project root to search the included scripts in")
+	configFile := flags.String("config", "", "JSON config of lint rules, rashlint.json in the project root is used by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: rash lsp [-root <dir>] [-config <file>]")
+	}
+	config, err := lintConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	reg, err := plugins()
+	if err != nil {
+		return err
+	}
+	server := lsp.New(os.Stdin, os.Stdout)
+	server.Registry = reg
+	server.Lint = config
+	return server.Serve()
+}
+
+// debugAdapter serves Debug Adapter Protocol over stdin and stdout, the script is set by the launch request
+func debugAdapter(args []string) error {
+	flags := flag.NewFlagSet("dap", flag.ContinueOnError)
+	flags.StringVar(&loaders.Default.Root, "root", "", "project root to search the included scripts in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: rash dap [-root <dir>]")
+	}
+	// the adapter is started by editors for any project, the scripts which don't use plugins are debugged without them
+	if _, err := plugins(); err != nil {
+		log.Printf("plugins are not loaded: %v", err)
+		evaluator.InitRegistry(extensions.New())
+	}
+	return dap.New(os.Stdin, os.Stdout).Serve()
+}
+
+// debug runs the script with the terminal debugger, the script is stopped before the first statement
+func debug(args []string) error {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "report missing hash keys and array indexes as errors")
+	flags.StringVar(&loaders.Default.Root, "root", "", "project root to search the included scripts in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: rash debug [-strict] [-root <dir>] <script>")
+	}
+	if _, err := plugins(); err != nil {
+		return err
+	}
+	evaluator.Strict = *strict
+	d := debugger.New()
+	terminal := debugger.NewTerminal(d, flags.Arg(0), os.Stdin, os.Stdout)
+	evaluator.Debug = d
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runScript(flags.Arg(0))
+	}()
+	return terminal.Run(done)
+}
+
+// setRules enables or disables the comma separated rules
+func setRules(config *lint.Config, rules string, enabled bool) error {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		if err := config.Set(rule, enabled); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scripts returns the files and *.rs files of the directories, hidden directories and rash_modules are skipped
+func scripts(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case file == path && !info.IsDir():
+				files = append(files, file)
+			case info.IsDir() && file != path && (info.Name() == packages.Dir || strings.HasPrefix(info.Name(), ".")):
+				return filepath.SkipDir
+			case !info.IsDir() && filepath.Ext(file) == ".rs":
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func scriptError(errObj *objects.Error) error {
+	return fmt.Errorf("%s\nStackTrace:\n%s", errObj.Inspect(), strings.Join(errObj.Stack, ";\n"))
+}
+
+// plugins loads the plugins from bin and attaches them to the interpreter,
+// only the commands which evaluate or describe the plugin calls need them
+func plugins() (*extensions.Registry, error) {
+	reg, err := extensionsRegistry()
+	if err != nil {
+		return nil, err
+	}
+	evaluator.InitRegistry(reg)
+	return reg, nil
+}
+
 func extensionsRegistry() (*extensions.Registry, error) {
 	r := extensions.New()
 	if err := r.Add("bin/sys.so", "SysPlugin"); err != nil {
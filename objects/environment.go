@@ -1,7 +1,14 @@
 package objects
 
+import (
+	"sort"
+	"strings"
+)
+
 type Environment struct {
 	store                map[string]Object
+	constants            map[string]bool
+	exports              map[string]bool // exported names of module, nil if the module has no export markers
 	externalEnvironments map[string]*Environment
 	outer                *Environment
 }
@@ -9,6 +16,7 @@ type Environment struct {
 func NewEnvironment() *Environment {
 	return &Environment{
 		store:                map[string]Object{},
+		constants:            map[string]bool{},
 		externalEnvironments: map[string]*Environment{},
 	}
 }
@@ -34,13 +42,41 @@ func (e *Environment) Get(key string) (Object, bool) {
 }
 
 func (e *Environment) Set(key string, value Object) Object {
+	delete(e.constants, key)
 	e.store[key] = value
 	return value
 }
 
+// SetConst binds the value to the key which can't be reassigned later
+func (e *Environment) SetConst(key string, value Object) Object {
+	e.store[key] = value
+	e.constants[key] = true
+	return value
+}
+
+// IsConst checks if the key is resolved to a constant binding
+func (e *Environment) IsConst(key string) bool {
+	if _, ok := e.store[key]; ok {
+		return e.constants[key]
+	}
+	if e.outer == nil {
+		return false
+	}
+	return e.outer.IsConst(key)
+}
+
+// Has checks if the key is bound in the environment itself, the outer environments are not checked
+func (e *Environment) Has(key string) bool {
+	_, ok := e.store[key]
+	return ok
+}
+
 func (e *Environment) Update(key string, value Object) (Object, bool) {
 	val, ok := e.store[key]
 	if !ok {
+		if e.outer == nil {
+			return nil, false
+		}
 		return e.outer.Update(key, value)
 	}
 	e.store[key] = value
@@ -55,3 +91,52 @@ func (e *Environment) GetExternalEnvironment(alias string) (*Environment, bool)
 	env, ok := e.externalEnvironments[alias]
 	return env, ok
 }
+
+// Export marks the top-level name of module as public, the module with exported names hides all other ones
+func (e *Environment) Export(key string) {
+	if e.exports == nil {
+		e.exports = map[string]bool{}
+	}
+	e.exports[key] = true
+}
+
+// IsPublic checks if the name of module is visible to the including scripts: only exported names are public
+// if the module has export markers, otherwise the names which don't start with underscore
+func (e *Environment) IsPublic(key string) bool {
+	if e.exports != nil {
+		return e.exports[key]
+	}
+	return !strings.HasPrefix(key, "_")
+}
+
+// PublicNames returns the sorted public names bound in the environment itself and aliases of included modules
+func (e *Environment) PublicNames() []string {
+	names := []string{}
+	for key := range e.store {
+		if e.IsPublic(key) {
+			names = append(names, key)
+		}
+	}
+	for alias := range e.externalEnvironments {
+		if _, ok := e.store[alias]; !ok && e.IsPublic(alias) {
+			names = append(names, alias)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Names returns the sorted names bound in the environment itself, the outer environments are not listed
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for key := range e.store {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Outer returns the enclosing environment, nil for the top-level one
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
@@ -6,7 +6,10 @@ import (
 	"github.com/YReshetko/rash-lang/ast"
 	"hash/fnv"
 	"math"
+	"sort"
 	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 type ObjectType string
@@ -25,6 +28,15 @@ const (
 	ARRAY_OBJ        ObjectType = "ARRAY"
 	HASH_OBJ         ObjectType = "HASH"
 	EXTERNAL_ENV     ObjectType = "EXTERNAL"
+	PROMISE_OBJ      ObjectType = "PROMISE"
+	TASK_OBJ         ObjectType = "TASK"
+	CHANNEL_OBJ      ObjectType = "CHANNEL"
+	STRUCT_OBJ       ObjectType = "STRUCT"
+	INSTANCE_OBJ     ObjectType = "INSTANCE"
+	BREAK_OBJ        ObjectType = "BREAK"
+	CONTINUE_OBJ     ObjectType = "CONTINUE"
+	RANGE_OBJ        ObjectType = "RANGE"
+	ITERATOR_OBJ     ObjectType = "ITERATOR"
 )
 
 var (
@@ -275,6 +287,11 @@ func (s *String) Inspect() string {
 func (s *String) Type() ObjectType {
 	return STRING_OBJ
 }
+
+// Len returns number of characters (runes) in the string
+func (s *String) Len() int {
+	return utf8.RuneCountInString(s.Value)
+}
 func (s *String) HashKey() HashKey {
 	hash := fnv.New64()
 	_, _ = hash.Write([]byte(s.Value))
@@ -331,6 +348,68 @@ func (r *ReturnValue) Type() ObjectType {
 	return RETURN_VALUE_OBJ
 }
 
+// Break is a signal to stop the loop with the label, or the innermost loop if the label is empty
+type Break struct {
+	Label string
+}
+
+func (b *Break) Inspect() string {
+	return "break " + b.Label
+}
+
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+// Continue is a signal to start the next iteration of the loop with the label, or the innermost loop if the label is empty
+type Continue struct {
+	Label string
+}
+
+func (c *Continue) Inspect() string {
+	return "continue " + c.Label
+}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
+// Range is a sequence of integers from Start to End excluding End: 0..10
+type Range struct {
+	Start int64
+	End   int64
+}
+
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("%d..%d", r.Start, r.End)
+}
+
+func (r *Range) Type() ObjectType {
+	return RANGE_OBJ
+}
+
+// Len returns the number of integers in the range
+func (r *Range) Len() int64 {
+	if r.End < r.Start {
+		return 0
+	}
+	return r.End - r.Start
+}
+
+// Iterator is a native sequence of values, for example streaming results of a plugin.
+// Next returns false when there are no more values, or an Error object as the value if the sequence is broken
+type Iterator struct {
+	Next func() (Object, bool)
+}
+
+func (i *Iterator) Inspect() string {
+	return "iterator"
+}
+
+func (i *Iterator) Type() ObjectType {
+	return ITERATOR_OBJ
+}
+
 type Error struct {
 	Message string
 	Stack   []string
@@ -349,8 +428,12 @@ func (e *Error) AddStackLine(line string) {
 
 type Function struct {
 	Parameters  []*ast.Identifier
+	Patterns    map[int]ast.Expression
+	Defaults    map[int]ast.Expression
+	Rest        *ast.Identifier
 	Body        *ast.BlockStatement
 	Environment *Environment
+	Async       bool
 }
 
 func (f *Function) Type() ObjectType {
@@ -359,13 +442,11 @@ func (f *Function) Type() ObjectType {
 func (f *Function) Inspect() string {
 	out := bytes.Buffer{}
 
-	params := []string{}
-	for _, parameter := range f.Parameters {
-		params = append(params, parameter.String())
+	if f.Async {
+		out.WriteString("async ")
 	}
-
 	out.WriteString("fn(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.FormatParameters(f.Parameters, nil, f.Defaults, f.Rest))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
@@ -399,6 +480,7 @@ func (b *Builtin) Inspect() string {
 
 type Array struct {
 	Elements []Object
+	Frozen   bool
 }
 
 func (a *Array) Type() ObjectType {
@@ -420,13 +502,70 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+func (a *Array) Push(elements ...Object) {
+	a.Elements = append(a.Elements, elements...)
+}
+
+// Pop removes the last element and returns it, NULL is returned for empty array
+func (a *Array) Pop() Object {
+	if len(a.Elements) == 0 {
+		return NULL
+	}
+	last := a.Elements[len(a.Elements)-1]
+	a.Elements = a.Elements[:len(a.Elements)-1]
+	return last
+}
+
+// Slice returns a copy of elements in range [start, end), the bounds have to be valid
+func (a *Array) Slice(start, end int) *Array {
+	elements := make([]Object, end-start)
+	copy(elements, a.Elements[start:end])
+	return &Array{Elements: elements}
+}
+
+// Freeze makes arrays and hashes immutable including all nested arrays and hashes, other objects are not changed
+func Freeze(obj Object) Object {
+	switch value := obj.(type) {
+	case *Array:
+		if value.Frozen {
+			return value
+		}
+		value.Frozen = true
+		for _, element := range value.Elements {
+			Freeze(element)
+		}
+	case *Hash:
+		if value.Frozen {
+			return value
+		}
+		value.Frozen = true
+		for _, pair := range value.Pairs {
+			Freeze(pair.Value)
+		}
+	}
+	return obj
+}
+
+// IsFrozen checks if the object is a frozen array or hash
+func IsFrozen(obj Object) bool {
+	switch value := obj.(type) {
+	case *Array:
+		return value.Frozen
+	case *Hash:
+		return value.Frozen
+	default:
+		return false
+	}
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	Pairs  map[HashKey]HashPair
+	Frozen bool
 }
 
 func (a *Hash) Type() ObjectType {
@@ -437,10 +576,8 @@ func (a *Hash) Inspect() string {
 	out := bytes.Buffer{}
 
 	elements := make([]string, len(a.Pairs))
-	i := 0
-	for _, v := range a.Pairs {
+	for i, v := range a.SortedPairs() {
 		elements[i] = v.Key.Inspect() + ":" + v.Value.Inspect()
-		i++
 	}
 
 	out.WriteString("{")
@@ -449,3 +586,335 @@ func (a *Hash) Inspect() string {
 
 	return out.String()
 }
+
+func (a *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := a.Pairs[key.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	return pair.Value, true
+}
+
+func (a *Hash) Set(key Object, value Object) bool {
+	hashable, ok := key.(Hashable)
+	if !ok {
+		return false
+	}
+	a.Pairs[hashable.HashKey()] = HashPair{Key: key, Value: value}
+	return true
+}
+
+// Delete removes the key from hash and returns the removed value
+func (a *Hash) Delete(key Hashable) (Object, bool) {
+	pair, ok := a.Pairs[key.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	delete(a.Pairs, key.HashKey())
+	return pair.Value, true
+}
+
+// SortedPairs returns pairs ordered by key type and then by key value, so the hash can be traversed deterministically
+func (a *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(a.Pairs))
+	for _, pair := range a.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return lessKey(pairs[i].Key, pairs[j].Key)
+	})
+	return pairs
+}
+
+func (a *Hash) Keys() []Object {
+	pairs := a.SortedPairs()
+	keys := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key
+	}
+	return keys
+}
+
+func (a *Hash) Values() []Object {
+	pairs := a.SortedPairs()
+	values := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		values[i] = pair.Value
+	}
+	return values
+}
+
+func lessKey(left, right Object) bool {
+	if left.Type() != right.Type() {
+		return left.Type() < right.Type()
+	}
+	switch l := left.(type) {
+	case *Integer:
+		return l.Value < right.(*Integer).Value
+	case *Double:
+		return l.Value < right.(*Double).Value
+	case *Boolean:
+		return !l.Value && right.(*Boolean).Value
+	default:
+		return left.Inspect() < right.Inspect()
+	}
+}
+
+type PromiseState int
+
+const (
+	PENDING PromiseState = iota
+	FULFILLED
+	REJECTED
+)
+
+// Promise is a placeholder for a value produced by asynchronous work scheduled on the event loop.
+// Rejected promise keeps an *Error as its value.
+type Promise struct {
+	mu        sync.Mutex
+	state     PromiseState
+	value     Object
+	callbacks []func(*Promise)
+	done      chan struct{}
+}
+
+func NewPromise() *Promise {
+	return &Promise{done: make(chan struct{})}
+}
+
+func (p *Promise) Type() ObjectType {
+	return PROMISE_OBJ
+}
+
+func (p *Promise) Inspect() string {
+	state, value := p.Result()
+	switch state {
+	case FULFILLED:
+		return "promise<fulfilled: " + value.Inspect() + ">"
+	case REJECTED:
+		return "promise<rejected: " + value.Inspect() + ">"
+	default:
+		return "promise<pending>"
+	}
+}
+
+// Result returns current state of the promise and the settled value if any
+func (p *Promise) Result() (PromiseState, Object) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state, p.value
+}
+
+// Done returns a channel which is closed when the promise is settled
+func (p *Promise) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *Promise) Settled() bool {
+	state, _ := p.Result()
+	return state != PENDING
+}
+
+func (p *Promise) Resolve(value Object) bool {
+	return p.settle(FULFILLED, value)
+}
+
+func (p *Promise) Reject(err *Error) bool {
+	return p.settle(REJECTED, err)
+}
+
+// OnSettle registers the callback which is called once the promise is settled.
+// If the promise is already settled the callback is called immediately.
+func (p *Promise) OnSettle(callback func(*Promise)) {
+	p.mu.Lock()
+	if p.state == PENDING {
+		p.callbacks = append(p.callbacks, callback)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	callback(p)
+}
+
+func (p *Promise) settle(state PromiseState, value Object) bool {
+	p.mu.Lock()
+	if p.state != PENDING {
+		p.mu.Unlock()
+		return false
+	}
+	p.state = state
+	p.value = value
+	callbacks := p.callbacks
+	p.callbacks = nil
+	close(p.done)
+	p.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(p)
+	}
+	return true
+}
+
+// Task is a handle of a function evaluated by `spawn`
+type Task struct {
+	result Object
+	done   chan struct{}
+}
+
+func NewTask() *Task {
+	return &Task{done: make(chan struct{})}
+}
+
+func (t *Task) Type() ObjectType {
+	return TASK_OBJ
+}
+
+func (t *Task) Inspect() string {
+	result, ok := t.Result()
+	if !ok {
+		return "task<running>"
+	}
+	return "task<done: " + result.Inspect() + ">"
+}
+
+// Done returns a channel which is closed when the task is completed
+func (t *Task) Done() <-chan struct{} {
+	return t.done
+}
+
+// Complete stores the result of the task, must be called once
+func (t *Task) Complete(result Object) {
+	t.result = result
+	close(t.done)
+}
+
+func (t *Task) Result() (Object, bool) {
+	select {
+	case <-t.done:
+		return t.result, true
+	default:
+		return nil, false
+	}
+}
+
+// Channel passes values between tasks, unbuffered channel is created with zero capacity
+type Channel struct {
+	mu     sync.Mutex
+	ch     chan Object
+	closed bool
+}
+
+func NewChannel(capacity int) *Channel {
+	return &Channel{ch: make(chan Object, capacity)}
+}
+
+func (c *Channel) Type() ObjectType {
+	return CHANNEL_OBJ
+}
+
+func (c *Channel) Inspect() string {
+	return fmt.Sprintf("channel<%d/%d>", len(c.ch), cap(c.ch))
+}
+
+func (c *Channel) Chan() chan Object {
+	return c.ch
+}
+
+// Close closes the channel, returns false if the channel is already closed
+func (c *Channel) Close() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.closed = true
+	close(c.ch)
+	return true
+}
+
+// Struct is a user defined type, calling the struct creates its instance.
+// Default values of fields are evaluated in the struct environment for each new instance.
+type Struct struct {
+	Name        string
+	Fields      []string
+	Defaults    map[string]ast.Expression
+	Methods     map[string]*Function
+	Environment *Environment
+}
+
+func (s *Struct) Type() ObjectType {
+	return STRUCT_OBJ
+}
+
+func (s *Struct) Inspect() string {
+	return "struct " + s.Name
+}
+
+// FieldIndex returns the index of field by its name
+func (s *Struct) FieldIndex(name string) (int, bool) {
+	for i, field := range s.Fields {
+		if field == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Instance is a value of struct, values are ordered as the struct fields
+type Instance struct {
+	Struct *Struct
+	Values []Object
+}
+
+func NewInstance(s *Struct) *Instance {
+	values := make([]Object, len(s.Fields))
+	for i := range values {
+		values[i] = NULL
+	}
+	return &Instance{Struct: s, Values: values}
+}
+
+func (i *Instance) Type() ObjectType {
+	return INSTANCE_OBJ
+}
+
+func (i *Instance) Inspect() string {
+	fields := make([]string, len(i.Values))
+	for ind, value := range i.Values {
+		fields[ind] = i.Struct.Fields[ind] + ": " + value.Inspect()
+	}
+	return i.Struct.Name + "{" + strings.Join(fields, ", ") + "}"
+}
+
+func (i *Instance) Get(name string) (Object, bool) {
+	ind, ok := i.Struct.FieldIndex(name)
+	if !ok {
+		return nil, false
+	}
+	return i.Values[ind], true
+}
+
+// Set updates the field, returns false if the struct has no such field
+func (i *Instance) Set(name string, value Object) bool {
+	ind, ok := i.Struct.FieldIndex(name)
+	if !ok {
+		return false
+	}
+	i.Values[ind] = value
+	return true
+}
+
+// Equal compares instances by reference the same way as they are hashed, the fields may be changed at any time
+func (i *Instance) Equal(other *Instance) bool {
+	return i == other
+}
+
+// HashKey identifies the instance by reference, the fields may be changed after the instance is used as a hash key
+func (i *Instance) HashKey() HashKey {
+	hash := fnv.New64()
+	_, _ = fmt.Fprintf(hash, "%p", i)
+	return HashKey{
+		Type:  i.Type(),
+		Value: hash.Sum64(),
+	}
+}
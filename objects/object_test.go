@@ -16,3 +16,90 @@ func TestStringHashKey(t *testing.T) {
 	assert.Equal(t, value1.HashKey(), value2.HashKey())
 	assert.NotEqual(t, key1.HashKey(), value1.HashKey())
 }
+
+func TestArrayPushPopSlice(t *testing.T) {
+	arr := &objects.Array{Elements: []objects.Object{&objects.Integer{Value: 1}}}
+	arr.Push(&objects.Integer{Value: 2}, &objects.Integer{Value: 3})
+	assert.Equal(t, "[1, 2, 3]", arr.Inspect())
+
+	slice := arr.Slice(1, 3)
+	assert.Equal(t, "[2, 3]", slice.Inspect())
+	slice.Elements[0] = objects.NULL
+	assert.Equal(t, "[1, 2, 3]", arr.Inspect())
+
+	assert.Equal(t, "3", arr.Pop().Inspect())
+	assert.Equal(t, "[1, 2]", arr.Inspect())
+	assert.Equal(t, objects.NULL, (&objects.Array{}).Pop())
+}
+
+func TestHashSortedKeys(t *testing.T) {
+	hash := &objects.Hash{Pairs: map[objects.HashKey]objects.HashPair{}}
+	hash.Set(&objects.String{Value: "b"}, &objects.Integer{Value: 2})
+	hash.Set(&objects.Integer{Value: 10}, &objects.Integer{Value: 3})
+	hash.Set(&objects.Integer{Value: 9}, &objects.Integer{Value: 4})
+	hash.Set(&objects.String{Value: "a"}, &objects.Integer{Value: 1})
+	assert.False(t, hash.Set(&objects.Array{}, objects.NULL))
+
+	assert.Equal(t, "{9:4, 10:3, a:1, b:2}", hash.Inspect())
+
+	value, ok := hash.Delete(&objects.String{Value: "a"})
+	assert.True(t, ok)
+	assert.Equal(t, "1", value.Inspect())
+	_, ok = hash.Get(&objects.String{Value: "a"})
+	assert.False(t, ok)
+}
+
+func TestInstanceEqualityAndHashKey(t *testing.T) {
+	point := &objects.Struct{Name: "Point", Fields: []string{"x", "y"}}
+	other := &objects.Struct{Name: "Point", Fields: []string{"x", "y"}}
+	shared := &objects.Array{}
+
+	newPoint := func(s *objects.Struct, x, y objects.Object) *objects.Instance {
+		instance := objects.NewInstance(s)
+		instance.Set("x", x)
+		instance.Set("y", y)
+		return instance
+	}
+
+	p1 := newPoint(point, &objects.Integer{Value: 1}, shared)
+	p2 := newPoint(point, &objects.Integer{Value: 1}, shared)
+	p3 := newPoint(point, &objects.Integer{Value: 1}, &objects.Array{})
+	p4 := newPoint(other, &objects.Integer{Value: 1}, shared)
+
+	assert.True(t, p1.Equal(p1))
+	assert.False(t, p1.Equal(p2))
+	assert.NotEqual(t, p1.HashKey(), p2.HashKey())
+	assert.Equal(t, p1.HashKey(), p1.HashKey())
+	assert.False(t, p1.Equal(p3))
+	assert.NotEqual(t, p1.HashKey(), p3.HashKey())
+	assert.False(t, p1.Equal(p4))
+	assert.NotEqual(t, p1.HashKey(), p4.HashKey())
+
+	key := p1.HashKey()
+	p1.Set("x", &objects.Integer{Value: 2})
+	assert.Equal(t, key, p1.HashKey())
+
+	self := newPoint(point, objects.NULL, objects.NULL)
+	self.Set("x", self)
+	assert.True(t, self.Equal(self))
+	assert.Equal(t, self.HashKey(), self.HashKey())
+
+	assert.False(t, p1.Set("z", objects.NULL))
+	assert.Equal(t, "Point{x: 2, y: []}", p1.Inspect())
+	assert.Equal(t, "Point{x: null, y: null}", objects.NewInstance(point).Inspect())
+}
+
+func TestEnvironmentPublicNames(t *testing.T) {
+	env := objects.NewEnvironment()
+	env.Set("b", objects.NULL)
+	env.Set("a", objects.NULL)
+	env.Set("_c", objects.NULL)
+	env.AddExternalEnvironment("m", objects.NewEnvironment())
+	assert.Equal(t, []string{"a", "b", "m"}, env.PublicNames())
+	assert.False(t, env.IsPublic("_c"))
+
+	env.Export("b")
+	assert.Equal(t, []string{"b"}, env.PublicNames())
+	assert.False(t, env.IsPublic("a"))
+	assert.True(t, env.IsPublic("b"))
+}
@@ -0,0 +1,126 @@
+package packages
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Fetcher copies the files of dependency into the empty directory dir.
+// The location is the source of requirement without the fetcher scheme
+type Fetcher interface {
+	Fetch(location, version, dir string) error
+}
+
+// Fetchers are selected by the scheme of requirement source `scheme:location`,
+// the source without a known scheme is a local directory relative to the project root
+var Fetchers = map[string]Fetcher{
+	"file":     DirFetcher{},
+	"git":      GitFetcher{},
+	"registry": RegistryFetcher{},
+}
+
+// splitSource returns the fetcher scheme and location of requirement source
+func splitSource(source string) (string, string) {
+	if i := strings.Index(source, ":"); i > 0 {
+		if _, ok := Fetchers[source[:i]]; ok {
+			return source[:i], source[i+1:]
+		}
+	}
+	return "file", source
+}
+
+// DirFetcher copies a local directory, the version is ignored
+type DirFetcher struct{}
+
+func (DirFetcher) Fetch(location, _, dir string) error {
+	return copyDir(location, dir)
+}
+
+// GitFetcher clones the repository, the version is a tag or a branch, the default branch is used without version
+type GitFetcher struct{}
+
+func (GitFetcher) Fetch(location, version, dir string) error {
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if version != "" {
+		args = append(args, "--branch", version)
+	}
+	// the location goes after -- so a source starting with a dash isn't read as a git option
+	out, err := exec.Command("git", append(args, "--", location, dir)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to clone %s: %v\n%s", location, err, out)
+	}
+	return os.RemoveAll(filepath.Join(dir, ".git"))
+}
+
+// RegistryFetcher copies the package from the file-based registry laid out as <root>/<name>/<version>.
+// The root is RASH_REGISTRY if it's empty
+type RegistryFetcher struct {
+	Root string
+}
+
+func (f RegistryFetcher) Fetch(location, version, dir string) error {
+	root := f.Root
+	if root == "" {
+		root = os.Getenv("RASH_REGISTRY")
+	}
+	if root == "" {
+		return errors.New("registry is not defined, set RASH_REGISTRY")
+	}
+	if version == "" {
+		return fmt.Errorf("registry package %s requires a version", location)
+	}
+	return copyDir(filepath.Join(root, location, version), dir)
+}
+
+// copyDir copies the regular files of the tree src into dst, VCS directories are skipped
+func copyDir(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", src)
+	}
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir() && info.Name() == ".git":
+			return filepath.SkipDir
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode().IsRegular():
+			return copyFile(path, target)
+		}
+		return nil
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,143 @@
+package packages
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// ManifestFile lists the dependencies of the project
+	ManifestFile = "rash.mod"
+	// LockFile pins the sources, versions and checksums of vendored dependencies
+	LockFile = "rash.lock"
+	// Dir is the module cache in the project root, the included scripts are searched in it by package name
+	Dir = "rash_modules"
+)
+
+// Requirement is a dependency of the project: require <name> <source> [<version>]
+type Requirement struct {
+	Name    string
+	Source  string
+	Version string
+}
+
+// Manifest is the parsed rash.mod:
+//
+//	module app
+//	require utils ./libs/utils
+//	require http git:https://github.com/user/http-rs.git v1.0.0
+type Manifest struct {
+	Module   string
+	Requires []Requirement
+}
+
+// Locked is the requirement pinned by the lockfile with the checksum of vendored files
+type Locked struct {
+	Requirement
+	Sum string
+}
+
+// ParseManifest parses rash.mod, the lines starting with # are comments
+func ParseManifest(src string) (*Manifest, error) {
+	manifest := &Manifest{}
+	names := map[string]bool{}
+	err := parseLines(src, func(line int, fields []string) error {
+		switch fields[0] {
+		case "module":
+			if len(fields) != 2 {
+				return fmt.Errorf("%s:%d: module expects a name", ManifestFile, line)
+			}
+			manifest.Module = fields[1]
+		case "require":
+			if len(fields) != 3 && len(fields) != 4 {
+				return fmt.Errorf("%s:%d: require expects name, source and optional version", ManifestFile, line)
+			}
+			requirement := Requirement{Name: fields[1], Source: fields[2]}
+			if len(fields) == 4 {
+				requirement.Version = fields[3]
+			}
+			if !validName(requirement.Name) {
+				return fmt.Errorf("%s:%d: invalid requirement name %s, expected a directory name", ManifestFile, line, requirement.Name)
+			}
+			if names[requirement.Name] {
+				return fmt.Errorf("%s:%d: duplicate requirement %s", ManifestFile, line, requirement.Name)
+			}
+			names[requirement.Name] = true
+			manifest.Requires = append(manifest.Requires, requirement)
+		default:
+			return fmt.Errorf("%s:%d: unknown directive %s", ManifestFile, line, fields[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// validName checks the requirement is vendored into a directory of rash_modules: the name isn't absolute
+// and has no path separators and `..`
+func validName(name string) bool {
+	return name != "" && name != "." && !filepath.IsAbs(name) && !strings.Contains(name, "..") && !strings.ContainsAny(name, `/\`)
+}
+
+// ParseLock parses rash.lock: <name> <source> <version> <checksum>, the version of unversioned requirement is `-`
+func ParseLock(src string) (map[string]Locked, error) {
+	locked := map[string]Locked{}
+	err := parseLines(src, func(line int, fields []string) error {
+		if len(fields) != 4 {
+			return fmt.Errorf("%s:%d: expected name, source, version and checksum", LockFile, line)
+		}
+		l := Locked{Requirement: Requirement{Name: fields[0], Source: fields[1], Version: fields[2]}, Sum: fields[3]}
+		if !validName(l.Name) {
+			return fmt.Errorf("%s:%d: invalid requirement name %s, expected a directory name", LockFile, line, l.Name)
+		}
+		if l.Version == "-" {
+			l.Version = ""
+		}
+		locked[l.Name] = l
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return locked, nil
+}
+
+// FormatLock writes the lockfile sorted by package names
+func FormatLock(locked map[string]Locked) string {
+	names := make([]string, 0, len(locked))
+	for name := range locked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := strings.Builder{}
+	out.WriteString("# generated by `rash mod`, do not edit\n")
+	for _, name := range names {
+		l := locked[name]
+		version := l.Version
+		if version == "" {
+			version = "-"
+		}
+		out.WriteString(fmt.Sprintf("%s %s %s %s\n", l.Name, l.Source, version, l.Sum))
+	}
+	return out.String()
+}
+
+func parseLines(src string, parse func(line int, fields []string) error) error {
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := parse(line, strings.Fields(text)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,138 @@
+package packages_test
+
+import (
+	"github.com/YReshetko/rash-lang/packages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	manifest, err := packages.ParseManifest(`
+		# dependencies
+		module app
+		require utils ./libs/utils
+		require json registry:json 1.2.0
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, &packages.Manifest{
+		Module: "app",
+		Requires: []packages.Requirement{
+			{Name: "utils", Source: "./libs/utils"},
+			{Name: "json", Source: "registry:json", Version: "1.2.0"},
+		},
+	}, manifest)
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"module", "rash.mod:1: module expects a name"},
+		{"require utils", "rash.mod:1: require expects name, source and optional version"},
+		{"require a ./a\nrequire a ./b", "rash.mod:2: duplicate requirement a"},
+		{"replace a ./a", "rash.mod:1: unknown directive replace"},
+		{"require ../../outside ./lib", "rash.mod:1: invalid requirement name ../../outside, expected a directory name"},
+		{"require lib/utils ./lib", "rash.mod:1: invalid requirement name lib/utils, expected a directory name"},
+		{`require lib\utils ./lib`, `rash.mod:1: invalid requirement name lib\utils, expected a directory name`},
+		{"require /tmp/lib ./lib", "rash.mod:1: invalid requirement name /tmp/lib, expected a directory name"},
+		{"require .. ./lib", "rash.mod:1: invalid requirement name .., expected a directory name"},
+	}
+	for _, test := range errors {
+		_, err := packages.ParseManifest(test.input)
+		require.Error(t, err, test.input)
+		assert.Equal(t, test.expected, err.Error(), test.input)
+	}
+}
+
+func TestLock(t *testing.T) {
+	locked := map[string]packages.Locked{
+		"b": {Requirement: packages.Requirement{Name: "b", Source: "./b"}, Sum: "sha256:02"},
+		"a": {Requirement: packages.Requirement{Name: "a", Source: "registry:a", Version: "1.0"}, Sum: "sha256:01"},
+	}
+	src := packages.FormatLock(locked)
+	assert.Equal(t, "# generated by `rash mod`, do not edit\na registry:a 1.0 sha256:01\nb ./b - sha256:02\n", src)
+
+	parsed, err := packages.ParseLock(src)
+	require.NoError(t, err)
+	assert.Equal(t, locked, parsed)
+
+	_, err = packages.ParseLock("../a ./a - sha256:01")
+	assert.EqualError(t, err, "rash.lock:1: invalid requirement name ../a, expected a directory name")
+}
+
+func TestSync(t *testing.T) {
+	root := t.TempDir()
+	registry := t.TempDir()
+	write(t, filepath.Join(root, "libs", "utils", "main.rs"), `let x = 1;`)
+	write(t, filepath.Join(registry, "json", "1.2.0", "main.rs"), `let parse = fn(s) { s };`)
+	write(t, filepath.Join(root, packages.ManifestFile), "module app\nrequire utils ./libs/utils\nrequire json registry:json 1.2.0\n")
+
+	packages.Fetchers["registry"] = packages.RegistryFetcher{Root: registry}
+	defer func() { packages.Fetchers["registry"] = packages.RegistryFetcher{} }()
+
+	locked, err := packages.Sync(root)
+	require.NoError(t, err)
+	require.Len(t, locked, 2)
+	assert.FileExists(t, filepath.Join(root, packages.Dir, "utils", "main.rs"))
+	assert.FileExists(t, filepath.Join(root, packages.Dir, "json", "main.rs"))
+	assert.FileExists(t, filepath.Join(root, packages.LockFile))
+	require.NoError(t, packages.Verify(root))
+
+	// the vendored package is modified
+	write(t, filepath.Join(root, packages.Dir, "utils", "main.rs"), `let x = 2;`)
+	assert.EqualError(t, packages.Verify(root), "utils is modified: rash.lock has "+locked["utils"].Sum+", rash_modules has "+checksum(t, filepath.Join(root, packages.Dir, "utils")))
+
+	// the sync restores the vendored package
+	_, err = packages.Sync(root)
+	require.NoError(t, err)
+	require.NoError(t, packages.Verify(root))
+
+	// the locked version is changed in the registry
+	write(t, filepath.Join(registry, "json", "1.2.0", "main.rs"), `let parse = fn(s) { null };`)
+	_, err = packages.Sync(root)
+	assert.EqualError(t, err, "checksum mismatch for json: rash.lock has "+locked["json"].Sum+", fetched "+checksum(t, filepath.Join(registry, "json", "1.2.0")))
+
+	// the new requirement isn't locked
+	write(t, filepath.Join(root, packages.ManifestFile), "require utils ./libs/utils\nrequire json registry:json 1.2.0\nrequire more ./libs/utils\n")
+	assert.EqualError(t, packages.Verify(root), "more is not locked, run `rash mod`")
+}
+
+func TestSyncErrors(t *testing.T) {
+	root := t.TempDir()
+	_, err := packages.Sync(root)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read rash.mod")
+
+	write(t, filepath.Join(root, packages.ManifestFile), "require utils ./missing\n")
+	_, err = packages.Sync(root)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to fetch utils")
+
+	write(t, filepath.Join(root, packages.ManifestFile), "require json registry:json\n")
+	packages.Fetchers["registry"] = packages.RegistryFetcher{Root: root}
+	defer func() { packages.Fetchers["registry"] = packages.RegistryFetcher{} }()
+	_, err = packages.Sync(root)
+	assert.EqualError(t, err, "unable to fetch json: registry package json requires a version")
+}
+
+func write(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func checksum(t *testing.T, dir string) string {
+	sum, err := packages.Checksum(dir)
+	require.NoError(t, err)
+	return sum
+}
+
+func TestGitFetcherLocationIsNotOption(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	err := packages.GitFetcher{}.Fetch("--upload-pack=touch "+marker, "", filepath.Join(t.TempDir(), "pkg"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "repository '--upload-pack=touch "+marker+"' does not exist")
+	assert.NoFileExists(t, marker)
+}
@@ -0,0 +1,151 @@
+package packages
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Sync vendors the dependencies of rash.mod in the project root into rash_modules and writes rash.lock.
+// The dependency which source and version are locked must have the locked checksum
+func Sync(root string) (map[string]Locked, error) {
+	manifest, locked, err := read(root)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := ioutil.TempDir("", "rash-mod")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	result := map[string]Locked{}
+	for _, requirement := range manifest.Requires {
+		fetched := filepath.Join(tmp, requirement.Name)
+		if err := fetch(root, requirement, fetched); err != nil {
+			return nil, fmt.Errorf("unable to fetch %s: %v", requirement.Name, err)
+		}
+		sum, err := Checksum(fetched)
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := locked[requirement.Name]; ok && l.Requirement == requirement && l.Sum != sum {
+			return nil, fmt.Errorf("checksum mismatch for %s: %s has %s, fetched %s", requirement.Name, LockFile, l.Sum, sum)
+		}
+		result[requirement.Name] = Locked{Requirement: requirement, Sum: sum}
+	}
+
+	modules := filepath.Join(root, Dir)
+	if err := os.RemoveAll(modules); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(modules, 0755); err != nil {
+		return nil, err
+	}
+	for name := range result {
+		if err := copyDir(filepath.Join(tmp, name), filepath.Join(modules, name)); err != nil {
+			return nil, err
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, LockFile), []byte(FormatLock(result)), 0644); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Verify checks the vendored dependencies match rash.mod and the checksums of rash.lock
+func Verify(root string) error {
+	manifest, locked, err := read(root)
+	if err != nil {
+		return err
+	}
+	for _, requirement := range manifest.Requires {
+		l, ok := locked[requirement.Name]
+		if !ok || l.Requirement != requirement {
+			return fmt.Errorf("%s is not locked, run `rash mod`", requirement.Name)
+		}
+		sum, err := Checksum(filepath.Join(root, Dir, requirement.Name))
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s is not vendored, run `rash mod`", requirement.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if sum != l.Sum {
+			return fmt.Errorf("%s is modified: %s has %s, %s has %s", requirement.Name, LockFile, l.Sum, Dir, sum)
+		}
+	}
+	return nil
+}
+
+func read(root string) (*Manifest, map[string]Locked, error) {
+	src, err := ioutil.ReadFile(filepath.Join(root, ManifestFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read %s: %v", ManifestFile, err)
+	}
+	manifest, err := ParseManifest(string(src))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	locked := map[string]Locked{}
+	src, err = ioutil.ReadFile(filepath.Join(root, LockFile))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, nil, fmt.Errorf("unable to read %s: %v", LockFile, err)
+	default:
+		if locked, err = ParseLock(string(src)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return manifest, locked, nil
+}
+
+// fetch copies the requirement into dir, local directories are relative to the project root
+func fetch(root string, requirement Requirement, dir string) error {
+	scheme, location := splitSource(requirement.Source)
+	if scheme == "file" && !filepath.IsAbs(location) {
+		location = filepath.Join(root, location)
+	}
+	return Fetchers[scheme].Fetch(location, requirement.Version, dir)
+}
+
+// Checksum hashes the relative paths and contents of the files in the directory
+func Checksum(dir string) (string, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return "", err
+	}
+	files := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, path := range files {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %x\n", filepath.ToSlash(rel), sha256.Sum256(content))
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/YReshetko/rash-lang/lexer"
 	"github.com/YReshetko/rash-lang/tokens"
 	"strconv"
+	"strings"
 )
 
 type (
@@ -20,6 +21,11 @@ type Parser struct {
 	peekToken tokens.Token
 
 	errors []string
+	scopes []scope
+	// labels of the loops enclosing the current statement, an unlabeled loop has empty label
+	loops []string
+	// label for the next parsed loop
+	label *ast.Identifier
 
 	prefixParseFns map[tokens.TokenType]prefixParseFn
 	infixParseFns  map[tokens.TokenType]infixParseFn
@@ -29,6 +35,7 @@ func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:              l,
 		errors:         []string{},
+		scopes:         []scope{{}},
 		prefixParseFns: map[tokens.TokenType]prefixParseFn{},
 		infixParseFns:  map[tokens.TokenType]infixParseFn{},
 	}
@@ -38,6 +45,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(tokens.DOUBLE, p.parseDoubleLiteral)
 	p.registerPrefix(tokens.TRUE, p.parseBooleanLiteral)
 	p.registerPrefix(tokens.FALSE, p.parseBooleanLiteral)
+	p.registerPrefix(tokens.NULL, p.parseNullLiteral)
 	p.registerPrefix(tokens.BANG, p.parsePrefixExpression)
 	p.registerPrefix(tokens.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(tokens.LPAREN, p.parseGroupedExpression)
@@ -45,21 +53,32 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(tokens.FOR, p.parseForExpression)
 	p.registerPrefix(tokens.LET, p.parseLetExpression)
 	p.registerPrefix(tokens.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(tokens.ASYNC, p.parseAsyncFunctionLiteral)
+	p.registerPrefix(tokens.AWAIT, p.parseAwaitExpression)
+	p.registerPrefix(tokens.SPAWN, p.parseSpawnExpression)
+	p.registerPrefix(tokens.SELECT, p.parseSelectExpression)
+	p.registerPrefix(tokens.MATCH, p.parseMatchExpression)
+	p.registerPrefix(tokens.ELLIPSIS, p.parseSpreadExpression)
 	p.registerPrefix(tokens.STRING, p.parseStringLiteral)
+	p.registerPrefix(tokens.TEMPLATE, p.parseTemplateLiteral)
 	p.registerPrefix(tokens.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(tokens.LBRACE, p.parseHashLiteral)
 
+	p.registerInfix(tokens.NULLISH, p.parseInfixExpression)
 	p.registerInfix(tokens.EQ, p.parseInfixExpression)
 	p.registerInfix(tokens.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(tokens.LT, p.parseInfixExpression)
 	p.registerInfix(tokens.GT, p.parseInfixExpression)
+	p.registerInfix(tokens.RANGE, p.parseInfixExpression)
 	p.registerInfix(tokens.PLUS, p.parseInfixExpression)
 	p.registerInfix(tokens.MINUS, p.parseInfixExpression)
 	p.registerInfix(tokens.SLASH, p.parseInfixExpression)
 	p.registerInfix(tokens.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(tokens.LPAREN, p.parseCallExpression)
 	p.registerInfix(tokens.DOT, p.parseInfixExpression)
+	p.registerInfix(tokens.OPTIONAL_DOT, p.parseOptionalChain)
 	p.registerInfix(tokens.LBRACKET, p.parseInfixIndexExpression)
+	p.registerInfix(tokens.OPTIONAL_LBRACKET, p.parseInfixIndexExpression)
 	p.registerInfix(tokens.ASSIGN, p.parseInfixExpression)
 
 	// Call twice to set current and peek tokens
@@ -90,12 +109,22 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currToken.Type {
-	case tokens.LET:
+	case tokens.LET, tokens.CONST:
 		return p.parseLetStatement()
 	case tokens.RETURN:
 		return p.parseReturnStatement()
 	case tokens.HASH:
 		return p.parseIncludeDeclarationStatement()
+	case tokens.EXPORT:
+		return p.parseExportStatement()
+	case tokens.STRUCT:
+		return p.parseStructStatement()
+	case tokens.BREAK, tokens.CONTINUE:
+		return p.parseBranchStatement()
+	case tokens.IDENT:
+		if p.peekTokenIs(tokens.COLON) {
+			return p.parseLabeledStatement()
+		}
 	}
 	return p.parseExpressionStatement()
 }
@@ -104,21 +133,37 @@ func (p *Parser) parseLetStatement() ast.Statement {
 	defer untrace(trace("parseLetStatement"))
 	statement := &ast.LetStatement{Token: p.currToken}
 
-	if !p.expectPeekToken(tokens.IDENT) {
-		return nil
+	if p.peekTokenIs(tokens.LBRACKET) || p.peekTokenIs(tokens.LBRACE) {
+		p.nextToken()
+		statement.Pattern = p.parsePattern()
+		if statement.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeekToken(tokens.IDENT) {
+			return nil
+		}
+		statement.Name = &ast.Identifier{
+			Token: p.currToken,
+			Value: p.currToken.Literal,
+		}
 	}
 
-	statement.Name = &ast.Identifier{
-		Token: p.currToken,
-		Value: p.currToken.Literal,
+	var ok bool
+	if statement.Type, ok = p.parseOptionalType(); !ok {
+		return nil
 	}
-
 	if !p.expectPeekToken(tokens.ASSIGN) {
 		return nil
 	}
 
 	p.nextToken()
 	statement.Value = p.parseExpression(LOWEST)
+	if statement.Pattern != nil {
+		p.declare(statement.Pattern, statement.IsConst(), statement.Token.LineNumber)
+	} else {
+		p.declare(statement.Name, statement.IsConst(), statement.Token.LineNumber)
+	}
 
 	if p.peekTokenIs(tokens.SEMICOLON) {
 		p.nextToken()
@@ -152,20 +197,35 @@ func (p *Parser) parseIncludeDeclarationStatement() ast.Statement {
 	statement := &ast.DeclarationStatement{
 		Token: p.currToken,
 	}
-	if !p.expectPeekToken(tokens.IDENT) {
-		return nil
-	}
+	include := &ast.IncludeDeclaration{Token: statement.Token}
 
-	alias := &ast.Identifier{
-		Token: p.currToken,
-		Value: p.currToken.Literal,
+	switch {
+	case p.peekTokenIs(tokens.LBRACE):
+		p.nextToken()
+		if include.Names = p.parseImportNames(); include.Names == nil {
+			return nil
+		}
+	case p.peekTokenIs(tokens.ASTERISK):
+		p.nextToken()
+		include.Wildcard = true
+	default:
+		if !p.expectPeekToken(tokens.IDENT) {
+			return nil
+		}
+		include.Alias = &ast.Identifier{
+			Token: p.currToken,
+			Value: p.currToken.Literal,
+		}
 	}
 
+	if include.Alias == nil && !p.expectContextual("from") {
+		return nil
+	}
 	if !p.expectPeekToken(tokens.STRING) {
 		return nil
 	}
 
-	include := &ast.StringLiteral{
+	include.Include = &ast.StringLiteral{
 		Token: p.currToken,
 		Value: p.currToken.Literal,
 	}
@@ -173,10 +233,73 @@ func (p *Parser) parseIncludeDeclarationStatement() ast.Statement {
 	if p.peekTokenIs(tokens.SEMICOLON) {
 		p.nextToken()
 	}
-	statement.Declaration = &ast.IncludeDeclaration{
-		Token:   statement.Token,
-		Alias:   alias,
-		Include: include,
+	statement.Declaration = include
+	return statement
+}
+
+// parseImportNames parses `{a, b as c}` of selective import, `from` and `as` are not reserved words
+func (p *Parser) parseImportNames() []*ast.ImportName {
+	names := []*ast.ImportName{}
+	bound := map[string]bool{}
+	for !p.peekTokenIs(tokens.RBRACE) {
+		if !p.expectPeekToken(tokens.IDENT) {
+			return nil
+		}
+		name := &ast.ImportName{Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+		if p.peekTokenIs(tokens.IDENT) && p.peekToken.Literal == "as" {
+			p.nextToken()
+			if !p.expectPeekToken(tokens.IDENT) {
+				return nil
+			}
+			name.Alias = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		}
+		if bound[name.Bound().Value] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate imported name %s on line %d", name.Bound().Value, p.currToken.LineNumber))
+			return nil
+		}
+		bound[name.Bound().Value] = true
+		names = append(names, name)
+		if !p.peekTokenIs(tokens.RBRACE) && !p.expectPeekToken(tokens.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	if len(names) == 0 {
+		p.errors = append(p.errors, fmt.Sprintf("empty import list on line %d", p.currToken.LineNumber))
+		return nil
+	}
+	return names
+}
+
+// expectContextual moves to the next token if it's the identifier which is a keyword in this context only
+func (p *Parser) expectContextual(word string) bool {
+	if p.peekTokenIs(tokens.IDENT) && p.peekToken.Literal == word {
+		p.nextToken()
+		return true
+	}
+	p.errors = append(p.errors, fmt.Sprintf("expected %s on line %d; instead got %s", word, p.peekToken.LineNumber, p.peekToken.Literal))
+	return false
+}
+
+// parseExportStatement parses `export` followed by let, const, struct or include declaration at the top level of module
+func (p *Parser) parseExportStatement() ast.Statement {
+	defer untrace(trace("parseExportStatement"))
+	statement := &ast.ExportStatement{Token: p.currToken}
+	if len(p.scopes) != 1 {
+		p.errors = append(p.errors, fmt.Sprintf("export is allowed only at the top level on line %d", p.currToken.LineNumber))
+		return nil
+	}
+
+	p.nextToken()
+	switch p.currToken.Type {
+	case tokens.LET, tokens.CONST, tokens.STRUCT, tokens.HASH:
+		statement.Statement = p.parseStatement()
+	default:
+		p.errors = append(p.errors, fmt.Sprintf("export expects let, const, struct or include, but got %s on line %d", p.currToken.Literal, p.currToken.LineNumber))
+		return nil
+	}
+	if statement.Statement == nil {
+		return nil
 	}
 	return statement
 }
@@ -185,8 +308,10 @@ const (
 	_ int = iota
 	LOWEST
 	ASSIGN      // =
+	NULLISH     // a ?? b
 	EQUAL       // ==
 	LESSGREATER // > or <
+	RANGE       // 0..10
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -x or !x
@@ -197,10 +322,12 @@ const (
 
 var precedences = map[tokens.TokenType]int{
 	tokens.ASSIGN:   ASSIGN,
+	tokens.NULLISH:  NULLISH,
 	tokens.EQ:       EQUAL,
 	tokens.NOT_EQ:   EQUAL,
 	tokens.LT:       LESSGREATER,
 	tokens.GT:       LESSGREATER,
+	tokens.RANGE:    RANGE,
 	tokens.PLUS:     SUM,
 	tokens.MINUS:    SUM,
 	tokens.SLASH:    PRODUCT,
@@ -208,12 +335,20 @@ var precedences = map[tokens.TokenType]int{
 	tokens.LPAREN:   CALL,
 	tokens.DOT:      DOT,
 	tokens.LBRACKET: INDEX,
+
+	tokens.OPTIONAL_DOT:      DOT,
+	tokens.OPTIONAL_LBRACKET: INDEX,
 }
 
 func (p *Parser) parseExpressionStatement() ast.Statement {
 	defer untrace(trace("parseExpressionStatement"))
 	statement := &ast.ExpressionStatement{Token: p.currToken}
-	statement.Expression = p.parseExpression(LOWEST)
+	statement.Expression = p.parseExpression(ASSIGN)
+	if p.peekTokenIs(tokens.COMMA) {
+		statement.Expression = p.parseMultipleAssignment(statement.Expression)
+	} else {
+		statement.Expression = p.parseInfixExpressions(statement.Expression, LOWEST)
+	}
 
 	if p.peekTokenIs(tokens.SEMICOLON) {
 		p.nextToken()
@@ -230,8 +365,11 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		return nil
 	}
 
-	leftExp := prefix()
+	return p.parseInfixExpressions(prefix(), precedence)
+}
 
+// parseInfixExpressions continues parsing of the expression which starts from the parsed left expression
+func (p *Parser) parseInfixExpressions(leftExp ast.Expression, precedence int) ast.Expression {
 	for !p.peekTokenIs(tokens.SEMICOLON) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
@@ -246,6 +384,132 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
+// parseMultipleAssignment parses `a, b = b, a` starting from the comma after the first target
+func (p *Parser) parseMultipleAssignment(first ast.Expression) ast.Expression {
+	defer untrace(trace("parseMultipleAssignment"))
+	targets := []ast.Expression{first}
+	for p.peekTokenIs(tokens.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		targets = append(targets, p.parseExpression(ASSIGN))
+	}
+	for _, target := range targets {
+		if !isAssignable(target) {
+			p.errors = append(p.errors, fmt.Sprintf("unable to assign to %v on line %d", target, p.currToken.LineNumber))
+			return nil
+		}
+		p.checkAssignment(target, p.currToken.LineNumber)
+	}
+
+	if !p.expectPeekToken(tokens.ASSIGN) {
+		return nil
+	}
+	exp := &ast.MultipleAssignment{Token: p.currToken, Targets: targets}
+
+	p.nextToken()
+	exp.Values = []ast.Expression{p.parseExpression(LOWEST)}
+	for p.peekTokenIs(tokens.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		exp.Values = append(exp.Values, p.parseExpression(LOWEST))
+	}
+	return exp
+}
+
+func isAssignable(exp ast.Expression) bool {
+	switch n := exp.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.IndexExpression:
+		return !n.Optional
+	case *ast.InfixExpression:
+		return n.Operator == "."
+	default:
+		return false
+	}
+}
+
+// parsePattern parses an identifier or destructuring pattern of array or hash
+func (p *Parser) parsePattern() ast.Expression {
+	defer untrace(trace("parsePattern"))
+	switch p.currToken.Type {
+	case tokens.IDENT:
+		return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	case tokens.LBRACKET:
+		return p.parseArrayPattern(p.parsePattern)
+	case tokens.LBRACE:
+		return p.parseHashPattern(p.parsePattern)
+	default:
+		msg := fmt.Sprintf("unexpected token %s in destructuring pattern on line %d", p.currToken.Literal, p.currToken.LineNumber)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+}
+
+// parseArrayPattern parses elements of the pattern with parseElement, which is different for destructuring and match
+func (p *Parser) parseArrayPattern(parseElement func() ast.Expression) ast.Expression {
+	defer untrace(trace("parseArrayPattern"))
+	pattern := &ast.ArrayPattern{Token: p.currToken}
+	if !p.parsePatternEntries(tokens.RBRACKET, &pattern.Rest, func() bool {
+		element := parseElement()
+		pattern.Elements = append(pattern.Elements, element)
+		return element != nil
+	}) {
+		return nil
+	}
+	return pattern
+}
+
+// parseHashPattern parses keys as names or strings: {name, "content-type": ct}, a string key requires a pattern
+func (p *Parser) parseHashPattern(parseElement func() ast.Expression) ast.Expression {
+	defer untrace(trace("parseHashPattern"))
+	pattern := &ast.HashPattern{Token: p.currToken}
+	if !p.parsePatternEntries(tokens.RBRACE, &pattern.Rest, func() bool {
+		if !p.currTokenIs(tokens.IDENT) && !p.currTokenIs(tokens.STRING) {
+			msg := fmt.Sprintf("expected key name in destructuring pattern on line %d; instead got %s", p.currToken.LineNumber, p.currToken.Literal)
+			p.errors = append(p.errors, msg)
+			return false
+		}
+		key := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		var target ast.Expression = key
+		if p.currTokenIs(tokens.STRING) || p.peekTokenIs(tokens.COLON) {
+			if !p.expectPeekToken(tokens.COLON) {
+				return false
+			}
+			p.nextToken()
+			target = parseElement()
+		}
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Targets = append(pattern.Targets, target)
+		return target != nil
+	}) {
+		return nil
+	}
+	return pattern
+}
+
+// parsePatternEntries parses comma separated entries of pattern until the end token, `...rest` is the last entry
+func (p *Parser) parsePatternEntries(end tokens.TokenType, rest **ast.Identifier, parseEntry func() bool) bool {
+	for !p.peekTokenIs(end) {
+		p.nextToken()
+		if p.currTokenIs(tokens.ELLIPSIS) {
+			if !p.expectPeekToken(tokens.IDENT) {
+				return false
+			}
+			*rest = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+			break
+		}
+		if !parseEntry() {
+			return false
+		}
+		if !p.peekTokenIs(tokens.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	return p.expectPeekToken(end)
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	defer untrace(trace("parseIdentifier"))
 	return &ast.Identifier{
@@ -289,11 +553,69 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
 }
 
+// parseTemplateLiteral parses each interpolation of the template by a separate parser
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	defer untrace(trace("parseTemplateLiteral"))
+	template := &ast.TemplateLiteral{Token: p.currToken}
+	texts, interpolations := lexer.SplitTemplate(p.currToken.Literal)
+	line := p.currToken.LineNumber
+
+	for i, text := range texts {
+		value, err := lexer.Unescape(text)
+		if err != nil {
+			p.errors = append(p.errors, fmt.Sprintf("%v on line %d", err, line))
+			return nil
+		}
+		line += strings.Count(text, "\n")
+		if value != "" {
+			token := p.currToken
+			token.Type = tokens.STRING
+			token.Literal = value
+			template.Parts = append(template.Parts, &ast.StringLiteral{Token: token, Value: value})
+		}
+		if i == len(interpolations) {
+			break
+		}
+
+		exp := p.parseInterpolation(interpolations[i], line)
+		if exp == nil {
+			return nil
+		}
+		template.Parts = append(template.Parts, exp)
+		line += strings.Count(interpolations[i], "\n")
+	}
+	return template
+}
+
+func (p *Parser) parseInterpolation(input string, line int) ast.Expression {
+	sub := New(lexer.NewFromLine(input, p.currToken.FileName, line))
+	sub.scopes = p.scopes
+	sub.loops = p.loops
+	if sub.currTokenIs(tokens.EOF) {
+		p.errors = append(p.errors, fmt.Sprintf("empty interpolation on line %d", line))
+		return nil
+	}
+	exp := sub.parseExpression(LOWEST)
+	if len(sub.Errors()) == 0 && !sub.peekTokenIs(tokens.EOF) {
+		sub.errors = append(sub.errors, fmt.Sprintf("unexpected %s in interpolation on line %d", sub.peekToken.Type, sub.peekToken.LineNumber))
+	}
+	if len(sub.Errors()) != 0 {
+		p.errors = append(p.errors, sub.Errors()...)
+		return nil
+	}
+	return exp
+}
+
 func (p *Parser) parseBooleanLiteral() ast.Expression {
 	defer untrace(trace("parseBooleanLiteral"))
 	return &ast.BooleanLiteral{Token: p.currToken, Value: p.currTokenIs(tokens.TRUE)}
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	defer untrace(trace("parseNullLiteral"))
+	return &ast.NullLiteral{Token: p.currToken}
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	defer untrace(trace("parsePrefixExpression"))
 	exp := &ast.PrefixExpression{
@@ -316,6 +638,13 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Left:     left,
 	}
 
+	if exp.Operator == "=" {
+		if isOptionalChain(left) {
+			p.errors = append(p.errors, fmt.Sprintf("cannot assign to optional chain %s on line %d", left.String(), exp.Token.LineNumber))
+		}
+		p.checkAssignment(left, exp.Token.LineNumber)
+	}
+
 	prec := p.currPrecedence()
 	p.nextToken()
 	exp.Right = p.parseExpression(prec)
@@ -323,6 +652,37 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseOptionalChain parses `left?.member` or the optional call `left?.(arguments)`
+func (p *Parser) parseOptionalChain(left ast.Expression) ast.Expression {
+	defer untrace(trace("parseOptionalChain"))
+	if !p.peekTokenIs(tokens.LPAREN) {
+		return p.parseInfixExpression(left)
+	}
+	p.nextToken()
+	call := &ast.CallExpression{
+		Token:    p.currToken,
+		Function: left,
+		Optional: true,
+	}
+	if !p.parseCallArguments(call) {
+		return nil
+	}
+	return call
+}
+
+func isOptionalChain(exp ast.Expression) bool {
+	switch n := exp.(type) {
+	case *ast.IndexExpression:
+		return n.Optional
+	case *ast.SliceExpression:
+		return n.Optional
+	case *ast.InfixExpression:
+		return n.Operator == "?."
+	default:
+		return false
+	}
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	defer untrace(trace("parseGroupedExpression"))
 	p.nextToken()
@@ -375,13 +735,24 @@ func (p *Parser) parseForExpression() ast.Expression {
 	defer untrace(trace("parseForExpression"))
 	exp := &ast.ForExpression{
 		Token: p.currToken,
+		Label: p.label,
 	}
+	p.label = nil
 
 	if !p.expectPeekToken(tokens.LPAREN) {
 		return nil
 	}
+	p.openScope()
+	defer p.closeScope()
 	// Parse for params expressions
-	args := p.parseForArguments()
+	var args []ast.Expression
+	if !p.peekTokenIs(tokens.RPAREN) {
+		p.nextToken()
+		if p.currTokenIs(tokens.IDENT) && (p.peekTokenIs(tokens.IN) || p.peekTokenIs(tokens.COMMA)) {
+			return p.parseForInExpression(exp.Token, exp.Label)
+		}
+		args = p.parseForArguments()
+	}
 
 	if !p.expectPeekToken(tokens.RPAREN) {
 		return nil
@@ -403,35 +774,136 @@ func (p *Parser) parseForExpression() ast.Expression {
 		exp.Complete = args[2]
 	}
 
-	exp.Body = p.parseBlockStatement()
+	exp.Body = p.parseLoopBody(exp.Label)
 
 	return exp
 }
 
-func (p *Parser) parseForArguments() []ast.Expression {
-	var args []ast.Expression
-	if p.peekTokenIs(tokens.RPAREN) {
-		return args
+// parseForInExpression parses `for (v in iterable)` and `for (k, v in iterable)` starting from the first variable
+func (p *Parser) parseForInExpression(token tokens.Token, label *ast.Identifier) ast.Expression {
+	defer untrace(trace("parseForInExpression"))
+	exp := &ast.ForInExpression{
+		Token: token,
+		Label: label,
+		Value: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal},
 	}
+	if p.peekTokenIs(tokens.COMMA) {
+		p.nextToken()
+		if !p.expectPeekToken(tokens.IDENT) {
+			return nil
+		}
+		exp.Key = exp.Value
+		exp.Value = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	}
+	if !p.expectPeekToken(tokens.IN) {
+		return nil
+	}
+
 	p.nextToken()
+	exp.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeekToken(tokens.RPAREN) {
+		return nil
+	}
+	if !p.expectPeekToken(tokens.LBRACE) {
+		return nil
+	}
 
-	args = append(args, p.parseForArgument())
-	if p.peekTokenIs(tokens.RPAREN) {
-		return args
+	if exp.Key != nil {
+		p.declare(exp.Key, false, exp.Key.Token.LineNumber)
 	}
-	p.nextToken()
+	p.declare(exp.Value, false, exp.Value.Token.LineNumber)
+	exp.Body = p.parseLoopBody(exp.Label)
+
+	return exp
+}
 
-	args = append(args, p.parseForArgument())
-	if p.peekTokenIs(tokens.RPAREN) {
-		return args
+// parseLoopBody parses the block where `break` and `continue` refer to the loop
+func (p *Parser) parseLoopBody(label *ast.Identifier) *ast.BlockStatement {
+	name := ""
+	if label != nil {
+		name = label.Value
+	}
+	p.loops = append(p.loops, name)
+	defer func() {
+		p.loops = p.loops[:len(p.loops)-1]
+	}()
+	return p.parseBlockStatement()
+}
+
+// parseLabeledStatement parses a loop with label: `outer: for (...) {...}`
+func (p *Parser) parseLabeledStatement() ast.Statement {
+	defer untrace(trace("parseLabeledStatement"))
+	label := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	for _, loop := range p.loops {
+		if loop == label.Value {
+			p.errors = append(p.errors, fmt.Sprintf("label %s is already defined on line %d", label.Value, label.Token.LineNumber))
+			return nil
+		}
 	}
 	p.nextToken()
+	if !p.expectPeekToken(tokens.FOR) {
+		return nil
+	}
+	p.label = label
+	return p.parseExpressionStatement()
+}
 
-	args = append(args, p.parseForArgument())
+// parseBranchStatement parses `break` and `continue` with optional label of an enclosing loop
+func (p *Parser) parseBranchStatement() ast.Statement {
+	defer untrace(trace("parseBranchStatement"))
+	token := p.currToken
+	var label *ast.Identifier
+	if p.peekTokenIs(tokens.IDENT) {
+		p.nextToken()
+		label = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	}
 	if p.peekTokenIs(tokens.SEMICOLON) {
 		p.nextToken()
 	}
 
+	if len(p.loops) == 0 {
+		p.errors = append(p.errors, fmt.Sprintf("%s outside of loop on line %d", token.Literal, token.LineNumber))
+		return nil
+	}
+	if label != nil && !p.hasLoop(label.Value) {
+		p.errors = append(p.errors, fmt.Sprintf("undefined loop label %s on line %d", label.Value, label.Token.LineNumber))
+		return nil
+	}
+
+	if token.Type == tokens.BREAK {
+		return &ast.BreakStatement{Token: token, Label: label}
+	}
+	return &ast.ContinueStatement{Token: token, Label: label}
+}
+
+func (p *Parser) hasLoop(label string) bool {
+	for _, loop := range p.loops {
+		if loop == label {
+			return true
+		}
+	}
+	return false
+}
+
+// enterFunction hides the enclosing loops, so `break` and `continue` can't leave the function body.
+// The returned function restores the loops
+func (p *Parser) enterFunction() func() {
+	loops := p.loops
+	p.loops = nil
+	return func() {
+		p.loops = loops
+	}
+}
+
+// parseForArguments parses up to three arguments of C-style loop starting from the first argument
+func (p *Parser) parseForArguments() []ast.Expression {
+	args := []ast.Expression{p.parseForArgument()}
+	for len(args) < 3 && !p.peekTokenIs(tokens.RPAREN) {
+		p.nextToken()
+		args = append(args, p.parseForArgument())
+	}
+
 	return args
 }
 
@@ -450,6 +922,8 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		Statements: []ast.Statement{},
 	}
 
+	p.openScope()
+	defer p.closeScope()
 	p.nextToken()
 
 	for !p.currTokenIs(tokens.RBRACE) && !p.currTokenIs(tokens.EOF) {
@@ -459,6 +933,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	block.End = p.currToken
 
 	return block
 }
@@ -473,7 +948,12 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	fnLit.Parameters = p.parseFunctionParameters()
+	p.openScope()
+	defer p.closeScope()
+	defer p.enterFunction()()
+	if !p.parseFunctionParameters(fnLit) || !p.parseResultType(fnLit) {
+		return nil
+	}
 
 	if !p.expectPeekToken(tokens.LBRACE) {
 		return nil
@@ -484,36 +964,427 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return fnLit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	defer untrace(trace("parseFunctionParameters"))
-	idents := []*ast.Identifier{}
-	p.nextToken()
+func (p *Parser) parseAsyncFunctionLiteral() ast.Expression {
+	defer untrace(trace("parseAsyncFunctionLiteral"))
+	if !p.expectPeekToken(tokens.FUNCTION) {
+		return nil
+	}
 
-	if p.currTokenIs(tokens.RPAREN) {
-		return idents
+	fnLit, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !ok || fnLit == nil {
+		return nil
 	}
+	fnLit.Async = true
 
-	ident := &ast.Identifier{
+	return fnLit
+}
+
+func (p *Parser) parseAwaitExpression() ast.Expression {
+	defer untrace(trace("parseAwaitExpression"))
+	exp := &ast.AwaitExpression{
 		Token: p.currToken,
-		Value: p.currToken.Literal,
 	}
-	idents = append(idents, ident)
 
-	for p.peekTokenIs(tokens.COMMA) {
+	p.nextToken()
+
+	exp.Value = p.parseExpression(PREFIX)
+
+	return exp
+}
+
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	defer untrace(trace("parseSpawnExpression"))
+	exp := &ast.SpawnExpression{
+		Token: p.currToken,
+	}
+
+	p.nextToken()
+
+	exp.Value = p.parseExpression(PREFIX)
+
+	return exp
+}
+
+func (p *Parser) parseStructStatement() ast.Statement {
+	defer untrace(trace("parseStructStatement"))
+	statement := &ast.StructStatement{Token: p.currToken}
+
+	if !p.expectPeekToken(tokens.IDENT) {
+		return nil
+	}
+	statement.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeekToken(tokens.LBRACE) {
+		return nil
+	}
+
+	members := map[string]bool{}
+	for !p.peekTokenIs(tokens.RBRACE) && !p.peekTokenIs(tokens.EOF) {
 		p.nextToken()
+		var name *ast.Identifier
+		switch p.currToken.Type {
+		case tokens.SEMICOLON, tokens.COMMA:
+			continue
+		case tokens.IDENT:
+			field := p.parseStructField()
+			if field == nil {
+				return nil
+			}
+			name = field.Name
+			statement.Fields = append(statement.Fields, field)
+		case tokens.FUNCTION, tokens.ASYNC:
+			method := p.parseStructMethod(statement.Name.Value)
+			if method == nil {
+				return nil
+			}
+			name = method.Name
+			statement.Methods = append(statement.Methods, method)
+		default:
+			msg := fmt.Sprintf("unexpected token %s in struct %s on line %d", p.currToken.Literal, statement.Name.Value, p.currToken.LineNumber)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+
+		if members[name.Value] {
+			msg := fmt.Sprintf("duplicate member %s in struct %s on line %d", name.Value, statement.Name.Value, name.Token.LineNumber)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		members[name.Value] = true
+	}
+
+	if !p.expectPeekToken(tokens.RBRACE) {
+		return nil
+	}
+	statement.End = p.currToken
+	if p.peekTokenIs(tokens.SEMICOLON) {
+		p.nextToken()
+	}
+	return statement
+}
+
+func (p *Parser) parseStructField() *ast.StructField {
+	defer untrace(trace("parseStructField"))
+	field := &ast.StructField{
+		Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal},
+	}
+	if p.peekTokenIs(tokens.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		field.Value = p.parseExpression(LOWEST)
+	}
+	return field
+}
+
+func (p *Parser) parseStructMethod(structName string) *ast.StructMethod {
+	defer untrace(trace("parseStructMethod"))
+	async := p.currTokenIs(tokens.ASYNC)
+	if async && !p.expectPeekToken(tokens.FUNCTION) {
+		return nil
+	}
+	fnLit := &ast.FunctionLiteral{Token: p.currToken, Async: async}
+
+	if !p.expectPeekToken(tokens.IDENT) {
+		return nil
+	}
+	method := &ast.StructMethod{
+		Name:     &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal},
+		Function: fnLit,
+	}
+
+	if !p.expectPeekToken(tokens.LPAREN) {
+		return nil
+	}
+	p.openScope()
+	defer p.closeScope()
+	defer p.enterFunction()()
+	if !p.parseFunctionParameters(fnLit) {
+		return nil
+	}
+	if len(fnLit.Parameters) == 0 {
+		msg := fmt.Sprintf("method %s of struct %s must receive self on line %d", method.Name.Value, structName, method.Name.Token.LineNumber)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	if !p.parseResultType(fnLit) || !p.expectPeekToken(tokens.LBRACE) {
+		return nil
+	}
+	fnLit.Body = p.parseBlockStatement()
+	return method
+}
+
+func (p *Parser) parseSelectExpression() ast.Expression {
+	defer untrace(trace("parseSelectExpression"))
+	exp := &ast.SelectExpression{
+		Token: p.currToken,
+	}
+
+	if !p.expectPeekToken(tokens.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(tokens.RBRACE) && !p.peekTokenIs(tokens.EOF) {
 		p.nextToken()
-		ident = &ast.Identifier{
+		switch {
+		case p.currTokenIs(tokens.CASE):
+			selectCase := p.parseSelectCase()
+			if selectCase == nil {
+				return nil
+			}
+			exp.Cases = append(exp.Cases, selectCase)
+		case p.currTokenIs(tokens.DEFAULT) && exp.Default == nil:
+			if !p.expectPeekToken(tokens.LBRACE) {
+				return nil
+			}
+			exp.Default = p.parseBlockStatement()
+		default:
+			msg := fmt.Sprintf("unexpected token %s in select on line %d", p.currToken.Literal, p.currToken.LineNumber)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+	}
+
+	if !p.expectPeekToken(tokens.RBRACE) {
+		return nil
+	}
+	exp.End = p.currToken
+	return exp
+}
+
+func (p *Parser) parseSelectCase() *ast.SelectCase {
+	defer untrace(trace("parseSelectCase"))
+	selectCase := &ast.SelectCase{
+		Token: p.currToken,
+	}
+	p.nextToken()
+
+	if p.currTokenIs(tokens.IDENT) && p.peekTokenIs(tokens.ASSIGN) {
+		selectCase.Name = &ast.Identifier{
 			Token: p.currToken,
 			Value: p.currToken.Literal,
 		}
-		idents = append(idents, ident)
+		p.nextToken()
+		p.nextToken()
+	}
+
+	call, ok := p.parseExpression(LOWEST).(*ast.CallExpression)
+	if !ok || !isChannelOperation(call, selectCase.Name == nil) {
+		msg := fmt.Sprintf("select case expects `recv(channel)` or `send(channel, value)` on line %d", selectCase.Token.LineNumber)
+		p.errors = append(p.errors, msg)
+		return nil
 	}
+	selectCase.Operation = call
+
+	if !p.expectPeekToken(tokens.LBRACE) {
+		return nil
+	}
+	selectCase.Body = p.parseBlockStatement()
+
+	return selectCase
+}
 
+func (p *Parser) parseMatchExpression() ast.Expression {
+	defer untrace(trace("parseMatchExpression"))
+	exp := &ast.MatchExpression{Token: p.currToken}
+
+	if !p.expectPeekToken(tokens.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
 	if !p.expectPeekToken(tokens.RPAREN) {
 		return nil
 	}
+	if !p.expectPeekToken(tokens.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(tokens.RBRACE) && !p.peekTokenIs(tokens.EOF) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+		if p.peekTokenIs(tokens.COMMA) || p.peekTokenIs(tokens.SEMICOLON) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeekToken(tokens.RBRACE) {
+		return nil
+	}
+	exp.End = p.currToken
+	return exp
+}
+
+// parseMatchArm parses `pattern if guard => body`, the body is a block or a single expression
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	defer untrace(trace("parseMatchArm"))
+	arm := &ast.MatchArm{Token: p.currToken}
+	p.openScope()
+	defer p.closeScope()
+
+	arm.Pattern = p.parseMatchPattern()
+	if arm.Pattern == nil {
+		return nil
+	}
+	p.declare(arm.Pattern, false, arm.Token.LineNumber)
+
+	if p.peekTokenIs(tokens.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeekToken(tokens.ARROW) {
+		return nil
+	}
+
+	p.nextToken()
+	if p.currTokenIs(tokens.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+	token := p.currToken
+	arm.Body = &ast.BlockStatement{
+		Token:      token,
+		Statements: []ast.Statement{&ast.ExpressionStatement{Token: token, Expression: p.parseExpression(LOWEST)}},
+	}
+	return arm
+}
+
+// parseMatchPattern parses alternatives of literals, `_`, bindings, array and hash patterns: 1 | [a, _] | {"k": v}
+func (p *Parser) parseMatchPattern() ast.Expression {
+	defer untrace(trace("parseMatchPattern"))
+	pattern := p.parseSingleMatchPattern()
+	if pattern == nil || !p.peekTokenIs(tokens.PIPE) {
+		return pattern
+	}
+
+	alternative := &ast.AlternativePattern{Token: p.peekToken, Patterns: []ast.Expression{pattern}}
+	for p.peekTokenIs(tokens.PIPE) {
+		p.nextToken()
+		p.nextToken()
+		pattern = p.parseSingleMatchPattern()
+		if pattern == nil {
+			return nil
+		}
+		alternative.Patterns = append(alternative.Patterns, pattern)
+	}
+	return alternative
+}
+
+func (p *Parser) parseSingleMatchPattern() ast.Expression {
+	switch p.currToken.Type {
+	case tokens.IDENT:
+		return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	case tokens.INT, tokens.DOUBLE, tokens.STRING, tokens.TRUE, tokens.FALSE, tokens.NULL:
+		return p.prefixParseFns[p.currToken.Type]()
+	case tokens.MINUS:
+		if p.peekTokenIs(tokens.INT) || p.peekTokenIs(tokens.DOUBLE) {
+			return p.parsePrefixExpression()
+		}
+	case tokens.LBRACKET:
+		return p.parseArrayPattern(p.parseMatchPattern)
+	case tokens.LBRACE:
+		return p.parseHashPattern(p.parseMatchPattern)
+	}
+	msg := fmt.Sprintf("unexpected token %s in match pattern on line %d", p.currToken.Literal, p.currToken.LineNumber)
+	p.errors = append(p.errors, msg)
+	return nil
+}
+
+// isChannelOperation checks the call is `recv` or, if allowed, `send`
+func isChannelOperation(call *ast.CallExpression, allowSend bool) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return false
+	}
+	return ident.Value == "recv" || (allowSend && ident.Value == "send")
+}
+
+// parseFunctionParameters parses identifiers and destructuring patterns with optional default values, and the rest
+// parameter at the end: fn(a, [b, c], d = 1, ...rest). A pattern parameter is named by the pattern string
+func (p *Parser) parseFunctionParameters(fnLit *ast.FunctionLiteral) bool {
+	defer untrace(trace("parseFunctionParameters"))
+	fnLit.Parameters = []*ast.Identifier{}
+	p.nextToken()
+
+	if p.currTokenIs(tokens.RPAREN) {
+		return true
+	}
+
+	for {
+		if p.currTokenIs(tokens.ELLIPSIS) {
+			if !p.expectPeekToken(tokens.IDENT) {
+				return false
+			}
+			fnLit.Rest = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+			p.declare(fnLit.Rest, false, fnLit.Rest.Token.LineNumber)
+			break
+		}
+
+		ident := &ast.Identifier{
+			Token: p.currToken,
+			Value: p.currToken.Literal,
+		}
+		index := len(fnLit.Parameters)
+		if p.currTokenIs(tokens.LBRACKET) || p.currTokenIs(tokens.LBRACE) {
+			pattern := p.parsePattern()
+			if pattern == nil {
+				return false
+			}
+			if fnLit.Patterns == nil {
+				fnLit.Patterns = map[int]ast.Expression{}
+			}
+			fnLit.Patterns[index] = pattern
+			ident.Value = pattern.String()
+			p.declare(pattern, false, ident.Token.LineNumber)
+		} else {
+			p.declare(ident, false, ident.Token.LineNumber)
+		}
+		fnLit.Parameters = append(fnLit.Parameters, ident)
+
+		t, ok := p.parseOptionalType()
+		if !ok {
+			return false
+		}
+		if t != nil {
+			if fnLit.Types == nil {
+				fnLit.Types = map[int]*ast.TypeAnnotation{}
+			}
+			fnLit.Types[index] = t
+		}
+
+		if p.peekTokenIs(tokens.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			if fnLit.Defaults == nil {
+				fnLit.Defaults = map[int]ast.Expression{}
+			}
+			fnLit.Defaults[index] = p.parseExpression(LOWEST)
+		} else if len(fnLit.Defaults) != 0 {
+			msg := fmt.Sprintf("parameter %s without default value follows optional parameter on line %d", ident.Value, ident.Token.LineNumber)
+			p.errors = append(p.errors, msg)
+			return false
+		}
+
+		if !p.peekTokenIs(tokens.COMMA) {
+			break
+		}
+		p.nextToken()
+		p.nextToken()
+	}
+
+	return p.expectPeekToken(tokens.RPAREN)
+}
 
-	return idents
+// parseResultType parses the optional result type of function following the parameters: fn(a: int): int
+func (p *Parser) parseResultType(fnLit *ast.FunctionLiteral) bool {
+	var ok bool
+	fnLit.Result, ok = p.parseOptionalType()
+	return ok
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -523,10 +1394,59 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 		Function: function,
 	}
 
-	call.Arguments = p.parseExpressionList(tokens.RPAREN)
+	if !p.parseCallArguments(call) {
+		return nil
+	}
 	return call
 }
 
+// parseCallArguments parses positional arguments followed by named ones: f(a, ...rest, scale: 2)
+func (p *Parser) parseCallArguments(call *ast.CallExpression) bool {
+	defer untrace(trace("parseCallArguments"))
+	call.Arguments = []ast.Expression{}
+	p.nextToken()
+	if p.currTokenIs(tokens.RPAREN) {
+		return true
+	}
+
+	for {
+		if p.currTokenIs(tokens.IDENT) && p.peekTokenIs(tokens.COLON) {
+			name := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+			for _, named := range call.Named {
+				if named.Name.Value == name.Value {
+					p.errors = append(p.errors, fmt.Sprintf("duplicate named argument %s on line %d", name.Value, name.Token.LineNumber))
+					return false
+				}
+			}
+			p.nextToken()
+			p.nextToken()
+			call.Named = append(call.Named, &ast.NamedArgument{Name: name, Value: p.parseExpression(LOWEST)})
+		} else if len(call.Named) != 0 {
+			msg := fmt.Sprintf("positional argument follows named argument on line %d", p.currToken.LineNumber)
+			p.errors = append(p.errors, msg)
+			return false
+		} else {
+			call.Arguments = append(call.Arguments, p.parseExpression(LOWEST))
+		}
+
+		if !p.peekTokenIs(tokens.COMMA) {
+			break
+		}
+		p.nextToken()
+		p.nextToken()
+	}
+
+	return p.expectPeekToken(tokens.RPAREN)
+}
+
+func (p *Parser) parseSpreadExpression() ast.Expression {
+	defer untrace(trace("parseSpreadExpression"))
+	exp := &ast.SpreadExpression{Token: p.currToken}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
 func (p *Parser) parseExpressionList(end tokens.TokenType) []ast.Expression {
 	defer untrace(trace("parseExpressionList"))
 	expressions := []ast.Expression{}
@@ -539,6 +1459,9 @@ func (p *Parser) parseExpressionList(end tokens.TokenType) []ast.Expression {
 
 	for p.peekTokenIs(tokens.COMMA) {
 		p.nextToken()
+		if p.peekTokenIs(end) {
+			break
+		}
 		p.nextToken()
 		expressions = append(expressions, p.parseExpression(LOWEST))
 	}
@@ -550,13 +1473,42 @@ func (p *Parser) parseExpressionList(end tokens.TokenType) []ast.Expression {
 }
 
 func (p *Parser) parseInfixIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{
-		Token: p.currToken,
-		Left:  left,
+	token := p.currToken
+	optional := token.Type == tokens.OPTIONAL_LBRACKET
+	var index ast.Expression
+	if !p.peekTokenIs(tokens.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(tokens.COLON) {
+		return p.parseSliceExpression(token, left, index, optional)
+	}
+	if !p.expectPeekToken(tokens.RBRACKET) {
+		return nil
+	}
+	return &ast.IndexExpression{
+		Token:    token,
+		Left:     left,
+		Index:    index,
+		Optional: optional,
+	}
+}
+
+// parseSliceExpression parses the rest of `left[start:end]` starting from the colon
+func (p *Parser) parseSliceExpression(token tokens.Token, left, start ast.Expression, optional bool) ast.Expression {
+	exp := &ast.SliceExpression{
+		Token:    token,
+		Left:     left,
+		Start:    start,
+		Optional: optional,
 	}
 	p.nextToken()
 
-	exp.Index = p.parseExpression(LOWEST)
+	if !p.peekTokenIs(tokens.RBRACKET) {
+		p.nextToken()
+		exp.End = p.parseExpression(LOWEST)
+	}
 	if !p.expectPeekToken(tokens.RBRACKET) {
 		return nil
 	}
@@ -564,10 +1516,10 @@ func (p *Parser) parseInfixIndexExpression(left ast.Expression) ast.Expression {
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
-	return &ast.ArrayLiteral{
-		Token:    p.currToken,
-		Elements: p.parseExpressionList(tokens.RBRACKET),
-	}
+	array := &ast.ArrayLiteral{Token: p.currToken}
+	array.Elements = p.parseExpressionList(tokens.RBRACKET)
+	array.End = p.currToken
+	return array
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
@@ -583,6 +1535,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		}
 		p.nextToken()
 		hash.Pairs[key] = p.parseExpression(LOWEST)
+		hash.Keys = append(hash.Keys, key)
 		if !p.peekTokenIs(tokens.RBRACE) && !p.expectPeekToken(tokens.COMMA) {
 			return nil
 		}
@@ -590,6 +1543,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeekToken(tokens.RBRACE) {
 		return nil
 	}
+	hash.End = p.currToken
 	return hash
 }
 
@@ -611,23 +1565,25 @@ func (p *Parser) expectPeekToken(t tokens.TokenType) bool {
 }
 
 func (p *Parser) peekPrecedence() int {
-	prec, ok := precedences[p.peekToken.Type]
-	if ok {
-		return prec
-	}
-	return LOWEST
+	return Precedence(p.peekToken.Type)
 }
 
 func (p *Parser) currPrecedence() int {
-	prec, ok := precedences[p.currToken.Type]
+	return Precedence(p.currToken.Type)
+}
+
+// Precedence returns the precedence of infix operator, it's LOWEST for other tokens
+func Precedence(t tokens.TokenType) int {
+	prec, ok := precedences[t]
 	if ok {
 		return prec
 	}
 	return LOWEST
 }
 
+// Errors returns lexer errors followed by parser errors
 func (p *Parser) Errors() []string {
-	return p.errors
+	return append(append([]string{}, p.l.Errors()...), p.errors...)
 }
 
 func (p *Parser) peekError(t tokens.TokenType) {
@@ -51,6 +51,322 @@ func TestLetStatement(t *testing.T) {
 	}
 }
 
+func TestConstStatement(t *testing.T) {
+	l := lexer.New(`const x = 5; const [a, b] = pair;`, "non-file")
+	p := parser.New(l)
+	program := p.ParseProgram()
+	require.Len(t, p.Errors(), 0)
+	require.Len(t, program.Statements, 2)
+
+	for i, expected := range []string{"const x = 5;", "const [a, b] = pair;"} {
+		statement, ok := program.Statements[i].(*ast.LetStatement)
+		require.True(t, ok)
+		assert.True(t, statement.IsConst())
+		assert.Equal(t, expected, statement.String())
+	}
+}
+
+func TestConstAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const a = 1; a = 2", "cannot assign to constant a on line 1"},
+		{"const [a, {b}] = v;\n b = 3", "cannot assign to constant b on line 2"},
+		{"const a = 1; let b = 0; b, a = 1, 2", "cannot assign to constant a on line 1"},
+		{"const a = 1; let f = fn() { a = 2 }", "cannot assign to constant a on line 1"},
+		{"const a = 1; if (true) { a = 2 }", "cannot assign to constant a on line 1"},
+		{"const a = 1; let a = 2", "constant a is already declared on line 1"},
+		{`const a = 1; "${a = 2}"`, "cannot assign to constant a on line 1"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+		p.ParseProgram()
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+
+	valid := []string{
+		"const a = 1; let f = fn(a) { a = 2 }",
+		"const a = 1; let f = fn([a]) { a = 2 }",
+		"const a = 1; if (true) { let a = 2; a = 3 }",
+		"const i = 1; for (let i = 0; i < 3; i = i + 1) {}",
+		"const a = {}; a.x = 1; a[\"y\"] = 2",
+	}
+	for _, input := range valid {
+		p := parser.New(lexer.New(input, "non-file"))
+		p.ParseProgram()
+		assert.Empty(t, p.Errors(), input)
+	}
+}
+
+func TestBreakContinue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for () { break; }", "for () {break;}"},
+		{"for () { continue }", "for () {continue;}"},
+		{"outer: for (i < 3) { for () { break outer; } }", "outer: for ((i < 3);) {for () {break outer;}}"},
+		{"outer: for () { if (true) { continue outer; } }", "outer: for () {if true continue outer;}"},
+	}
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		program := p.ParseProgram()
+		require.Empty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, program.String(), test.input)
+	}
+}
+
+func TestBreakContinueErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "break outside of loop on line 1"},
+		{"if (true) { continue }", "continue outside of loop on line 1"},
+		{"for () { let f = fn() { break; } }", "break outside of loop on line 1"},
+		{"for () { break outer; }", "undefined loop label outer on line 1"},
+		{"outer: for () { outer: for () {} }", "label outer is already defined on line 1"},
+		{"outer: 5", "expected token FOR on line 1; instead got INT"},
+	}
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		p.ParseProgram()
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+}
+
+func TestForInExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x in arr) { x }", "for (x in arr) {x}"},
+		{"for (k, v in {}) { k }", "for (k, v in {}) {k}"},
+		{"for (i in 0..n + 1) { i }", "for (i in (0 .. (n + 1))) {i}"},
+		{"outer: for (c in \"abc\") { break outer }", "outer: for (c in abc) {break outer;}"},
+		{"for (x) { x }", "for (x;) {x}"},
+		{"for (let i = 0; i < 3; i = i + 1) {}", "for (let i = 0;;(i < 3);(i = (i + 1));) {}"},
+	}
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		program := p.ParseProgram()
+		require.Empty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, program.String(), test.input)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"for (k, in arr) {}", "expected token IDENT on line 1; instead got IN"},
+		{"for (k, v arr) {}", "expected token IN on line 1; instead got IDENT"},
+		{"for (x in arr) x", "expected token { on line 1; instead got IDENT"},
+	}
+	for _, test := range errors {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		p.ParseProgram()
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (x) { 1 => "one", _ => "other" }`, "match (x) {1 => {one}, _ => {other}}"},
+		{`match (x) { "a" | "b" => 1; -1 | 2.5 => { 2 } }`, "match (x) {a | b => {1}, (-1) | 2.5 => {2}}"},
+		{`match (x) { [a, [_, b], ...rest] => a, {"k": v, name, ...other} => v }`, "match (x) {[a, [_, b], ...rest] => {a}, {k: v, name, ...other} => {v}}"},
+		{`match (x) { n if n > 10 => n, true | false => 0, }`, "match (x) {n if (n > 10) => {n}, true | false => {0}}"},
+		{`match (f(x)) { }`, "match (f(x)) {}"},
+	}
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		program := p.ParseProgram()
+		require.Empty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, program.String(), test.input)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`match x { _ => 1 }`, "expected token ( on line 1; instead got IDENT"},
+		{`match (x) { a + 1 => 1 }`, "expected token => on line 1; instead got +"},
+		{`match (x) { fn() {} => 1 }`, "unexpected token fn in match pattern on line 1"},
+		{`match (x) { {"k"} => 1 }`, "expected token : on line 1; instead got }"},
+		{`match (x) { 1 | => 1 }`, "unexpected token => in match pattern on line 1"},
+		{`const c = 1; match (x) { c => { c = 2 } }`, ""},
+	}
+	for _, test := range errors {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		p.ParseProgram()
+		if test.expected == "" {
+			assert.Empty(t, p.Errors(), test.input)
+			continue
+		}
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+}
+
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a, b = 2) { a }", "fn(a, b = 2)a"},
+		{"fn(a, ...rest) { rest }", "fn(a, ...rest)rest"},
+		{"fn(a = 1, [b, c] = [2, 3], ...rest) {}", "fn(a = 1, [b, c] = [2, 3], ...rest)"},
+		{"f(1, ...arr, x: 2, y: a + b)", "f(1, ...arr, x: 2, y: (a + b))"},
+		{"[...a, 1, ...b]", "[...a, 1, ...b]"},
+		{"p.move(dx: 1)", "(p.move(dx: 1))"},
+	}
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		program := p.ParseProgram()
+		require.Empty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, program.String(), test.input)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a = 1, b) {}", "parameter b without default value follows optional parameter on line 1"},
+		{"fn(...rest, a) {}", "expected token ) on line 1; instead got ,"},
+		{"f(x: 1, 2)", "positional argument follows named argument on line 1"},
+		{"f(x: 1, x: 2)", "duplicate named argument x on line 1"},
+		{"const c = 1; fn(c = 2) { c = 3 }", ""},
+	}
+	for _, test := range errors {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		p.ParseProgram()
+		if test.expected == "" {
+			assert.Empty(t, p.Errors(), test.input)
+			continue
+		}
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+}
+
+func TestNullSafety(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a ?? b", "(a ?? b)"},
+		{"a ?? b == c", "(a ?? (b == c))"},
+		{"a?.b ?? null", "((a?.b) ?? null)"},
+		{"a?.b.c", "((a?.b).c)"},
+		{"a?[\"k\"]?[0]", "((a?[k])?[0])"},
+		{"a?[1:]", "(a?[1:])"},
+		{"f?.(1, x: 2)", "f?.(1, x: 2)"},
+		{"a.f?.()", "(a.f)?.()"},
+		{"a = null", "(a = null)"},
+	}
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		program := p.ParseProgram()
+		require.Empty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, program.String(), test.input)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"a?[0] = 1", "cannot assign to optional chain (a?[0]) on line 1"},
+		{"a?.b = 1", "cannot assign to optional chain (a?.b) on line 1"},
+		{"a ? b", "no prefix parse functions found for ILLEGAL on line 1"},
+	}
+	for _, test := range errors {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		p.ParseProgram()
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let port: string = "3000"`, "let port: string = 3000;"},
+		{"let xs: array[int] = [1]", "let xs: array[int] = [1];"},
+		{"let v: int | null = null", "let v: int | null = null;"},
+		{"let add = fn(a: int, b: int): int { a + b }", "let add = fn(a: int, b: int): int (a + b);"},
+		{"let f = fn(a: double = 1, ...rest): string { a }", "let f = fn(a: double = 1, ...rest): string a;"},
+		{"let cb: fn(string, ...int): bool = g", "let cb: fn(string, ...int): bool = g;"},
+	}
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		program := p.ParseProgram()
+		require.Empty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, program.String(), test.input)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: = 1", "unexpected token = in type annotation on line 1"},
+		{"let x: array[...int] = 1", "unexpected token ... in type annotation on line 1"},
+	}
+	for _, test := range errors {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		p.ParseProgram()
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+}
+
+func TestImportsAndExports(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`# sys "sys.rs"`, `# sys "sys.rs";`},
+		{`# {print, len as length} from "imports.rs";`, `# {print, len as length} from "imports.rs";`},
+		{`# * from "imports.rs"`, `# * from "imports.rs";`},
+		{`export let a = 1;`, `export let a = 1;`},
+		{`export const {a, b} = h;`, `export const {a, b} = h;`},
+		{`export # {a} from "m.rs"`, `export # {a} from "m.rs";`},
+		{`let from = 1; let as = from;`, `let from = 1;let as = from;`},
+	}
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		program := p.ParseProgram()
+		require.Empty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, program.String(), test.input)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`# {a, b as a} from "m.rs"`, "duplicate imported name a on line 1"},
+		{`# {} from "m.rs"`, "empty import list on line 1"},
+		{`# {a} "m.rs"`, "expected from on line 1; instead got m.rs"},
+		{`# * "m.rs"`, "expected from on line 1; instead got m.rs"},
+		{`export a = 1`, "export expects let, const, struct or include, but got a on line 1"},
+		{`let f = fn() { export let a = 1; }`, "export is allowed only at the top level on line 1"},
+	}
+	for _, test := range errors {
+		p := parser.New(lexer.New(test.input, "non-file"))
+		p.ParseProgram()
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+}
+
 func TestReturnStatement(t *testing.T) {
 	input := `
 	return 5;
@@ -656,6 +972,19 @@ func TestParsingArrayLiterals(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestParsingArrayLiteralTrailingComma(t *testing.T) {
+	input := "[\n  1,\n  2,\n];"
+
+	p := parser.New(lexer.New(input, "non-file"))
+	program := p.ParseProgram()
+	require.Len(t, p.Errors(), 0)
+
+	arr, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ArrayLiteral)
+	require.True(t, ok)
+	assert.Equal(t, "[1, 2]", arr.String())
+	assert.Equal(t, 4, arr.End.LineNumber)
+}
+
 func TestParsingIndexExpression(t *testing.T) {
 	input := `myArray[1 + 2];`
 
@@ -1002,3 +1331,377 @@ func TestForStatementNoParams(t *testing.T) {
 	_, ok = forExp.Body.Statements[0].(*ast.ExpressionStatement)
 	assert.True(t, ok)
 }
+
+func TestAsyncFunctionLiteral(t *testing.T) {
+	input := `let f = async fn(x) { await g(x); };`
+	l := lexer.New(input, "non-file")
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	require.Len(t, p.Errors(), 0)
+	require.NotNil(t, program)
+	require.Len(t, program.Statements, 1)
+
+	letStatement, ok := program.Statements[0].(*ast.LetStatement)
+	require.True(t, ok)
+
+	fnLit, ok := letStatement.Value.(*ast.FunctionLiteral)
+	require.True(t, ok)
+	assert.True(t, fnLit.Async)
+	require.Len(t, fnLit.Parameters, 1)
+
+	require.Len(t, fnLit.Body.Statements, 1)
+	expStmt, ok := fnLit.Body.Statements[0].(*ast.ExpressionStatement)
+	require.True(t, ok)
+	awaitExp, ok := expStmt.Expression.(*ast.AwaitExpression)
+	require.True(t, ok)
+	assert.Equal(t, "g(x)", awaitExp.Value.String())
+	assert.Equal(t, "let f = async fn(x)(await g(x));", letStatement.String())
+}
+
+func TestAwaitExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"await a", "(await a)"},
+		{"await a + 1", "((await a) + 1)"},
+		{"await lib.fetch(1)", "(await (lib.fetch(1)))"},
+		{"await a[0]", "(await (a[0]))"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		require.Len(t, p.Errors(), 0)
+		require.Len(t, program.Statements, 1)
+		assert.Equal(t, test.expected, program.String())
+	}
+}
+
+func TestAsyncWithoutFunction(t *testing.T) {
+	l := lexer.New("async 5", "non-file")
+	p := parser.New(l)
+
+	p.ParseProgram()
+	assert.NotEmpty(t, p.Errors())
+}
+
+func TestSpawnExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"spawn fn(){ x }", "spawn fn()x"},
+		{"spawn worker(1, 2)", "spawn worker(1, 2)"},
+		{"let t = spawn lib.worker(1)", "let t = spawn (lib.worker(1));"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		require.Len(t, p.Errors(), 0)
+		require.Len(t, program.Statements, 1)
+		assert.Equal(t, test.expected, program.String())
+	}
+}
+
+func TestSelectExpression(t *testing.T) {
+	input := `
+	select {
+		case v = recv(a) { v }
+		case send(b, 1) { 2 }
+		case recv(c) { 3 }
+		default { 4 }
+	}
+`
+	l := lexer.New(input, "non-file")
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	require.Len(t, p.Errors(), 0)
+	require.Len(t, program.Statements, 1)
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	require.True(t, ok)
+
+	selectExp, ok := statement.Expression.(*ast.SelectExpression)
+	require.True(t, ok)
+
+	require.Len(t, selectExp.Cases, 3)
+	assert.Equal(t, "v", selectExp.Cases[0].Name.Value)
+	assert.Equal(t, "recv(a)", selectExp.Cases[0].Operation.String())
+	assert.Nil(t, selectExp.Cases[1].Name)
+	assert.Equal(t, "send(b, 1)", selectExp.Cases[1].Operation.String())
+	assert.Equal(t, "recv(c)", selectExp.Cases[2].Operation.String())
+	require.NotNil(t, selectExp.Default)
+	assert.Equal(t, "4", selectExp.Default.String())
+}
+
+func TestSelectExpressionErrors(t *testing.T) {
+	tests := []string{
+		"select { case foo(a) {} }",
+		"select { case v = send(a, 1) {} }",
+		"select { let a = 1; }",
+		"select { default {} default {} }",
+	}
+	for _, input := range tests {
+		l := lexer.New(input, "non-file")
+		p := parser.New(l)
+
+		p.ParseProgram()
+		assert.NotEmpty(t, p.Errors(), input)
+	}
+}
+
+func TestParsingSliceExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`a[1:3]`, "(a[1:3])"},
+		{`a[:n - 1]`, "(a[:(n - 1)])"},
+		{`a[1:]`, "(a[1:])"},
+		{`a[:]`, "(a[:])"},
+		{`a[1:2][0]`, "((a[1:2])[0])"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		require.Len(t, p.Errors(), 0, test.input)
+		require.Len(t, program.Statements, 1)
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		require.True(t, ok)
+		assert.Equal(t, test.expected, statement.Expression.String())
+	}
+}
+
+func TestParsingSliceExpressionErrors(t *testing.T) {
+	for _, input := range []string{`a[1:2:3]`, `a[1:`, `a[:`} {
+		l := lexer.New(input, "non-file")
+		p := parser.New(l)
+		p.ParseProgram()
+		assert.NotEmpty(t, p.Errors(), input)
+	}
+}
+
+func TestParsingTemplateLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		parts    int
+	}{
+		{`"a = ${a}"`, `"a = ${a}"`, 2},
+		{`"${a + 1}${b[0]}!\n"`, "\"${(a + 1)}${(b[0])}!\n\"", 3},
+		{`"sum: ${ add(1, len("${x}")) }"`, `"sum: ${add(1, len("${x}"))}"`, 2},
+		{`"\${a} ${a}"`, `"${a} ${a}"`, 2},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		require.Len(t, p.Errors(), 0, test.input)
+		require.Len(t, program.Statements, 1)
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		require.True(t, ok)
+		template, ok := statement.Expression.(*ast.TemplateLiteral)
+		require.True(t, ok)
+		assert.Equal(t, test.expected, template.String())
+		assert.Len(t, template.Parts, test.parts)
+	}
+}
+
+func TestParsingTemplateLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"${}"`, "empty interpolation on line 1"},
+		{"\n\"a\n${a b}\"", "unexpected IDENT in interpolation on line 3"},
+		{`"${a +}"`, "no prefix parse functions found for EOF on line 1"},
+		{`"\q ${a}"`, `invalid escape sequence \q on line 1`},
+		{`"abc`, "unterminated string on line 1"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+		p.ParseProgram()
+		assert.Equal(t, []string{test.expected}, p.Errors(), test.input)
+	}
+}
+
+func TestMethodCallPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`s.split(",")`, "(s.split(,))"},
+		{"a.map(f).filter(g)", "((a.map(f)).filter(g))"},
+		{"h.items[0] + 1", "((h.(items[0])) + 1)"},
+		{"h.items[1:]", "(h.(items[1:]))"},
+		{`"abc".upper()`, "(abc.upper())"},
+		{"-a.len()", "(-(a.len()))"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		require.Len(t, p.Errors(), 0)
+		require.Len(t, program.Statements, 1)
+		assert.Equal(t, test.expected, program.String())
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	input := `
+struct Point {
+	x, y = 0;
+	fn len(self) { self.x * self.x + self.y * self.y }
+	async fn move(self, dx) { self.x = self.x + dx; }
+}`
+	l := lexer.New(input, "non-file")
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	require.Len(t, p.Errors(), 0)
+	require.Len(t, program.Statements, 1)
+
+	statement, ok := program.Statements[0].(*ast.StructStatement)
+	require.True(t, ok)
+	assert.Equal(t, "Point", statement.Name.Value)
+	require.Len(t, statement.Fields, 2)
+	assert.Equal(t, "x", statement.Fields[0].Name.Value)
+	assert.Nil(t, statement.Fields[0].Value)
+	assert.Equal(t, "y", statement.Fields[1].Name.Value)
+	assert.Equal(t, "0", statement.Fields[1].Value.String())
+	require.Len(t, statement.Methods, 2)
+	assert.Equal(t, "len", statement.Methods[0].Name.Value)
+	assert.False(t, statement.Methods[0].Function.Async)
+	assert.True(t, statement.Methods[1].Function.Async)
+	assert.Equal(t, "struct Point { x; y = 0; fn len(self)(((self.x) * (self.x)) + ((self.y) * (self.y))) async fn move(self, dx)((self.x) = ((self.x) + dx)) }", statement.String())
+}
+
+func TestStructStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct { x }", "expected token IDENT on line 1; instead got {"},
+		{"struct A { x; x }", "duplicate member x in struct A on line 1"},
+		{"struct A { x; fn x(self) {} }", "duplicate member x in struct A on line 1"},
+		{"struct A { fn f() {} }", "method f of struct A must receive self on line 1"},
+		{"struct A { 5 }", "unexpected token 5 in struct A on line 1"},
+		{"struct A { x", "expected token } on line 1; instead got EOF"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+		p.ParseProgram()
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+}
+
+func TestDestructuringLetStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = arr;", "let [a, b] = arr;"},
+		{"let [a, [b, c], ...rest] = arr;", "let [a, [b, c], ...rest] = arr;"},
+		{"let [...all] = arr;", "let [...all] = arr;"},
+		{"let {name, port: p} = cfg;", "let {name, port: p} = cfg;"},
+		{"let {server: {host, port}, ...other} = cfg;", "let {server: {host, port}, ...other} = cfg;"},
+		{"let [] = arr;", "let [] = arr;"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		require.Len(t, p.Errors(), 0, test.input)
+		require.Len(t, program.Statements, 1)
+
+		statement, ok := program.Statements[0].(*ast.LetStatement)
+		require.True(t, ok)
+		assert.Nil(t, statement.Name)
+		assert.Equal(t, test.expected, statement.String())
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, 1] = arr;", "unexpected token 1 in destructuring pattern on line 1"},
+		{"let [...rest, a] = arr;", "expected token ] on line 1; instead got ,"},
+		{"let {1: a} = h;", "expected key name in destructuring pattern on line 1; instead got 1"},
+		{"let [a, b = arr;", "expected token ] on line 1; instead got ="},
+		{"a, 1 = 1, 2", "unable to assign to 1 on line 1"},
+		{"a, b + 1 = 1, 2", "unable to assign to (b + 1) on line 1"},
+		{"a, b", "expected token = on line 1; instead got EOF"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+		p.ParseProgram()
+		require.NotEmpty(t, p.Errors(), test.input)
+		assert.Equal(t, test.expected, p.Errors()[0], test.input)
+	}
+}
+
+func TestMultipleAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a, b = b, a", "a, b = b, a"},
+		{"h.x, arr[0], c = 1 + 2, f(x), [1]", "(h.x), (arr[0]), c = (1 + 2), f(x), [1]"},
+		{"a, b = pair", "a, b = pair"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input, "non-file")
+		p := parser.New(l)
+
+		program := p.ParseProgram()
+		require.Len(t, p.Errors(), 0, test.input)
+		require.Len(t, program.Statements, 1)
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		require.True(t, ok)
+		_, ok = statement.Expression.(*ast.MultipleAssignment)
+		require.True(t, ok)
+		assert.Equal(t, test.expected, statement.String())
+	}
+}
+
+func TestDestructuredParameters(t *testing.T) {
+	input := `fn(a, [b, ...c], {d, e: f}) { a }`
+	l := lexer.New(input, "non-file")
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	require.Len(t, p.Errors(), 0)
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	require.True(t, ok)
+	fnLit, ok := statement.Expression.(*ast.FunctionLiteral)
+	require.True(t, ok)
+
+	require.Len(t, fnLit.Parameters, 3)
+	assert.Equal(t, "a", fnLit.Parameters[0].Value)
+	assert.Equal(t, "[b, ...c]", fnLit.Parameters[1].Value)
+	assert.Equal(t, "{d, e: f}", fnLit.Parameters[2].Value)
+	require.Len(t, fnLit.Patterns, 2)
+	assert.IsType(t, &ast.ArrayPattern{}, fnLit.Patterns[1])
+	assert.IsType(t, &ast.HashPattern{}, fnLit.Patterns[2])
+	assert.Equal(t, "fn(a, [b, ...c], {d, e: f})a", fnLit.String())
+}
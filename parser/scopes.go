@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/YReshetko/rash-lang/ast"
+)
+
+// scope holds the names declared in a block, the value is true for constants.
+// Scopes let the parser report assignments to constants before the script is evaluated
+type scope map[string]bool
+
+func (p *Parser) openScope() {
+	p.scopes = append(p.scopes, scope{})
+}
+
+func (p *Parser) closeScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// declare adds names bound by the identifier or destructuring pattern to the current scope
+func (p *Parser) declare(target ast.Expression, isConst bool, line int) {
+	current := p.scopes[len(p.scopes)-1]
+	for _, name := range ast.BoundNames(target) {
+		if current[name] {
+			p.errors = append(p.errors, fmt.Sprintf("constant %s is already declared on line %d", name, line))
+			continue
+		}
+		current[name] = isConst
+	}
+}
+
+// checkAssignment reports assignment to a name which is resolved to a constant
+func (p *Parser) checkAssignment(target ast.Expression, line int) {
+	ident, ok := target.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		isConst, ok := p.scopes[i][ident.Value]
+		if !ok {
+			continue
+		}
+		if isConst {
+			p.errors = append(p.errors, fmt.Sprintf("cannot assign to constant %s on line %d", ident.Value, line))
+		}
+		return
+	}
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/YReshetko/rash-lang/ast"
+	"github.com/YReshetko/rash-lang/lexer"
+	"github.com/YReshetko/rash-lang/tokens"
+)
+
+// ParseType parses a standalone type annotation, for example a function signature from plugin manifest: fn(string): int
+func ParseType(input string) (*ast.TypeAnnotation, []string) {
+	p := New(lexer.New(input, "type"))
+	t := p.parseTypeAnnotation()
+	if t != nil && !p.peekTokenIs(tokens.EOF) {
+		p.errors = append(p.errors, fmt.Sprintf("unexpected token %s after type on line %d", p.peekToken.Literal, p.peekToken.LineNumber))
+	}
+	return t, p.Errors()
+}
+
+// parseOptionalType parses `: type` following the current token, nil is returned if there is no annotation
+func (p *Parser) parseOptionalType() (*ast.TypeAnnotation, bool) {
+	if !p.peekTokenIs(tokens.COLON) {
+		return nil, true
+	}
+	p.nextToken()
+	p.nextToken()
+	t := p.parseTypeAnnotation()
+	return t, t != nil
+}
+
+// parseTypeAnnotation parses the type starting from the current token: int, array[string], fn(int): bool, int | null
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	defer untrace(trace("parseTypeAnnotation"))
+	first := p.parseSingleType()
+	if first == nil || !p.peekTokenIs(tokens.PIPE) {
+		return first
+	}
+
+	union := &ast.TypeAnnotation{Token: first.Token, Union: []*ast.TypeAnnotation{first}}
+	for p.peekTokenIs(tokens.PIPE) {
+		p.nextToken()
+		p.nextToken()
+		alternative := p.parseSingleType()
+		if alternative == nil {
+			return nil
+		}
+		union.Union = append(union.Union, alternative)
+	}
+	return union
+}
+
+func (p *Parser) parseSingleType() *ast.TypeAnnotation {
+	t := &ast.TypeAnnotation{Token: p.currToken, Name: p.currToken.Literal}
+	switch p.currToken.Type {
+	case tokens.IDENT, tokens.NULL:
+		if p.peekTokenIs(tokens.LBRACKET) {
+			p.nextToken()
+			if !p.parseTypeParameters(t, tokens.RBRACKET) {
+				return nil
+			}
+		}
+		return t
+	case tokens.FUNCTION:
+		if !p.expectPeekToken(tokens.LPAREN) || !p.parseTypeParameters(t, tokens.RPAREN) {
+			return nil
+		}
+		result, ok := p.parseOptionalType()
+		if !ok {
+			return nil
+		}
+		t.Result = result
+		return t
+	}
+	p.errors = append(p.errors, fmt.Sprintf("unexpected token %s in type annotation on line %d", p.currToken.Literal, p.currToken.LineNumber))
+	return nil
+}
+
+// parseTypeParameters parses the types in brackets of array[int] or in parentheses of fn(int, ...string),
+// only the last parameter of function type can be variadic
+func (p *Parser) parseTypeParameters(t *ast.TypeAnnotation, end tokens.TokenType) bool {
+	p.nextToken()
+	if p.currTokenIs(end) {
+		return true
+	}
+
+	for {
+		if end == tokens.RPAREN && p.currTokenIs(tokens.ELLIPSIS) {
+			t.Variadic = true
+			p.nextToken()
+		}
+		parameter := p.parseTypeAnnotation()
+		if parameter == nil {
+			return false
+		}
+		t.Parameters = append(t.Parameters, parameter)
+		if t.Variadic || !p.peekTokenIs(tokens.COMMA) {
+			break
+		}
+		p.nextToken()
+		p.nextToken()
+	}
+	return p.expectPeekToken(end)
+}
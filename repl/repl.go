@@ -15,7 +15,7 @@ const PROMPT = ">> "
 const initial = `
 # http "http.rs";
 # sys "imports.rs";
-let server1 = http.new_server("4000");
+let server = http.new_server("4000");
 server["register"]("GET", "/hello", fn(){return "Hello world"});
 server["start"]();
 
@@ -28,6 +28,12 @@ server["start"]();
 func Start(in io.Reader, out io.Writer) error {
 	scanner := bufio.NewScanner(in)
 	env := objects.NewEnvironment()
+	evaluator.Output = out
+
+	// Timers and plugin callbacks are evaluated in background while REPL waits for the next input
+	evaluator.ServeEventLoop(func(err *objects.Error) {
+		_, _ = fmt.Fprintf(out, "\t%s\n", err.Inspect())
+	})
 
 	eval(initial, env, out)
 
@@ -70,7 +76,7 @@ func eval(input string, environment *objects.Environment, out io.Writer) {
 		return
 	}
 
-	obj := evaluator.Eval(program, environment)
+	obj := evaluator.Execute(program, environment)
 	if obj != objects.NULL {
 		_, err := fmt.Fprintf(out, "%s\n", obj.Inspect())
 		if err != nil {
@@ -1,5 +1,7 @@
 package tokens
 
+import "sort"
+
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
@@ -9,6 +11,8 @@ const (
 	INT    = "INT"
 	DOUBLE = "DOUBLE"
 	STRING = "STRING"
+	// TEMPLATE is a string with `${...}` interpolations, the literal keeps the raw content
+	TEMPLATE = "TEMPLATE"
 
 	// Operators
 	ASSIGN   = "="
@@ -23,7 +27,15 @@ const (
 	EQ     = "=="
 	NOT_EQ = "!="
 
-	DOT = "."
+	DOT      = "."
+	ELLIPSIS = "..."
+	RANGE    = ".."
+	ARROW    = "=>"
+	PIPE     = "|"
+
+	OPTIONAL_DOT      = "?."
+	OPTIONAL_LBRACKET = "?["
+	NULLISH           = "??"
 
 	// Delimeters
 	COMMA     = ","
@@ -43,12 +55,26 @@ const (
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	IF       = "IF"
 	FOR      = "FOR"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
+	ASYNC    = "ASYNC"
+	AWAIT    = "AWAIT"
+	SPAWN    = "SPAWN"
+	SELECT   = "SELECT"
+	CASE     = "CASE"
+	DEFAULT  = "DEFAULT"
+	STRUCT   = "STRUCT"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	IN       = "IN"
+	MATCH    = "MATCH"
+	NULL     = "NULL"
+	EXPORT   = "EXPORT"
 )
 
 type TokenType string
@@ -59,17 +85,43 @@ type Token struct {
 	// For debug
 	FileName   string
 	LineNumber int
+	// Raw is the source text of string literal including quotes, it's kept for the formatter
+	Raw string
 }
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"for":    FOR,
-	"else":   ELSE,
-	"return": RETURN,
-	"true":   TRUE,
-	"false":  FALSE,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"const":    CONST,
+	"if":       IF,
+	"for":      FOR,
+	"else":     ELSE,
+	"return":   RETURN,
+	"true":     TRUE,
+	"false":    FALSE,
+	"async":    ASYNC,
+	"await":    AWAIT,
+	"spawn":    SPAWN,
+	"select":   SELECT,
+	"case":     CASE,
+	"default":  DEFAULT,
+	"struct":   STRUCT,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"in":       IN,
+	"match":    MATCH,
+	"null":     NULL,
+	"export":   EXPORT,
+}
+
+// Keywords returns the sorted keywords of the language
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
 }
 
 func LookupIdent(literal string) TokenType {